@@ -2,35 +2,426 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/history"
+	"github.com/stahir80td/quantum-trader/orderbook"
 	"github.com/stahir80td/quantum-trader/ringbuffer"
 	"github.com/stahir80td/quantum-trader/strategies"
 )
 
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+// HealthHandler reports "ok" while every feed is healthy, "degraded" when
+// some have an open circuit, and "down" with a 503 when none is healthy.
+func HealthHandler(feeds []feed.MarketDataFeed) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := make([]feed.Health, 0, len(feeds))
+		healthy := 0
+		for _, f := range feeds {
+			h := f.Health()
+			if h.Healthy() {
+				healthy++
+			}
+			health = append(health, h)
+		}
+
+		status, code := "ok", http.StatusOK
+		switch {
+		case len(feeds) > 0 && healthy == 0:
+			status, code = "down", http.StatusServiceUnavailable
+		case healthy < len(feeds):
+			status = "degraded"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": status,
+			"feeds":  health,
+		})
+	}
+}
+
+// ErrUnknownSymbol is returned by a remove callback for symbols that are
+// not tracked.
+var ErrUnknownSymbol = errors.New("unknown symbol")
+
+// SymbolsHandler lists the tracked symbols. POST starts tracking one, with
+// a body like {"symbol": "avaxusdt", "venues": {"coinbase": "AVAX-USD"}}
+// (venues may be omitted for listed instruments); DELETE ?symbol= stops
+// tracking one and drops its buffer, bars and history.
+func SymbolsHandler(buffers *ringbuffer.Registry, add func(feed.Instrument) error, remove func(symbol string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var inst feed.Instrument
+			if err := json.NewDecoder(r.Body).Decode(&inst); err != nil || inst.Symbol == "" {
+				writeError(w, http.StatusBadRequest, "body must be JSON with a symbol")
+				return
+			}
+			if err := add(inst); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			symbol := r.URL.Query().Get("symbol")
+			if err := remove(symbol); errors.Is(err, ErrUnknownSymbol) {
+				writeUnknownSymbol(w, symbol)
+				return
+			} else if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		case http.MethodGet:
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbols": buffers.Symbols(),
+			"default": buffers.Default(),
+		})
+	}
 }
 
-func BufferStatusHandler(buffer *ringbuffer.RingBuffer) http.HandlerFunc {
+func BufferStatusHandler(buffers *ringbuffer.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, buffer, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbol":       symbol,
 			"writeIndex":   buffer.GetWriteIndex(),
+			"sequence":     buffer.GetSequence(),
 			"count":        buffer.GetCount(),
 			"size":         buffer.GetSize(),
 			"currentPrice": buffer.GetCurrentPrice(),
+			"subscribers":  buffer.GetSubscribers(),
+			"dropped":      buffer.GetDropped(),
 		})
 	}
 }
 
-func SignalsHandler(buffer *ringbuffer.RingBuffer) http.HandlerFunc {
+// SignalsHandler serves the signals for ?symbol=, with the book of ?venue=
+// (default: the first venue with a book). Signals on raw ticks are the ones
+// the symbol's strategy loop last published to board; those on a ?bars=
+// series are evaluated per request.
+func SignalsHandler(buffers *ringbuffer.Registry, barSets *bars.Registry, books *orderbook.Registry, board *strategies.Board) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		prices := buffer.ReadLast(100)
-		results := strategies.AnalyzeAll(prices)
+		symbol, buffer, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		series, ok := lookupSeries(barSets, symbol, r)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown bar series: "+r.URL.Query().Get("bars"))
+			return
+		}
+
+		book, _ := books.Get(symbol, r.URL.Query().Get("venue"))
+		var results strategies.StrategyResults
+		if u, ok := board.Latest(symbol); ok && series == nil {
+			results = published(u, buffer, book)
+		} else {
+			results = analyze(buffer, series, book, make([]float64, 100))
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(results)
 	}
 }
+
+// BookHandler returns the top ?depth= levels (default 20, 0 for all) of a
+// symbol's order book on ?venue= (default: the first venue with a book).
+func BookHandler(buffers *ringbuffer.Registry, books *orderbook.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, _, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		venue := r.URL.Query().Get("venue")
+		book, ok := books.Get(symbol, venue)
+		if !ok {
+			writeError(w, http.StatusNotFound, "no order book for "+symbol)
+			return
+		}
+
+		depth := 20
+		if v := r.URL.Query().Get("depth"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, "invalid depth")
+				return
+			}
+			depth = n
+		}
+
+		snap := book.Depth(depth)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"book":      snap,
+			"mid":       snap.Mid(),
+			"spread":    snap.Spread(),
+			"imbalance": snap.Imbalance(),
+			"venues":    books.Venues(symbol),
+		})
+	}
+}
+
+func GapsHandler(buffers *ringbuffer.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, buffer, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbol":  symbol,
+			"quality": buffer.GetQuality(time.Now()),
+			"gaps":    buffer.GetGaps(),
+		})
+	}
+}
+
+// VenuesHandler shows how a symbol's composite price was built and the
+// latest raw ticks from each venue. consolidator may be nil, in which case
+// the buffer holds one venue's ticks as they arrived.
+func VenuesHandler(buffers *ringbuffer.Registry, consolidator *feed.Consolidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, _, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		limit := 20
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+			limit = v
+		}
+
+		resp := map[string]interface{}{
+			"symbol":       symbol,
+			"consolidated": consolidator != nil,
+		}
+		if consolidator != nil {
+			if composite, ok := consolidator.Get(symbol); ok {
+				resp["composite"] = composite
+			}
+			ticks := make(map[string][]ringbuffer.Tick)
+			for _, venue := range consolidator.Venues() {
+				if raw, ok := consolidator.Raw(venue, symbol); ok {
+					ticks[venue] = raw.ReadLastTicks(limit)
+				}
+			}
+			resp["ticks"] = ticks
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// FilterHandler reports what the ingestion filter accepted and rejected
+// per venue and symbol.
+func FilterHandler(filter *feed.Filter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := []feed.FilterStats{}
+		if filter != nil {
+			stats = filter.Stats()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"enabled": filter != nil,
+			"streams": stats,
+		})
+	}
+}
+
+func BarsHandler(buffers *ringbuffer.Registry, barSets *bars.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, _, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		// The buffer is registered first and removed last, so a symbol
+		// being added or removed may not have its bars
+		builder, ok := barSets.Get(symbol)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+
+		name := r.URL.Query().Get("series")
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"symbol": symbol,
+				"series": builder.Names(),
+			})
+			return
+		}
+
+		series, ok := builder.Series(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown bar series: "+name)
+			return
+		}
+
+		limit := 100
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+			limit = v
+		}
+		current, forming := series.Current()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbol":  symbol,
+			"series":  series.Spec().Name(),
+			"bars":    series.ReadLast(limit),
+			"current": current,
+			"forming": forming,
+		})
+	}
+}
+
+// HistoryHandler serves /api/history?symbol=&from=&to=&points=. from and to
+// accept RFC 3339 or unix seconds and default to the last hour; points is
+// the maximum number of bars to return (default 500).
+func HistoryHandler(buffers *ringbuffer.Registry, stores *history.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, _, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		store, ok := stores.Get(symbol)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+
+		q := r.URL.Query()
+		to, err := parseTime(q.Get("to"), time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to: "+err.Error())
+			return
+		}
+		from, err := parseTime(q.Get("from"), to.Add(-time.Hour))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from: "+err.Error())
+			return
+		}
+		points := 500
+		if v, err := strconv.Atoi(q.Get("points")); err == nil && v > 0 {
+			points = v
+		}
+
+		result := store.Query(from, to, points)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbol":     symbol,
+			"from":       from,
+			"to":         to,
+			"resolution": result.Resolution,
+			"bars":       result.Bars,
+			"truncated":  result.Truncated,
+		})
+	}
+}
+
+// parseTime accepts RFC 3339 or unix seconds, returning def for "".
+func parseTime(v string, def time.Time) (time.Time, error) {
+	if v == "" {
+		return def, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// lookupBuffer resolves the ?symbol= query parameter, falling back to the
+// registry's default symbol when it is omitted.
+func lookupBuffer(buffers *ringbuffer.Registry, r *http.Request) (string, *ringbuffer.TickBuffer, bool) {
+	symbol := ringbuffer.NormalizeSymbol(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		symbol = buffers.Default()
+	}
+	buffer, ok := buffers.Get(symbol)
+	return symbol, buffer, ok
+}
+
+// lookupSeries resolves the optional ?bars= query parameter. A nil series
+// with ok == true means the strategies should run on raw ticks.
+func lookupSeries(barSets *bars.Registry, symbol string, r *http.Request) (*bars.Series, bool) {
+	name := r.URL.Query().Get("bars")
+	if name == "" {
+		return nil, true
+	}
+	return barSets.Series(symbol, name)
+}
+
+// analyze runs the strategies on the closes of the chosen bar series, or on
+// the buffer's rolling statistics when running on raw ticks, and then
+// suppresses signals the data quality does not support. scratch holds the
+// closes so the bar path does not allocate.
+func analyze(buffer *ringbuffer.TickBuffer, series *bars.Series, book *orderbook.Book, scratch []float64) strategies.StrategyResults {
+	var results strategies.StrategyResults
+	quality := buffer.GetQuality(time.Now())
+	if series != nil {
+		// Bar series fill gaps with flat bars, so only staleness applies
+		quality.TicksSinceGap = -1
+		results = strategies.ApplyQuality(strategies.AnalyzeAll(scratch[:series.ReadClosesInto(scratch)]), quality)
+	} else {
+		results = strategies.ApplyQuality(strategies.AnalyzeSnapshot(buffer.GetStats()), quality)
+	}
+	if book != nil {
+		results = strategies.ApplyBook(results, book.Depth(strategies.BookLevels))
+	}
+	return results
+}
+
+// published completes results a strategy loop published: quality is checked
+// again, since a quiet market publishes nothing that would turn it stale,
+// and the current book is attached.
+func published(u strategies.Update, buffer *ringbuffer.TickBuffer, book *orderbook.Book) strategies.StrategyResults {
+	results := strategies.ApplyQuality(u.Results, buffer.GetQuality(time.Now()))
+	if book != nil {
+		results = strategies.ApplyBook(results, book.Depth(strategies.BookLevels))
+	}
+	return results
+}
+
+// dataPoints is how many prices analyze would see.
+func dataPoints(buffer *ringbuffer.TickBuffer, series *bars.Series) int {
+	if series != nil {
+		return series.GetCount()
+	}
+	return buffer.GetCount()
+}
+
+func writeUnknownSymbol(w http.ResponseWriter, symbol string) {
+	writeError(w, http.StatusNotFound, "unknown symbol: "+symbol)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
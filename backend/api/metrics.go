@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+var feedStates = []string{
+	feed.StateIdle,
+	feed.StateConnecting,
+	feed.StateConnected,
+	feed.StateDisconnected,
+	feed.StateBackoff,
+	feed.StateUnhealthy,
+	feed.StateHalfOpen,
+	feed.StateStopped,
+}
+
+// MetricsHandler exposes feed connection metrics in the Prometheus text
+// format. filter may be nil.
+func MetricsHandler(feeds []feed.MarketDataFeed, filter *feed.Filter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := make([]feed.Health, 0, len(feeds))
+		for _, f := range feeds {
+			health = append(health, f.Health())
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP feed_state 1 for the connection state each feed is in.")
+		fmt.Fprintln(w, "# TYPE feed_state gauge")
+		for _, h := range health {
+			for _, state := range feedStates {
+				v := 0
+				if h.State == state {
+					v = 1
+				}
+				fmt.Fprintf(w, "feed_state{venue=%q,state=%q} %d\n", h.Venue, state, v)
+			}
+		}
+
+		gauge := func(name, help string, value func(feed.Health) float64) {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+			for _, h := range health {
+				fmt.Fprintf(w, "%s{venue=%q} %g\n", name, h.Venue, value(h))
+			}
+		}
+		counter := func(name, help string, value func(feed.Health) uint64) {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+			for _, h := range health {
+				fmt.Fprintf(w, "%s{venue=%q} %d\n", name, h.Venue, value(h))
+			}
+		}
+
+		gauge("feed_connections", "Open WebSocket connections.",
+			func(h feed.Health) float64 { return float64(h.Connections) })
+		gauge("feed_consecutive_failures", "Connection attempts failed in a row.",
+			func(h feed.Health) float64 { return float64(h.Failures) })
+		gauge("feed_last_message_timestamp_seconds", "Unix time of the last message received.",
+			func(h feed.Health) float64 {
+				if h.LastMessage.IsZero() {
+					return 0
+				}
+				return float64(h.LastMessage.UnixNano()) / 1e9
+			})
+		counter("feed_messages_total", "Messages received.",
+			func(h feed.Health) uint64 { return h.Messages })
+		counter("feed_reconnects_total", "Connections lost.",
+			func(h feed.Health) uint64 { return h.Reconnects })
+		counter("feed_state_transitions_total", "Connection state changes.",
+			func(h feed.Health) uint64 { return h.Transitions })
+		counter("feed_trade_gaps_total", "Jumps in trade ids.",
+			func(h feed.Health) uint64 { return h.TradeGaps })
+		counter("feed_missed_trades_total", "Trades skipped by trade id jumps.",
+			func(h feed.Health) uint64 { return h.Missed })
+		counter("feed_out_of_order_total", "Stale or duplicate messages dropped.",
+			func(h feed.Health) uint64 { return h.OutOfOrder })
+
+		if filter == nil {
+			return
+		}
+		stats := filter.Stats()
+		fmt.Fprintln(w, "# HELP feed_ticks_accepted_total Ticks that passed the ingestion filter.")
+		fmt.Fprintln(w, "# TYPE feed_ticks_accepted_total counter")
+		for _, st := range stats {
+			fmt.Fprintf(w, "feed_ticks_accepted_total{venue=%q,symbol=%q} %d\n", st.Venue, st.Symbol, st.Accepted)
+		}
+		fmt.Fprintln(w, "# HELP feed_ticks_rejected_total Ticks dropped by the ingestion filter.")
+		fmt.Fprintln(w, "# TYPE feed_ticks_rejected_total counter")
+		for _, st := range stats {
+			for _, reason := range feed.RejectReasons {
+				fmt.Fprintf(w, "feed_ticks_rejected_total{venue=%q,symbol=%q,reason=%q} %d\n", st.Venue, st.Symbol, reason, st.Rejected[reason])
+			}
+		}
+	}
+}
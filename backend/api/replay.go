@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/replay"
+)
+
+// ReplayHandler reports and controls the replay feed. POST a JSON body
+// with an action:
+//
+//	{"action": "pause"}
+//	{"action": "resume"}
+//	{"action": "seek", "time": "2024-05-01T14:30:00Z"}
+//	{"action": "speed", "speed": "10x"}   // or "max"
+//
+// player is nil when no replay feed is configured.
+func ReplayHandler(player *replay.Feed) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if player == nil {
+			writeError(w, http.StatusNotFound, "no replay feed is running")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			var req struct {
+				Action string `json:"action"`
+				Time   string `json:"time"`
+				Speed  string `json:"speed"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "body must be JSON with an action")
+				return
+			}
+			switch req.Action {
+			case "pause":
+				player.Pause()
+			case "resume":
+				player.Resume()
+			case "seek":
+				t, err := time.Parse(time.RFC3339Nano, req.Time)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "seek needs an RFC 3339 time")
+					return
+				}
+				if err := player.Seek(t); errors.Is(err, replay.ErrSeekBackwards) {
+					writeError(w, http.StatusConflict, err.Error())
+					return
+				}
+			case "speed":
+				speed, err := replay.ParseSpeed(req.Speed)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				player.SetSpeed(speed)
+			default:
+				writeError(w, http.StatusBadRequest, "unknown action: "+req.Action)
+				return
+			}
+		case http.MethodGet:
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(player.Status())
+	}
+}
@@ -1,24 +1,58 @@
 package api
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/orderbook"
 	"github.com/stahir80td/quantum-trader/ringbuffer"
 	"github.com/stahir80td/quantum-trader/strategies"
 )
 
+// wsPushInterval caps how often a single client is sent updates.
+const wsPushInterval = 100 * time.Millisecond
+
+// wsCloseTimeout bounds the close handshake on shutdown.
+const wsCloseTimeout = time.Second
+
 type WSMessage struct {
+	Symbol      string                     `json:"symbol"`
 	Price       float64                    `json:"price"`
 	BufferIndex int                        `json:"bufferIndex"`
+	Sequence    uint64                     `json:"sequence"`
 	Signals     strategies.StrategyResults `json:"signals"`
 	Timestamp   int64                      `json:"timestamp"`
 }
 
-func WebSocketHandler(buffer *ringbuffer.RingBuffer, upgrader websocket.Upgrader) http.HandlerFunc {
+// WebSocketHandler streams signals to a client until it disconnects or ctx
+// is cancelled, in which case the client gets a going-away close frame.
+// Signals on raw ticks are pushed as the symbol's strategy loop publishes
+// them to board; those on a ?bars= series are evaluated per push.
+// http.Server.Shutdown does not wait for hijacked connections, so each
+// handler is tracked in clients instead.
+func WebSocketHandler(ctx context.Context, clients *sync.WaitGroup, buffers *ringbuffer.Registry, barSets *bars.Registry, books *orderbook.Registry, board *strategies.Board, upgrader websocket.Upgrader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		symbol, buffer, ok := lookupBuffer(buffers, r)
+		if !ok {
+			writeUnknownSymbol(w, symbol)
+			return
+		}
+		series, ok := lookupSeries(barSets, symbol, r)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown bar series: "+r.URL.Query().Get("bars"))
+			return
+		}
+
+		venue := r.URL.Query().Get("venue")
+
+		clients.Add(1)
+		defer clients.Done()
+
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println("WebSocket upgrade error:", err)
@@ -26,30 +60,116 @@ func WebSocketHandler(buffer *ringbuffer.RingBuffer, upgrader websocket.Upgrader
 		}
 		defer conn.Close()
 
-		log.Println("✅ WebSocket client connected")
+		log.Printf("✅ WebSocket client connected: %s", symbol)
 
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
+		// Coalesced wake-ups: a burst of ticks or published evaluations
+		// collapses into one pending notification, and pushes are spaced at
+		// least wsPushInterval apart
+		var updated <-chan struct{}
+		var written <-chan ringbuffer.Tick
+		var next func() (WSMessage, bool)
+		if series == nil {
+			sub := board.Subscribe(symbol)
+			defer sub.Close()
+			updated = sub.C
+			var sent uint64
+			next = func() (WSMessage, bool) {
+				u, ok := board.Latest(symbol)
+				if !ok || u.Sequence == sent {
+					return WSMessage{}, false
+				}
+				sent = u.Sequence
+				book, _ := books.Get(symbol, venue)
+				return WSMessage{
+					Symbol:      symbol,
+					Price:       u.Price,
+					BufferIndex: u.BufferIndex,
+					Sequence:    u.Sequence,
+					Signals:     published(u, buffer, book),
+					Timestamp:   time.Now().Unix(),
+				}, true
+			}
+		} else {
+			sub := buffer.Subscribe(1)
+			defer sub.Close()
+			written = sub.C
 
-		for range ticker.C {
-			prices := buffer.ReadLast(100)
-			if len(prices) < 20 {
-				continue
+			// Reused across pushes so the hot path does not allocate
+			scratch := make([]float64, 100)
+			ticks := make([]ringbuffer.Tick, 256)
+			cursor := buffer.NewCursor()
+			next = func() (WSMessage, bool) {
+				// Only push an update when something new arrived
+				if n, _ := cursor.Read(ticks); n == 0 || dataPoints(buffer, series) < 20 {
+					return WSMessage{}, false
+				}
+				// Looked up per push: the book appears once the feed
+				// delivers one
+				book, _ := books.Get(symbol, venue)
+				return WSMessage{
+					Symbol:      symbol,
+					Price:       buffer.GetCurrentPrice(),
+					BufferIndex: buffer.GetWriteIndex(),
+					Sequence:    cursor.Sequence(),
+					Signals:     analyze(buffer, series, book, scratch),
+					Timestamp:   time.Now().Unix(),
+				}, true
 			}
+		}
 
-			signals := strategies.AnalyzeAll(prices)
+		// Nothing is written on a quiet market, so watch the read side to
+		// notice clients that went away
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
 
-			msg := WSMessage{
-				Price:       buffer.GetCurrentPrice(),
-				BufferIndex: buffer.GetWriteIndex(),
-				Signals:     signals,
-				Timestamp:   time.Now().Unix(),
+		for {
+			msg, ok := next()
+			if !ok {
+				select {
+				case <-updated:
+				case <-written:
+				case <-done:
+					return
+				case <-ctx.Done():
+					closeClient(conn, done)
+					return
+				}
+				continue
 			}
 
 			if err := conn.WriteJSON(msg); err != nil {
 				log.Println("WebSocket write error:", err)
 				return
 			}
+
+			select {
+			case <-time.After(wsPushInterval):
+			case <-done:
+				return
+			case <-ctx.Done():
+				closeClient(conn, done)
+				return
+			}
 		}
 	}
 }
+
+// closeClient sends a going-away close frame and waits for the client to
+// answer it, which ends the read pump and closes done.
+func closeClient(conn *websocket.Conn, done <-chan struct{}) {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsCloseTimeout)); err != nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(wsCloseTimeout):
+	}
+}
@@ -0,0 +1,189 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+type Config struct {
+	Bars    int           // bars seeded per time series, at most
+	Timeout time.Duration // for the whole backfill
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Bars:    100,
+		Timeout: 15 * time.Second,
+	}
+}
+
+// Run seeds the time bar series of symbols with history from the first
+// feed that serves candles for each, so strategies on bars have their
+// lookback straight after a deploy. It must be called before the bar
+// builders run; live ticks then continue the forming bar (see
+// bars.Series.Seed). Failures are logged and leave a series to fill from
+// live ticks. It returns the number of bars seeded.
+func Run(ctx context.Context, feeds []feed.MarketDataFeed, barSets *bars.Registry, symbols []string, cfg Config) int {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+	for _, symbol := range symbols {
+		builder, ok := barSets.Get(symbol)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			n := seed(ctx, feeds, symbol, builder, cfg.Bars, time.Now())
+			mu.Lock()
+			total += n
+			mu.Unlock()
+		}(symbol)
+	}
+	wg.Wait()
+	return total
+}
+
+// Symbol seeds the series of one symbol added at runtime, before its
+// builder starts. See Run.
+func Symbol(ctx context.Context, feeds []feed.MarketDataFeed, symbol string, builder *bars.Builder, cfg Config) int {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	return seed(ctx, feeds, symbol, builder, cfg.Bars, time.Now())
+}
+
+// seed backfills one symbol's series, one request per series.
+func seed(ctx context.Context, feeds []feed.MarketDataFeed, symbol string, builder *bars.Builder, n int, now time.Time) int {
+	total := 0
+	var seeded []string
+	for _, name := range builder.Names() {
+		series, _ := builder.Series(name)
+		if series.Spec().Kind != bars.Time {
+			continue
+		}
+		for _, f := range feeds {
+			src, ok := f.(feed.CandleSource)
+			if !ok {
+				continue
+			}
+			if _, listed := feed.VenueSymbol(f.Name(), symbol); !listed {
+				continue
+			}
+			history, err := Fetch(ctx, src, symbol, series.Spec().Interval, n, now)
+			if err != nil {
+				log.Printf("⚠️  Backfill of %s %s from %s failed: %v", symbol, name, f.Name(), err)
+				continue
+			}
+			if len(history) == 0 {
+				continue
+			}
+			count := series.Seed(history, now)
+			total += count
+			seeded = append(seeded, fmt.Sprintf("%s=%d (%s)", name, count, f.Name()))
+			break
+		}
+	}
+	if len(seeded) > 0 {
+		log.Printf("⏪ Backfilled %s: %s", symbol, strings.Join(seeded, " "))
+	}
+	return total
+}
+
+// Fetch returns up to n closed bars of interval ending at the last interval
+// boundary before now, oldest first, followed by the bar still forming at
+// now if it has trades. Intervals the venue does not serve are built from
+// the largest granularity that divides them; when none does it returns
+// nothing.
+func Fetch(ctx context.Context, src feed.CandleSource, symbol string, interval time.Duration, n int, now time.Time) ([]bars.Bar, error) {
+	var granularity time.Duration
+	for _, g := range src.Granularities() {
+		if g <= interval && interval%g == 0 {
+			granularity = g
+		}
+	}
+	if granularity == 0 {
+		return nil, nil
+	}
+	perBar := int(interval / granularity)
+	// One bar's worth of candles is kept for the forming bar
+	if limit := src.MaxCandles()/perBar - 1; n > limit {
+		n = limit
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+
+	start := now.Truncate(interval).Add(-time.Duration(n) * interval)
+	klines, err := src.Candles(ctx, symbol, granularity, start, now)
+	if err != nil {
+		return nil, err
+	}
+	return aggregate(klines, interval, start, now), nil
+}
+
+// aggregate folds candles starting in [start, end) into bars of interval.
+// The last bar ends after end if its interval was still running. Candles
+// may arrive in any order.
+func aggregate(klines []feed.Kline, interval time.Duration, start, end time.Time) []bars.Bar {
+	sort.Slice(klines, func(i, j int) bool { return klines[i].Start.Before(klines[j].Start) })
+
+	var out []bars.Bar
+	for _, k := range klines {
+		if k.Start.Before(start) || !k.Start.Before(end) {
+			continue
+		}
+		notional := k.Notional
+		if notional == 0 {
+			// Typical price stands in for the VWAP the venue does not report
+			notional = (k.High + k.Low + k.Close) / 3 * k.Volume
+		}
+
+		barStart := k.Start.Truncate(interval)
+		if n := len(out); n > 0 && out[n-1].Start.Equal(barStart) {
+			b := &out[n-1]
+			if k.High > b.High {
+				b.High = k.High
+			}
+			if k.Low < b.Low {
+				b.Low = k.Low
+			}
+			b.Close = k.Close
+			b.Volume += k.Volume
+			b.Notional += notional
+			b.Trades += int(k.Trades)
+			continue
+		}
+		out = append(out, bars.Bar{
+			Start:    barStart,
+			End:      barStart.Add(interval),
+			Open:     k.Open,
+			High:     k.High,
+			Low:      k.Low,
+			Close:    k.Close,
+			Volume:   k.Volume,
+			Notional: notional,
+			Trades:   int(k.Trades),
+		})
+	}
+	return out
+}
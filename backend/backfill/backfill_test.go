@@ -0,0 +1,191 @@
+package backfill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var epoch = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+func kline(start time.Time, d time.Duration, o, h, l, c, v, notional float64) feed.Kline {
+	return feed.Kline{Start: start, End: start.Add(d), Open: o, High: h, Low: l, Close: c, Volume: v, Notional: notional, Trades: 1}
+}
+
+func TestAggregate(t *testing.T) {
+	m := func(i int) time.Time { return epoch.Add(time.Duration(i) * time.Minute) }
+	tests := []struct {
+		name     string
+		klines   []feed.Kline
+		interval time.Duration
+		end      time.Time
+		want     []bars.Bar
+	}{
+		{
+			name:     "one to one, unsorted",
+			klines:   []feed.Kline{kline(m(1), time.Minute, 2, 3, 1, 2, 1, 2), kline(m(0), time.Minute, 1, 2, 1, 1.5, 2, 3)},
+			interval: time.Minute,
+			end:      m(2),
+			want: []bars.Bar{
+				{Start: m(0), End: m(1), Open: 1, High: 2, Low: 1, Close: 1.5, Volume: 2, Notional: 3, Trades: 1},
+				{Start: m(1), End: m(2), Open: 2, High: 3, Low: 1, Close: 2, Volume: 1, Notional: 2, Trades: 1},
+			},
+		},
+		{
+			name: "minutes into five minutes",
+			klines: []feed.Kline{
+				kline(m(0), time.Minute, 10, 12, 9, 11, 1, 11),
+				kline(m(2), time.Minute, 11, 15, 10, 14, 2, 28),
+				kline(m(4), time.Minute, 14, 14, 8, 9, 3, 30),
+				kline(m(5), time.Minute, 9, 10, 9, 10, 1, 10),
+			},
+			interval: 5 * time.Minute,
+			end:      m(10),
+			want: []bars.Bar{
+				{Start: m(0), End: m(5), Open: 10, High: 15, Low: 8, Close: 9, Volume: 6, Notional: 69, Trades: 3},
+				{Start: m(5), End: m(10), Open: 9, High: 10, Low: 9, Close: 10, Volume: 1, Notional: 10, Trades: 1},
+			},
+		},
+		{
+			name:     "typical price stands in for notional",
+			klines:   []feed.Kline{kline(m(0), time.Minute, 10, 12, 6, 9, 2, 0)},
+			interval: time.Minute,
+			end:      m(1),
+			want:     []bars.Bar{{Start: m(0), End: m(1), Open: 10, High: 12, Low: 6, Close: 9, Volume: 2, Notional: 18, Trades: 1}},
+		},
+		{
+			name: "candles outside the window are dropped",
+			klines: []feed.Kline{
+				kline(m(-1), time.Minute, 1, 1, 1, 1, 1, 1),
+				kline(m(0), time.Minute, 2, 2, 2, 2, 1, 2),
+				kline(m(3), time.Minute, 3, 3, 3, 3, 1, 3),
+			},
+			interval: time.Minute,
+			end:      m(3),
+			want:     []bars.Bar{{Start: m(0), End: m(1), Open: 2, High: 2, Low: 2, Close: 2, Volume: 1, Notional: 2, Trades: 1}},
+		},
+		{
+			name: "forming bar is kept",
+			klines: []feed.Kline{
+				kline(m(0), time.Minute, 1, 1, 1, 1, 1, 1),
+				kline(m(5), time.Minute, 2, 3, 2, 3, 1, 3),
+				kline(m(6), time.Minute, 3, 4, 3, 4, 1, 4),
+			},
+			interval: 5 * time.Minute,
+			end:      m(6).Add(30 * time.Second),
+			want: []bars.Bar{
+				{Start: m(0), End: m(5), Open: 1, High: 1, Low: 1, Close: 1, Volume: 1, Notional: 1, Trades: 1},
+				{Start: m(5), End: m(10), Open: 2, High: 4, Low: 2, Close: 4, Volume: 2, Notional: 7, Trades: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregate(tt.klines, tt.interval, epoch, tt.end)
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d bars, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("bar %d:\n got %+v\nwant %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeSource serves one-minute candles with close i for the i-th minute
+// after epoch, up to and including the one forming at the request's end.
+type fakeSource struct {
+	feed.MarketDataFeed
+	name          string
+	granularities []time.Duration
+	max           int
+	start, end    time.Time // of the last request
+	granularity   time.Duration
+}
+
+func (f *fakeSource) Name() string                   { return f.name }
+func (f *fakeSource) Granularities() []time.Duration { return f.granularities }
+func (f *fakeSource) MaxCandles() int                { return f.max }
+
+func (f *fakeSource) Candles(ctx context.Context, symbol string, granularity time.Duration, start, end time.Time) ([]feed.Kline, error) {
+	f.start, f.end, f.granularity = start, end, granularity
+	var out []feed.Kline
+	for t := start; t.Before(end); t = t.Add(granularity) {
+		c := float64(t.Sub(epoch) / time.Minute)
+		out = append(out, kline(t, granularity, c, c, c, c, 1, c))
+	}
+	return out, nil
+}
+
+func TestFetch(t *testing.T) {
+	src := &fakeSource{granularities: []time.Duration{time.Minute, 5 * time.Minute, time.Hour}, max: 300}
+	now := epoch.Add(100*time.Hour + 7*time.Minute + 30*time.Second)
+
+	history, err := Fetch(context.Background(), src, "btcusdt", 15*time.Minute, 500, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 300 candles of 5m hold 100 bars of 15m, one of which is forming
+	if src.granularity != 5*time.Minute {
+		t.Errorf("granularity %s, want 5m", src.granularity)
+	}
+	boundary := now.Truncate(15 * time.Minute)
+	if want := boundary.Add(-99 * 15 * time.Minute); !src.start.Equal(want) || !src.end.Equal(now) {
+		t.Errorf("requested %s-%s, want %s-%s", src.start, src.end, want, now)
+	}
+	if len(history) != 100 {
+		t.Fatalf("%d bars, want 100", len(history))
+	}
+	last := history[len(history)-1]
+	if !last.Start.Equal(boundary) || !last.End.After(now) || last.Volume != 2 {
+		t.Errorf("forming bar %+v", last)
+	}
+	if prev := history[len(history)-2]; !prev.End.Equal(boundary) || prev.Volume != 3 {
+		t.Errorf("last closed bar %+v", prev)
+	}
+
+	src.granularities = src.granularities[1:]
+	if history, _ := Fetch(context.Background(), src, "btcusdt", 7*time.Minute, 10, now); history != nil {
+		t.Errorf("7m bars from 5m and 1h candles: got %d, want none", len(history))
+	}
+}
+
+func TestSeedContinuesFormingBar(t *testing.T) {
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt"}, ringbuffer.Options{Size: 16})
+	buffer, _ := buffers.Get("btcusdt")
+	builder := bars.NewBuilder(buffer, []bars.Spec{{Kind: bars.Time, Interval: time.Minute}}, 16)
+	series, _ := builder.Series("1m")
+
+	src := &fakeSource{name: "coinbase", granularities: []time.Duration{time.Minute}, max: 300}
+	now := epoch.Add(10*time.Minute + 20*time.Second)
+	if n := seed(context.Background(), []feed.MarketDataFeed{src}, "btcusdt", builder, 5, now); n != 6 {
+		t.Fatalf("seeded %d bars, want 5 closed and 1 forming", n)
+	}
+
+	closed := series.ReadLast(10)
+	if len(closed) != 5 || !closed[4].End.Equal(epoch.Add(10*time.Minute)) {
+		t.Fatalf("closed bars %+v", closed)
+	}
+	forming, ok := series.Current()
+	if !ok || !forming.Start.Equal(epoch.Add(10*time.Minute)) || forming.Volume != 1 {
+		t.Fatalf("forming bar %+v %v", forming, ok)
+	}
+
+	// The candle already holds trades up to now; later ones complete it
+	series.Add(ringbuffer.Tick{Time: now.Add(-time.Second), Price: 50, Size: 5})
+	series.Add(ringbuffer.Tick{Time: now.Add(time.Second), Price: 12, Size: 2})
+	if forming, _ = series.Current(); forming.Volume != 3 || forming.High != 12 || forming.Open != 10 || forming.Close != 12 {
+		t.Errorf("forming bar after live ticks %+v", forming)
+	}
+
+	series.Add(ringbuffer.Tick{Time: epoch.Add(11 * time.Minute), Price: 13, Size: 1})
+	if closed := series.ReadLast(1); len(closed) != 1 || closed[0].Volume != 3 || closed[0].Close != 12 {
+		t.Errorf("completed bar %+v", closed)
+	}
+}
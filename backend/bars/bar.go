@@ -0,0 +1,138 @@
+package bars
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the sampling rule that decides when a bar closes.
+type Kind int
+
+const (
+	// Time bars close on fixed wall-clock boundaries.
+	Time Kind = iota
+	// Tick bars close after a fixed number of trades.
+	Tick
+	// Volume bars close after a fixed traded base volume.
+	Volume
+	// Dollar bars close after a fixed traded notional (price × size).
+	Dollar
+)
+
+// Spec describes one bar series, e.g. 1m time bars or $1M dollar bars.
+type Spec struct {
+	Kind      Kind
+	Interval  time.Duration // Time bars
+	Threshold float64       // Tick, Volume and Dollar bars
+}
+
+// Name is the spec's canonical string form, accepted by ParseSpec: "1m",
+// "tick:100", "volume:10", "dollar:1000000".
+func (s Spec) Name() string {
+	threshold := strconv.FormatFloat(s.Threshold, 'f', -1, 64)
+	switch s.Kind {
+	case Tick:
+		return "tick:" + threshold
+	case Volume:
+		return "volume:" + threshold
+	case Dollar:
+		return "dollar:" + threshold
+	default:
+		return formatInterval(s.Interval)
+	}
+}
+
+func ParseSpec(name string) (Spec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	kind, value, found := strings.Cut(name, ":")
+	if !found {
+		d, err := time.ParseDuration(name)
+		if err != nil || d <= 0 {
+			return Spec{}, fmt.Errorf("invalid bar interval %q", name)
+		}
+		return Spec{Kind: Time, Interval: d}, nil
+	}
+
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 {
+		return Spec{}, fmt.Errorf("invalid bar threshold %q", name)
+	}
+	switch kind {
+	case "tick":
+		return Spec{Kind: Tick, Threshold: threshold}, nil
+	case "volume":
+		return Spec{Kind: Volume, Threshold: threshold}, nil
+	case "dollar":
+		return Spec{Kind: Dollar, Threshold: threshold}, nil
+	}
+	return Spec{}, fmt.Errorf("unknown bar kind %q", kind)
+}
+
+// ParseSpecs parses a comma-separated list of spec names.
+func ParseSpecs(list string) ([]Spec, error) {
+	var specs []Spec
+	for _, name := range strings.Split(list, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		spec, err := ParseSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// DefaultSpecs are the series built for every symbol unless configured
+// otherwise. Volume bars are left out because a sensible threshold depends
+// on the instrument.
+func DefaultSpecs() []Spec {
+	return []Spec{
+		{Kind: Time, Interval: time.Second},
+		{Kind: Time, Interval: time.Minute},
+		{Kind: Time, Interval: 5 * time.Minute},
+		{Kind: Time, Interval: time.Hour},
+		{Kind: Tick, Threshold: 100},
+		{Kind: Dollar, Threshold: 1000000},
+	}
+}
+
+// formatInterval trims time.Duration's trailing zero units ("1m0s" -> "1m").
+func formatInterval(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// Bar is one OHLCV bar. A bar with Trades == 0 covers an interval with no
+// trades and is flat at the previous close, unless it was seeded from venue
+// candles that do not count trades, in which case Volume is set.
+type Bar struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"`   // base units
+	Notional float64   `json:"notional"` // quote units, sum of price × size
+	Trades   int       `json:"trades"`
+}
+
+// VWAP is the bar's volume-weighted average price, or Close if no volume
+// was recorded.
+func (b Bar) VWAP() float64 {
+	if b.Volume == 0 {
+		return b.Close
+	}
+	return b.Notional / b.Volume
+}
@@ -0,0 +1,259 @@
+package bars
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// lateTickGrace delays closing a time bar on the wall clock so that ticks
+// stamped just before the boundary but delivered just after it still land
+// in the right bar.
+const lateTickGrace = time.Second
+
+// Clock tells a Builder how far time bars may be closed, given the exchange
+// time of the latest tick it consumed (zero before the first one). A zero
+// result closes nothing.
+type Clock func(latest time.Time) time.Time
+
+// WallClock closes bars on the local clock, less a grace period for late
+// ticks, so that quiet live periods still produce bars.
+func WallClock(time.Time) time.Time {
+	return time.Now().Add(-lateTickGrace)
+}
+
+// TickClock closes bars only as the feed's own time moves on, for replays
+// whose exchange times are unrelated to the local clock. Quiet periods are
+// filled in when the next tick arrives.
+func TickClock(latest time.Time) time.Time {
+	return latest
+}
+
+// Builder feeds one symbol's ticks into all of its bar series.
+type Builder struct {
+	buffer *ringbuffer.TickBuffer
+	series map[string]*Series
+	names  []string
+	clock  Clock
+}
+
+func NewBuilder(buffer *ringbuffer.TickBuffer, specs []Spec, size int) *Builder {
+	series := make([]*Series, len(specs))
+	for i, spec := range specs {
+		series[i] = NewSeries(spec, size)
+	}
+	return NewBuilderFromSeries(buffer, series...)
+}
+
+// NewBuilderFromSeries builds from pre-made series, for callers that need a
+// different capacity per series. Later series with a duplicate spec are
+// ignored.
+func NewBuilderFromSeries(buffer *ringbuffer.TickBuffer, series ...*Series) *Builder {
+	b := &Builder{
+		buffer: buffer,
+		series: make(map[string]*Series, len(series)),
+		clock:  WallClock,
+	}
+	for _, s := range series {
+		name := s.Spec().Name()
+		if _, ok := b.series[name]; ok {
+			continue
+		}
+		b.series[name] = s
+		b.names = append(b.names, name)
+	}
+	return b
+}
+
+// Series returns the series with the given spec name (see Spec.Name).
+func (b *Builder) Series(name string) (*Series, bool) {
+	spec, err := ParseSpec(name)
+	if err != nil {
+		return nil, false
+	}
+	s, ok := b.series[spec.Name()]
+	return s, ok
+}
+
+// SetClock replaces the WallClock that closes time bars. It must be called
+// before Run.
+func (b *Builder) SetClock(clock Clock) {
+	b.clock = clock
+}
+
+// Names returns the configured series names in configuration order.
+func (b *Builder) Names() []string {
+	return b.names
+}
+
+// Run consumes ticks as they are written, starting with whatever is already
+// buffered, and closes time bars every second on the builder's Clock. It
+// returns when ctx is cancelled.
+func (b *Builder) Run(ctx context.Context) {
+	sub := b.buffer.Subscribe(1)
+	defer sub.Close()
+
+	cursor := b.buffer.NewCursor()
+	ticks := make([]ringbuffer.Tick, 256)
+
+	clock := time.NewTicker(time.Second)
+	defer clock.Stop()
+	var latest time.Time
+
+	for {
+		for {
+			n, _ := cursor.Read(ticks)
+			if n == 0 {
+				break
+			}
+			for _, t := range ticks[:n] {
+				for _, name := range b.names {
+					b.series[name].Add(t)
+				}
+				if t.Time.After(latest) {
+					latest = t.Time
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.C:
+		case <-clock.C:
+			if now := b.clock(latest); !now.IsZero() {
+				for _, name := range b.names {
+					b.series[name].Advance(now)
+				}
+			}
+		}
+	}
+}
+
+// Registry holds one Builder per symbol.
+type Registry struct {
+	builders map[string]*Builder
+	specs    []Spec
+	size     int
+	ctx      context.Context // set by Run
+	clock    Clock
+	stop     map[string]context.CancelFunc
+	prepare  func(ctx context.Context, symbol string, b *Builder)
+	mu       sync.RWMutex
+}
+
+func NewRegistry(buffers *ringbuffer.Registry, specs []Spec, size int) *Registry {
+	r := &Registry{
+		builders: make(map[string]*Builder),
+		clock:    WallClock,
+		stop:     make(map[string]context.CancelFunc),
+		specs:    specs,
+		size:     size,
+	}
+	for _, symbol := range buffers.Symbols() {
+		buffer, _ := buffers.Get(symbol)
+		r.Add(symbol, buffer)
+	}
+	return r
+}
+
+// Add creates the builder for a symbol added at runtime, starting it if the
+// registry is already running.
+func (r *Registry) Add(symbol string, buffer *ringbuffer.TickBuffer) *Builder {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.builders[symbol]; ok {
+		return b
+	}
+	b := NewBuilder(buffer, r.specs, r.size)
+	b.SetClock(r.clock)
+	r.builders[symbol] = b
+	if r.ctx != nil {
+		ctx := r.start(symbol)
+		go func(prepare func(context.Context, string, *Builder)) {
+			if prepare != nil {
+				prepare(ctx, symbol, b)
+			}
+			b.Run(ctx)
+		}(r.prepare)
+	}
+	return b
+}
+
+// Remove stops the builder for symbol and forgets it, reporting whether it
+// existed.
+func (r *Registry) Remove(symbol string) bool {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.builders[symbol]; !ok {
+		return false
+	}
+	if stop, ok := r.stop[symbol]; ok {
+		stop()
+		delete(r.stop, symbol)
+	}
+	delete(r.builders, symbol)
+	return true
+}
+
+// start derives the context a symbol's builder runs under, so that Remove
+// can stop it alone. r.mu must be held.
+func (r *Registry) start(symbol string) context.Context {
+	ctx, stop := context.WithCancel(r.ctx)
+	r.stop[symbol] = stop
+	return ctx
+}
+
+// SetPrepare installs fn to run on builders added while the registry is
+// running, before they start consuming ticks, so their series can be
+// seeded first. Ticks written meanwhile stay buffered for the builder.
+func (r *Registry) SetPrepare(fn func(ctx context.Context, symbol string, b *Builder)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prepare = fn
+}
+
+// SetClock sets the Clock of every builder, including those added later. It
+// must be called before Run.
+func (r *Registry) SetClock(clock Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = clock
+	for _, b := range r.builders {
+		b.SetClock(clock)
+	}
+}
+
+func (r *Registry) Get(symbol string) (*Builder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b, ok := r.builders[ringbuffer.NormalizeSymbol(symbol)]
+	return b, ok
+}
+
+// Series looks up one symbol's series by spec name.
+func (r *Registry) Series(symbol, name string) (*Series, bool) {
+	b, ok := r.Get(symbol)
+	if !ok {
+		return nil, false
+	}
+	return b.Series(name)
+}
+
+// Run starts every builder in its own goroutine.
+func (r *Registry) Run(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	for symbol, b := range r.builders {
+		go b.Run(r.start(symbol))
+	}
+}
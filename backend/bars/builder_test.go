@@ -0,0 +1,101 @@
+package bars
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// TestRegistryPreparesRuntimeBuilders checks that a builder added while the
+// registry runs is prepared before it consumes ticks, and then picks up the
+// ticks buffered meanwhile.
+func TestRegistryPreparesRuntimeBuilders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buffers := ringbuffer.NewRegistry(nil, ringbuffer.Options{Size: 64})
+	reg := NewRegistry(buffers, []Spec{{Kind: Time, Interval: time.Hour}}, 16)
+	reg.Run(ctx)
+
+	now := time.Now()
+	hour := now.Truncate(time.Hour)
+	release := make(chan struct{})
+	prepared := make(chan string, 1)
+	reg.SetPrepare(func(ctx context.Context, symbol string, b *Builder) {
+		<-release
+		s, _ := b.Series("1h")
+		s.Seed([]Bar{{Start: hour.Add(-time.Hour), End: hour, Open: 1, High: 1, Low: 1, Close: 1}}, now)
+		prepared <- symbol
+	})
+
+	buffer := buffers.Add("solusdt")
+	reg.Add("solusdt", buffer)
+	buffer.WriteTick(ringbuffer.Tick{Symbol: "solusdt", Time: now.Add(time.Millisecond), Price: 150, Size: 2})
+	if _, ok := reg.Series("solusdt", "1h"); !ok {
+		t.Fatal("series not registered")
+	}
+	close(release)
+	if symbol := <-prepared; symbol != "solusdt" {
+		t.Fatalf("prepared %s", symbol)
+	}
+
+	s, _ := reg.Series("solusdt", "1h")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if cur, ok := s.Current(); ok {
+			if cur.Open != 150 || cur.Volume != 2 {
+				t.Errorf("forming bar %+v", cur)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("buffered tick never reached the builder")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if seeded := s.ReadLast(2); len(seeded) != 1 || seeded[0].Close != 1 {
+		t.Errorf("seeded bars %+v", seeded)
+	}
+}
+
+// TestRegistryRemove checks that removing a symbol stops its builder alone.
+func TestRegistryRemove(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt", "ethusdt"}, ringbuffer.Options{Size: 64})
+	reg := NewRegistry(buffers, []Spec{{Kind: Time, Interval: time.Minute}}, 16)
+	reg.Run(ctx)
+	btc, _ := buffers.Get("btcusdt")
+	eth, _ := buffers.Get("ethusdt")
+	waitSubscribers := func(buffer *ringbuffer.TickBuffer, want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for buffer.GetSubscribers() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d subscribers, want %d", buffer.GetSubscribers(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitSubscribers(btc, 1)
+	waitSubscribers(eth, 1)
+
+	if !reg.Remove("BTCUSDT") || reg.Remove("btcusdt") {
+		t.Fatal("Remove did not report the builder exactly once")
+	}
+	if _, ok := reg.Get("btcusdt"); ok {
+		t.Error("removed builder still registered")
+	}
+	waitSubscribers(btc, 0)
+	waitSubscribers(eth, 1)
+
+	if !buffers.Remove("btcusdt") || buffers.Remove("btcusdt") {
+		t.Fatal("buffer not removed exactly once")
+	}
+	if got := buffers.Symbols(); len(got) != 1 || got[0] != "ethusdt" || buffers.Default() != "ethusdt" {
+		t.Errorf("symbols %v, default %s after removal", got, buffers.Default())
+	}
+}
@@ -0,0 +1,277 @@
+package bars
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Series aggregates ticks into bars of one Spec. Finalized bars go into a
+// ring buffer; the bar still forming is kept aside until it closes.
+//
+// Add and Advance must be called from a single goroutine (the Builder);
+// the read methods are safe to call concurrently.
+type Series struct {
+	spec Spec
+	ring *ringbuffer.RingBuffer[Bar]
+
+	mu      sync.Mutex
+	cur     Bar
+	open    bool      // cur holds at least one trade
+	next    time.Time // time bars: start of the bar after the last finalized one
+	started bool      // at least one bar has been finalized or opened
+	measure float64   // threshold bars: progress towards Threshold
+	seeded  time.Time // ticks before this are already in the seeded bars
+}
+
+func NewSeries(spec Spec, size int) *Series {
+	return &Series{
+		spec: spec,
+		ring: ringbuffer.NewRingBuffer[Bar](size),
+	}
+}
+
+func (s *Series) Spec() Spec {
+	return s.spec
+}
+
+// Add folds one tick into the series, closing bars as required.
+func (s *Series) Add(t ringbuffer.Tick) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.spec.Kind == Time {
+		s.addTimed(t)
+	} else {
+		s.addThreshold(t)
+	}
+}
+
+// Advance finalizes time bars whose interval ended at or before now and
+// emits flat bars for intervals that passed with no trades. It is a no-op
+// for threshold-based series.
+func (s *Series) Advance(now time.Time) {
+	if s.spec.Kind != Time {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open && !s.cur.End.After(now) {
+		s.finalize()
+	}
+	if !s.open && s.started {
+		s.fillTo(now.Truncate(s.spec.Interval))
+	}
+}
+
+// Seed fills a time series that has not started yet with historical bars,
+// oldest first, so strategies have a lookback right after startup. Bars
+// must lie on the series' boundaries; intervals missing from history get
+// flat bars. A last bar still running at asOf is kept open, so live ticks
+// complete it instead of starting a partial bar. Live ticks stamped before
+// the end of the history, or before asOf when a bar is open, are dropped,
+// since the bars already include them. It must be called before the
+// Builder runs and returns how many bars were used.
+func (s *Series) Seed(history []Bar, asOf time.Time) int {
+	if s.spec.Kind != Time {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return 0
+	}
+	n := 0
+	for _, b := range history {
+		if s.started {
+			if b.Start.Before(s.next) {
+				continue
+			}
+			s.fillTo(b.Start)
+		}
+		s.started = true
+		n++
+		if b.End.After(asOf) {
+			s.cur = b
+			s.open = true
+			break
+		}
+		s.ring.Write(b)
+		s.next = b.End
+	}
+	s.seeded = s.next
+	if s.open {
+		s.seeded = asOf
+	}
+	return n
+}
+
+func (s *Series) addTimed(t ringbuffer.Tick) {
+	if t.Time.Before(s.seeded) {
+		return
+	}
+	start := t.Time.Truncate(s.spec.Interval)
+
+	// Same bar, or a late tick for a bar that is still open
+	if s.open && !start.After(s.cur.Start) {
+		s.update(t)
+		return
+	}
+
+	if s.open {
+		s.finalize()
+	}
+	if s.started {
+		// A late tick for an interval that was already closed (for example
+		// by Advance) is folded into the next bar rather than rewriting
+		// history.
+		if start.Before(s.next) {
+			start = s.next
+		}
+		s.fillTo(start)
+	}
+	s.begin(start, start.Add(s.spec.Interval), t)
+}
+
+func (s *Series) addThreshold(t ringbuffer.Tick) {
+	if !s.open {
+		s.begin(t.Time, t.Time, t)
+	} else {
+		s.update(t)
+	}
+
+	switch s.spec.Kind {
+	case Tick:
+		s.measure++
+	case Volume:
+		s.measure += t.Size
+	case Dollar:
+		s.measure += t.Price * t.Size
+	}
+
+	if s.measure >= s.spec.Threshold {
+		s.finalize()
+	}
+}
+
+func (s *Series) begin(start, end time.Time, t ringbuffer.Tick) {
+	s.cur = Bar{
+		Start: start,
+		End:   end,
+		Open:  t.Price,
+		High:  t.Price,
+		Low:   t.Price,
+	}
+	s.open = true
+	s.started = true
+	s.measure = 0
+	s.update(t)
+}
+
+func (s *Series) update(t ringbuffer.Tick) {
+	if t.Price > s.cur.High {
+		s.cur.High = t.Price
+	}
+	if t.Price < s.cur.Low {
+		s.cur.Low = t.Price
+	}
+	s.cur.Close = t.Price
+	s.cur.Volume += t.Size
+	s.cur.Notional += t.Price * t.Size
+	s.cur.Trades++
+	if s.spec.Kind != Time {
+		s.cur.End = t.Time
+	}
+}
+
+func (s *Series) finalize() {
+	s.ring.Write(s.cur)
+	s.next = s.cur.End
+	s.open = false
+	s.measure = 0
+}
+
+// fillTo emits flat, zero-volume bars at the last close for every interval
+// from s.next up to (excluding) boundary. Gaps longer than the ring are
+// skipped ahead, since the older flat bars would be overwritten anyway.
+func (s *Series) fillTo(boundary time.Time) {
+	if !s.next.Before(boundary) {
+		return
+	}
+
+	iv := s.spec.Interval
+	missing := int(boundary.Sub(s.next) / iv)
+	if skip := missing - s.ring.GetSize(); skip > 0 {
+		s.next = s.next.Add(time.Duration(skip) * iv)
+	}
+
+	last, _ := s.ring.GetLast()
+	for s.next.Before(boundary) {
+		s.ring.Write(Bar{
+			Start: s.next,
+			End:   s.next.Add(iv),
+			Open:  last.Close,
+			High:  last.Close,
+			Low:   last.Close,
+			Close: last.Close,
+		})
+		s.next = s.next.Add(iv)
+	}
+}
+
+// ReadLast returns the last n finalized bars, oldest first.
+func (s *Series) ReadLast(n int) []Bar {
+	return s.ring.ReadLast(n)
+}
+
+// ReadRange returns the finalized bars overlapping [from, to), oldest
+// first, followed by the forming bar if it overlaps too. A zero to means no
+// upper bound.
+func (s *Series) ReadRange(from, to time.Time) []Bar {
+	var result []Bar
+	overlaps := func(b *Bar) bool {
+		return b.End.After(from) && (to.IsZero() || b.Start.Before(to))
+	}
+
+	s.ring.Visit(s.ring.GetSize(), func(i int, b *Bar) {
+		if overlaps(b) {
+			result = append(result, *b)
+		}
+	})
+	if cur, ok := s.Current(); ok && overlaps(&cur) {
+		result = append(result, cur)
+	}
+	return result
+}
+
+// GetOldest returns the oldest finalized bar still retained.
+func (s *Series) GetOldest() (Bar, bool) {
+	// Reading from sequence 0 starts at the oldest retained bar
+	var oldest [1]Bar
+	n, _, _ := s.ring.ReadFrom(0, oldest[:])
+	return oldest[0], n > 0
+}
+
+// ReadClosesInto fills dst with the closes of the last len(dst) finalized
+// bars, oldest first, without allocating.
+func (s *Series) ReadClosesInto(dst []float64) int {
+	return s.ring.Visit(len(dst), func(i int, b *Bar) {
+		dst[i] = b.Close
+	})
+}
+
+// Current returns the bar that is still forming, if any.
+func (s *Series) Current() (Bar, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur, s.open
+}
+
+func (s *Series) GetCount() int {
+	return s.ring.GetCount()
+}
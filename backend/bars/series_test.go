@@ -0,0 +1,249 @@
+package bars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var epoch = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// at is a tick ms milliseconds after epoch.
+func at(ms int, price, size float64) ringbuffer.Tick {
+	return ringbuffer.Tick{Symbol: "btcusdt", Time: epoch.Add(time.Duration(ms) * time.Millisecond), Price: price, Size: size}
+}
+
+func minuteSeries(size int) *Series {
+	return NewSeries(Spec{Kind: Time, Interval: time.Minute}, size)
+}
+
+func TestTimeBarBoundaries(t *testing.T) {
+	s := minuteSeries(10)
+	s.Add(at(0, 100, 1))       // opens 14:00
+	s.Add(at(30000, 103, 2))   // high
+	s.Add(at(45000, 99, 1))    // low
+	s.Add(at(59999, 101, 0.5)) // last moment of 14:00
+	s.Add(at(60000, 102, 1))   // the boundary belongs to 14:01
+
+	bars := s.ReadLast(10)
+	if len(bars) != 1 {
+		t.Fatalf("%d finalized bars, want 1", len(bars))
+	}
+	want := Bar{Start: epoch, End: epoch.Add(time.Minute), Open: 100, High: 103, Low: 99, Close: 101, Volume: 4.5,
+		Notional: 100 + 206 + 99 + 50.5, Trades: 4}
+	if bars[0] != want {
+		t.Errorf("bar %+v, want %+v", bars[0], want)
+	}
+	cur, ok := s.Current()
+	if !ok || !cur.Start.Equal(epoch.Add(time.Minute)) || cur.Open != 102 || cur.Trades != 1 {
+		t.Errorf("forming bar %+v", cur)
+	}
+
+	// Advance closes the bar once its interval has ended, not before
+	s.Advance(epoch.Add(119 * time.Second))
+	if s.GetCount() != 1 {
+		t.Fatal("bar closed before its end")
+	}
+	s.Advance(epoch.Add(2 * time.Minute))
+	if s.GetCount() != 2 {
+		t.Fatal("bar not closed at its end")
+	}
+}
+
+func TestLateTicks(t *testing.T) {
+	s := minuteSeries(10)
+	s.Add(at(10000, 100, 1))
+	s.Add(at(70000, 101, 1))
+
+	// Late for a bar that is already closed: folded into the open one
+	s.Add(at(50000, 90, 1))
+	if bars := s.ReadLast(10); len(bars) != 1 || bars[0].Low != 100 || bars[0].Trades != 1 {
+		t.Errorf("closed bar rewritten: %+v", bars)
+	}
+	cur, _ := s.Current()
+	if cur.Low != 90 || cur.Trades != 2 || cur.Close != 90 {
+		t.Errorf("forming bar %+v, want the late tick folded in", cur)
+	}
+
+	// Late after Advance closed the bar: it opens the next interval
+	s.Advance(epoch.Add(2 * time.Minute))
+	s.Add(at(110000, 95, 3))
+	bars := s.ReadLast(10)
+	if len(bars) != 2 || bars[1].Volume != 2 {
+		t.Fatalf("finalized bars %+v", bars)
+	}
+	cur, _ = s.Current()
+	if !cur.Start.Equal(epoch.Add(2*time.Minute)) || cur.Volume != 3 || cur.Open != 95 {
+		t.Errorf("forming bar %+v, want the late tick in 14:02", cur)
+	}
+}
+
+func TestFlatFill(t *testing.T) {
+	s := minuteSeries(10)
+	s.Add(at(10000, 100, 1))
+	s.Add(at(20000, 104, 1))
+	s.Add(at(3*60000+5000, 105, 1)) // 14:01 and 14:02 had no trades
+
+	bars := s.ReadLast(10)
+	if len(bars) != 3 {
+		t.Fatalf("%d bars, want 3: %+v", len(bars), bars)
+	}
+	for i, b := range bars[1:] {
+		start := epoch.Add(time.Duration(i+1) * time.Minute)
+		if !b.Start.Equal(start) || b.Open != 104 || b.High != 104 || b.Low != 104 || b.Close != 104 || b.Volume != 0 || b.Trades != 0 {
+			t.Errorf("flat bar %d: %+v", i, b)
+		}
+	}
+
+	// Quiet periods are filled by Advance too
+	s.Advance(epoch.Add(6 * time.Minute))
+	if got := s.GetCount(); got != 6 {
+		t.Errorf("%d bars after Advance, want 6", got)
+	}
+}
+
+func TestGapLongerThanRing(t *testing.T) {
+	s := minuteSeries(5)
+	s.Add(at(0, 100, 1))
+	s.Add(at(1000*60000, 110, 1)) // 1000 minutes later
+
+	bars := s.ReadLast(5)
+	if len(bars) != 5 {
+		t.Fatalf("%d bars, want a full ring of 5", len(bars))
+	}
+	for i, b := range bars {
+		start := epoch.Add(time.Duration(995+i) * time.Minute)
+		if !b.Start.Equal(start) || b.Close != 100 || b.Trades != 0 {
+			t.Errorf("bar %d: %+v, want flat at 100 from %s", i, b, start)
+		}
+	}
+	if cur, _ := s.Current(); !cur.Start.Equal(epoch.Add(1000 * time.Minute)) {
+		t.Errorf("forming bar starts %s", cur.Start)
+	}
+}
+
+func TestThresholdBars(t *testing.T) {
+	ticks := []ringbuffer.Tick{at(0, 100, 1), at(1, 101, 2), at(2, 102, 0.5), at(3, 99, 3), at(4, 100, 1), at(5, 98, 4)}
+	tests := []struct {
+		spec   Spec
+		trades []int // per finalized bar
+		open   bool  // a bar is left forming
+	}{
+		{Spec{Kind: Tick, Threshold: 2}, []int{2, 2, 2}, false},
+		{Spec{Kind: Tick, Threshold: 4}, []int{4}, true},
+		// 1+2 = 3; 0.5+3 = 3.5; 1+4 = 5
+		{Spec{Kind: Volume, Threshold: 3}, []int{2, 2, 2}, false},
+		// 100+202+51+297 = 650; 100+392 = 492
+		{Spec{Kind: Dollar, Threshold: 400}, []int{4, 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec.Name(), func(t *testing.T) {
+			s := NewSeries(tt.spec, 10)
+			for _, tick := range ticks {
+				s.Add(tick)
+			}
+			bars := s.ReadLast(10)
+			if len(bars) != len(tt.trades) {
+				t.Fatalf("%d bars, want %d: %+v", len(bars), len(tt.trades), bars)
+			}
+			for i, b := range bars {
+				if b.Trades != tt.trades[i] {
+					t.Errorf("bar %d has %d trades, want %d", i, b.Trades, tt.trades[i])
+				}
+				if !b.End.Equal(ticks[sum(tt.trades[:i+1])-1].Time) {
+					t.Errorf("bar %d ends %s, want at its last trade", i, b.End)
+				}
+			}
+			if _, open := s.Current(); open != tt.open {
+				t.Errorf("forming bar %v, want %v", open, tt.open)
+			}
+			// Threshold bars ignore the clock
+			s.Advance(epoch.Add(time.Hour))
+			if s.GetCount() != len(tt.trades) {
+				t.Error("Advance closed a threshold bar")
+			}
+		})
+	}
+}
+
+func sum(values []int) int {
+	n := 0
+	for _, v := range values {
+		n += v
+	}
+	return n
+}
+
+func TestSeed(t *testing.T) {
+	history := []Bar{
+		{Start: epoch, End: epoch.Add(time.Minute), Open: 1, High: 2, Low: 1, Close: 2, Volume: 10},
+		// 14:01 missing
+		{Start: epoch.Add(2 * time.Minute), End: epoch.Add(3 * time.Minute), Open: 2, High: 3, Low: 2, Close: 3, Volume: 5},
+		{Start: epoch.Add(3 * time.Minute), End: epoch.Add(4 * time.Minute), Open: 3, High: 3, Low: 3, Close: 3, Volume: 1},
+	}
+
+	t.Run("forming bar kept open", func(t *testing.T) {
+		s := minuteSeries(10)
+		asOf := epoch.Add(3*time.Minute + 30*time.Second)
+		if n := s.Seed(history, asOf); n != 3 {
+			t.Fatalf("seeded %d bars, want 3", n)
+		}
+		bars := s.ReadLast(10)
+		if len(bars) != 3 || bars[1].Trades != 0 || bars[1].Close != 2 {
+			t.Fatalf("seeded bars %+v, want 14:01 flat at 2", bars)
+		}
+
+		// Ticks before asOf are already in the bars; later ones complete
+		// the forming bar
+		s.Add(at(3*60000+10000, 9, 1))
+		s.Add(at(3*60000+40000, 4, 2))
+		cur, ok := s.Current()
+		if !ok || cur.High != 4 || cur.Close != 4 || cur.Volume != 3 {
+			t.Errorf("forming bar %+v", cur)
+		}
+		if s.Seed(history, asOf) != 0 {
+			t.Error("a started series was seeded again")
+		}
+	})
+
+	t.Run("all bars closed", func(t *testing.T) {
+		s := minuteSeries(10)
+		if n := s.Seed(history, epoch.Add(5*time.Minute)); n != 3 {
+			t.Fatalf("seeded %d bars, want 3", n)
+		}
+		s.Add(at(3*60000+50000, 9, 1)) // covered by the seeded 14:03
+		s.Add(at(4*60000+10000, 4, 2))
+		bars := s.ReadLast(10)
+		if len(bars) != 4 || bars[3].High != 3 {
+			t.Errorf("seeded bars %+v, want 14:03 untouched", bars)
+		}
+		if cur, _ := s.Current(); !cur.Start.Equal(epoch.Add(4*time.Minute)) || cur.Volume != 2 {
+			t.Errorf("forming bar %+v", cur)
+		}
+	})
+
+	t.Run("threshold series", func(t *testing.T) {
+		if n := NewSeries(Spec{Kind: Tick, Threshold: 10}, 10).Seed(history, epoch); n != 0 {
+			t.Errorf("seeded %d tick bars", n)
+		}
+	})
+}
+
+func TestParseSpec(t *testing.T) {
+	for _, name := range []string{"1s", "1m", "5m", "1h", "tick:100", "volume:2.5", "dollar:1000000"} {
+		spec, err := ParseSpec(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if spec.Name() != name {
+			t.Errorf("%s parses to %s", name, spec.Name())
+		}
+	}
+	for _, name := range []string{"", "0s", "-1m", "tick:0", "volume:x", "range:5"} {
+		if _, err := ParseSpec(name); err == nil {
+			t.Errorf("%q accepted", name)
+		}
+	}
+}
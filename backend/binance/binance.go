@@ -0,0 +1,248 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+)
+
+const (
+	Name           = "binance"
+	DefaultURL     = "wss://stream.binance.com:9443"
+	DefaultRESTURL = "https://api.binance.com"
+)
+
+// DefaultStreams are subscribed per symbol when the config names none.
+var DefaultStreams = []string{"trade", "bookTicker", "depth@100ms"}
+
+// bookDepth is how many levels per side a REST book snapshot fetches.
+const bookDepth = 1000
+
+// maxKlines is the most candles /api/v3/klines returns per request.
+const maxKlines = 1000
+
+// klineIntervals are the REST kline intervals up to a day, ascending.
+var klineIntervals = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1s", time.Second},
+	{"1m", time.Minute},
+	{"3m", 3 * time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"2h", 2 * time.Hour},
+	{"4h", 4 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"8h", 8 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+}
+
+// Binance closes every connection at the 24 hour mark, so connections are
+// rotated shortly before that.
+var maxConnectionAge = 23*time.Hour + 30*time.Minute
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed is the Binance combined-stream adapter. All symbols share one
+// connection to <url>/stream and are added with SUBSCRIBE requests.
+type Feed struct {
+	*feed.Stream
+	restURL string
+	client  *http.Client
+	streams []string
+	nextID  int64 // request id; guarded by the Stream's write lock
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	url := cfg.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	streams := append([]string(nil), cfg.Streams...)
+	if len(streams) == 0 {
+		streams = append(streams, DefaultStreams...)
+	}
+	for i, stream := range streams {
+		if stream == "kline" {
+			streams[i] = "kline_1m"
+		}
+	}
+	restURL := cfg.RESTURL
+	if restURL == "" {
+		restURL = DefaultRESTURL
+	}
+	f := &Feed{
+		restURL: strings.TrimSuffix(restURL, "/"),
+		client:  cfg.HTTPClient,
+		streams: streams,
+	}
+	if f.client == nil {
+		f.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	f.Stream = feed.NewStream(feed.StreamConfig{
+		Venue:    Name,
+		URL:      strings.TrimSuffix(url, "/") + "/stream",
+		Policy:   cfg.Policy,
+		Recorder: cfg.Recorder,
+		MaxAge:   maxConnectionAge,
+	}, f)
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ResyncBook fetches a REST depth snapshot in the background. Diffs that
+// arrive meanwhile are queued by the book and replayed on top of it.
+func (f *Feed) ResyncBook(symbol string) error {
+	product, ok := feed.VenueSymbol(Name, symbol)
+	if !ok {
+		return nil
+	}
+	f.Go(func(ctx context.Context) {
+		update, err := f.fetchBook(ctx, product)
+		if err != nil {
+			log.Printf("⚠️  Binance book snapshot for %s failed: %v", symbol, err)
+			return
+		}
+		f.Emit(ctx, feed.Event{Kind: feed.EventBook, Venue: Name, Symbol: symbol, Book: update})
+	})
+	return nil
+}
+
+func (f *Feed) fetchBook(ctx context.Context, product string) (orderbook.Update, error) {
+	url := fmt.Sprintf("%s/api/v3/depth?symbol=%s&limit=%d", f.restURL, product, bookDepth)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return orderbook.Update{}, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return orderbook.Update{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return orderbook.Update{}, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var snap depthSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return orderbook.Update{}, err
+	}
+	return snap.toUpdate(time.Now())
+}
+
+func (f *Feed) Granularities() []time.Duration {
+	out := make([]time.Duration, len(klineIntervals))
+	for i, iv := range klineIntervals {
+		out[i] = iv.duration
+	}
+	return out
+}
+
+func (f *Feed) MaxCandles() int {
+	return maxKlines
+}
+
+// Candles fetches klines from /api/v3/klines, which selects them by open
+// time.
+func (f *Feed) Candles(ctx context.Context, symbol string, granularity time.Duration, start, end time.Time) ([]feed.Kline, error) {
+	product, ok := feed.VenueSymbol(Name, symbol)
+	if !ok {
+		return nil, fmt.Errorf("binance: %s is not listed", symbol)
+	}
+	var interval string
+	for _, iv := range klineIntervals {
+		if iv.duration == granularity {
+			interval = iv.name
+		}
+	}
+	if interval == "" {
+		return nil, fmt.Errorf("binance: no %s klines", granularity)
+	}
+
+	url := fmt.Sprintf("%s/api/v3/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=%d",
+		f.restURL, product, interval, start.UnixMilli(), end.UnixMilli()-1, maxKlines)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var rows []restKline
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	klines := make([]feed.Kline, 0, len(rows))
+	for _, row := range rows {
+		k, err := row.toKline(interval, granularity, now)
+		if err != nil {
+			return nil, err
+		}
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
+
+// Send implements feed.Protocol with a SUBSCRIBE or UNSUBSCRIBE request.
+func (f *Feed) Send(conn *websocket.Conn, subscribe bool, symbols []string) error {
+	method := "SUBSCRIBE"
+	if !subscribe {
+		method = "UNSUBSCRIBE"
+	}
+	f.nextID++
+	return conn.WriteJSON(map[string]interface{}{
+		"method": method,
+		"params": f.streamNames(symbols),
+		"id":     f.nextID,
+	})
+}
+
+// streamNames builds e.g. "btcusdt@trade" for each symbol and stream.
+func (f *Feed) streamNames(symbols []string) []string {
+	names := make([]string, 0, len(symbols)*len(f.streams))
+	for _, symbol := range symbols {
+		product, _ := feed.VenueSymbol(Name, symbol)
+		for _, stream := range f.streams {
+			names = append(names, strings.ToLower(product)+"@"+stream)
+		}
+	}
+	return names
+}
+
+// Session implements feed.Protocol. Unknown streams and products are
+// logged and skipped rather than ending the connection.
+func (f *Feed) Session() feed.Handler {
+	return func(ctx context.Context, frame []byte, receivedAt time.Time) error {
+		ev, ok, err := Parse(frame, receivedAt)
+		if err != nil {
+			log.Printf("⚠️  Binance: %v", err)
+			return nil
+		}
+		if ok && f.Active(ev.Symbol) {
+			f.Emit(ctx, ev)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,418 @@
+package binance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+// restFeed builds a feed whose REST calls go to srv.
+func restFeed(t *testing.T, srv *httptest.Server) *Feed {
+	t.Helper()
+	f, err := New(feed.Config{RESTURL: srv.URL, HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.(*Feed)
+}
+
+func TestCandles(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	forming := now.Truncate(5 * time.Minute)
+	start := forming.Add(-5 * time.Minute)
+
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/klines" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write([]byte(`[
+			[` + ms(start) + `,"64000.0","64100.0","63900.0","64050.0","12.5",` + ms(forming) + `,"800000.0",420,"6","384000.0","0"],
+			[` + ms(forming) + `,"64050.0","64060.0","64010.0","64020.0","1.25",` + ms(forming.Add(5*time.Minute)) + `,"80000.0",30,"1","40000.0","0"]
+		]`))
+	}))
+	defer srv.Close()
+
+	klines, err := restFeed(t, srv).Candles(context.Background(), "btcusdt", 5*time.Minute, start, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "symbol=BTCUSDT&interval=5m&startTime=" + ms(start) + "&endTime=" + ms(now.Add(-time.Millisecond)) + "&limit=1000"; query != want {
+		t.Errorf("query %s, want %s", query, want)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("%d klines, want 2", len(klines))
+	}
+	closed, open := klines[0], klines[1]
+	if !closed.Start.Equal(start) || !closed.End.Equal(forming) || !closed.Closed {
+		t.Errorf("closed kline %+v", closed)
+	}
+	if closed.Open != 64000 || closed.High != 64100 || closed.Low != 63900 || closed.Close != 64050 {
+		t.Errorf("closed kline prices %+v", closed)
+	}
+	if closed.Volume != 12.5 || closed.Notional != 800000 || closed.Trades != 420 || closed.Interval != "5m" {
+		t.Errorf("closed kline volume %+v", closed)
+	}
+	if open.Closed || !open.Start.Equal(forming) {
+		t.Errorf("forming kline %+v", open)
+	}
+}
+
+func TestCandlesErrors(t *testing.T) {
+	body := `[[1714572000000,"1","1","1","1"]]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("interval") == "1h" {
+			http.Error(w, `{"code":-1003}`, http.StatusTeapot)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	f := restFeed(t, srv)
+	now := time.Now()
+	ctx := context.Background()
+
+	if _, err := f.Candles(ctx, "btcusdt", time.Hour, now.Add(-time.Hour), now); err == nil {
+		t.Error("HTTP error not reported")
+	}
+	if _, err := f.Candles(ctx, "btcusdt", time.Minute, now.Add(-time.Hour), now); err == nil {
+		t.Error("short kline row not reported")
+	}
+	if _, err := f.Candles(ctx, "btcusdt", 7*time.Minute, now.Add(-time.Hour), now); err == nil {
+		t.Error("unserved granularity not reported")
+	}
+	if _, err := f.Candles(ctx, "dogeusdt", time.Minute, now.Add(-time.Hour), now); err == nil {
+		t.Error("unlisted symbol not reported")
+	}
+}
+
+func ms(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// fakeStream is a combined-stream endpoint. Each connection must open with
+// a SUBSCRIBE; the first connection is then pinged and sent the captured
+// frames.
+type fakeStream struct {
+	frames   [][]byte
+	requests chan streamRequest
+	pongs    chan string   // pong payloads from the client
+	pings    chan struct{} // client heartbeats
+	conns    atomic.Int32
+	srv      *httptest.Server
+}
+
+type streamRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int64    `json:"id"`
+}
+
+func newFakeStream(t *testing.T) *fakeStream {
+	t.Helper()
+	data, err := os.ReadFile("testdata/stream.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeStream{
+		requests: make(chan streamRequest, 8),
+		pongs:    make(chan string, 4),
+		pings:    make(chan struct{}, 64),
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		s.frames = append(s.frames, line)
+	}
+
+	upgrader := websocket.Upgrader{}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stream" {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n := s.conns.Add(1)
+
+		var req streamRequest
+		if err := conn.ReadJSON(&req); err != nil || req.Method != "SUBSCRIBE" {
+			return
+		}
+		s.requests <- req
+
+		conn.SetPongHandler(func(data string) error {
+			s.pongs <- data
+			return nil
+		})
+		conn.SetPingHandler(func(data string) error {
+			select {
+			case s.pings <- struct{}{}:
+			default:
+			}
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				var req streamRequest
+				if err := conn.ReadJSON(&req); err != nil {
+					return
+				}
+				s.requests <- req
+			}
+		}()
+
+		if n == 1 {
+			conn.WriteControl(websocket.PingMessage, []byte("keepalive-1"), time.Now().Add(time.Second))
+			for _, frame := range s.frames {
+				if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+					return
+				}
+			}
+		}
+		<-closed
+	}))
+	return s
+}
+
+func (s *fakeStream) url() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+func (s *fakeStream) request(t *testing.T) streamRequest {
+	t.Helper()
+	select {
+	case req := <-s.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request")
+		return streamRequest{}
+	}
+}
+
+func next(t *testing.T, events <-chan feed.Event) feed.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return feed.Event{}
+	}
+}
+
+func TestStream(t *testing.T) {
+	stream := newFakeStream(t)
+	defer stream.srv.Close()
+
+	defer func(age time.Duration) { maxConnectionAge = age }(maxConnectionAge)
+	maxConnectionAge = time.Second
+
+	f, err := New(feed.Config{
+		URL:     stream.url(),
+		Symbols: []string{"btcusdt", "ethusdt"},
+		Streams: []string{"trade", "aggTrade", "bookTicker", "kline", "depth@100ms", "depth5@100ms"},
+		Policy:  feed.Policy{PingInterval: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	params := stream.request(t).Params
+	want := "btcusdt@trade btcusdt@aggTrade btcusdt@bookTicker btcusdt@kline_1m btcusdt@depth@100ms btcusdt@depth5@100ms " +
+		"ethusdt@trade ethusdt@aggTrade ethusdt@bookTicker ethusdt@kline_1m ethusdt@depth@100ms ethusdt@depth5@100ms"
+	if got := strings.Join(params, " "); got != want {
+		t.Errorf("subscribed to %s\nwant %s", got, want)
+	}
+
+	events := f.Events()
+	if ev := next(t, events); ev.Kind != feed.EventStatus || !ev.Connected || len(ev.Symbols) != 2 {
+		t.Fatalf("first event %+v, want connected status", ev)
+	}
+
+	ev := next(t, events)
+	if ev.Kind != feed.EventTrade || ev.Symbol != "btcusdt" || ev.Venue != Name {
+		t.Fatalf("trade event %+v", ev)
+	}
+	if tick := ev.Tick; tick.Price != 64012.34 || tick.Size != 0.0125 || tick.Side != "sell" || ev.TradeID != 3561744012 ||
+		!tick.Time.Equal(time.UnixMilli(1714572000122)) || tick.ReceivedAt.IsZero() {
+		t.Errorf("trade %+v id %d", tick, ev.TradeID)
+	}
+
+	ev = next(t, events)
+	if ev.Kind != feed.EventTrade || ev.Symbol != "ethusdt" || ev.Tick.Side != "buy" || ev.TradeID != 1189371022 || ev.Tick.Price != 3001.25 {
+		t.Errorf("aggTrade event %+v", ev)
+	}
+
+	ev = next(t, events)
+	if q := ev.Quote; ev.Kind != feed.EventQuote || q.Bid != 64012.33 || q.BidSize != 2.1042 || q.Ask != 64012.34 || q.AskSize != 0.5183 || q.Sequence != 46711283391 {
+		t.Errorf("bookTicker event %+v", ev)
+	}
+
+	ev = next(t, events)
+	if k := ev.Kline; ev.Kind != feed.EventKline || k.Interval != "1m" || !k.Closed || k.Open != 64012.34 || k.High != 64051.1 ||
+		k.Low != 63998 || k.Close != 64020 || k.Volume != 41.231 || k.Notional != 2639555.1921 || k.Trades != 1091 {
+		t.Errorf("kline event %+v", ev)
+	}
+
+	ev = next(t, events)
+	if b := ev.Book; ev.Kind != feed.EventBook || b.Snapshot || b.FirstSeq != 46711283392 || b.LastSeq != 46711283398 ||
+		len(b.Bids) != 2 || b.Bids[1].Size != 0 || len(b.Asks) != 1 {
+		t.Errorf("depth diff event %+v", ev)
+	}
+
+	ev = next(t, events)
+	if b := ev.Book; ev.Kind != feed.EventBook || ev.Symbol != "ethusdt" || !b.Snapshot || b.LastSeq != 32901122871 || len(b.Bids) != 2 {
+		t.Errorf("partial depth event %+v", ev)
+	}
+
+	// The unlisted product and unknown stream are skipped
+	if ev = next(t, events); ev.Kind != feed.EventTrade || ev.TradeID != 3561744013 || ev.Tick.Side != "buy" {
+		t.Errorf("last trade event %+v", ev)
+	}
+
+	select {
+	case data := <-stream.pongs:
+		if data != "keepalive-1" {
+			t.Errorf("pong payload %q, want the ping's", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("server ping not answered")
+	}
+	select {
+	case <-stream.pings:
+	case <-time.After(5 * time.Second):
+		t.Error("client never pinged")
+	}
+
+	// At its age limit the connection is replaced without reporting an
+	// outage
+	if req := stream.request(t); req.Method != "SUBSCRIBE" || len(req.Params) != 12 {
+		t.Errorf("after rotation %s %d streams, want SUBSCRIBE 12", req.Method, len(req.Params))
+	}
+	if n := stream.conns.Load(); n != 2 {
+		t.Errorf("%d connections, want 2", n)
+	}
+	if ev = next(t, events); ev.Kind != feed.EventStatus || !ev.Connected {
+		t.Errorf("event after rotation %+v, want connected status", ev)
+	}
+	if h := f.Health(); h.Reconnects != 1 || h.Connections != 1 || h.State != feed.StateConnected || h.LastError != "" {
+		t.Errorf("health after rotation %+v", h)
+	}
+	if h := f.Health(); h.Messages < uint64(len(stream.frames)) {
+		t.Errorf("%d messages counted, want at least %d", h.Messages, len(stream.frames))
+	}
+}
+
+func TestStreamSubscriptionChanges(t *testing.T) {
+	stream := newFakeStream(t)
+	defer stream.srv.Close()
+
+	f, err := New(feed.Config{URL: stream.url(), Symbols: []string{"btcusdt"}, Streams: []string{"trade"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if req := stream.request(t); strings.Join(req.Params, " ") != "btcusdt@trade" {
+		t.Errorf("subscribed to %v", req.Params)
+	}
+	events := f.Events()
+	next(t, events) // connected
+
+	// Frames of other symbols in the capture are dropped
+	for ev := next(t, events); ev.TradeID != 3561744013; ev = next(t, events) {
+		if ev.Symbol != "btcusdt" {
+			t.Errorf("event for unsubscribed %s", ev.Symbol)
+		}
+	}
+
+	if err := f.Subscribe([]string{"ethusdt", "btcusdt"}); err != nil {
+		t.Fatal(err)
+	}
+	if req := stream.request(t); req.Method != "SUBSCRIBE" || strings.Join(req.Params, " ") != "ethusdt@trade" || req.ID != 2 {
+		t.Errorf("runtime subscribe %+v", req)
+	}
+	if ev := next(t, events); ev.Kind != feed.EventStatus || !ev.Connected || strings.Join(ev.Symbols, " ") != "ethusdt" {
+		t.Errorf("subscribe event %+v", ev)
+	}
+
+	if err := f.Unsubscribe([]string{"btcusdt"}); err != nil {
+		t.Fatal(err)
+	}
+	if req := stream.request(t); req.Method != "UNSUBSCRIBE" || strings.Join(req.Params, " ") != "btcusdt@trade" {
+		t.Errorf("runtime unsubscribe %+v", req)
+	}
+	if ev := next(t, events); ev.Kind != feed.EventStatus || ev.Connected || strings.Join(ev.Symbols, " ") != "btcusdt" {
+		t.Errorf("unsubscribe event %+v", ev)
+	}
+	if h := f.Health(); strings.Join(h.Symbols, " ") != "ethusdt" {
+		t.Errorf("health symbols %v", h.Symbols)
+	}
+
+	f.Stop()
+	for range events {
+	}
+	if h := f.Health(); h.State != feed.StateStopped {
+		t.Errorf("state %s after Stop", h.State)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		ok      bool
+		kind    feed.EventKind
+		wantErr string
+	}{
+		{"trade", `{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","t":1,"p":"1.5","q":"2","T":1714572000000}}`, true, feed.EventTrade, ""},
+		{"control reply", `{"result":null,"id":1}`, false, 0, ""},
+		{"unknown stream", `{"stream":"btcusdt@markPrice","data":{}}`, false, 0, ErrUnknownStream.Error()},
+		{"unknown product", `{"stream":"xrpusdt@trade","data":{}}`, false, 0, "unknown product"},
+		{"bad decimal", `{"stream":"btcusdt@trade","data":{"e":"trade","p":"abc","q":"1"}}`, false, 0, "bad decimal"},
+		{"malformed", `{"stream":`, false, 0, "unexpected end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, ok, err := Parse([]byte(tt.frame), time.Now())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok || (ok && ev.Kind != tt.kind) {
+				t.Errorf("ok %v kind %v, want %v %v", ok, ev.Kind, tt.ok, tt.kind)
+			}
+		})
+	}
+
+	_, _, err := Parse([]byte(`{"stream":"ethusdt@forceOrder","data":{}}`), time.Now())
+	if !errors.Is(err, ErrUnknownStream) {
+		t.Errorf("err %v is not ErrUnknownStream", err)
+	}
+}
@@ -0,0 +1,310 @@
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// envelope is the combined-stream wrapper around every payload.
+type envelope struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Binance keys differ only by case ("e"/"E", "m"/"M", ...), and
+// encoding/json falls back to case-insensitive matching, so every colliding
+// key is declared even when unused.
+
+type tradeMessage struct {
+	Event      string `json:"e"`
+	EventTime  int64  `json:"E"`
+	Symbol     string `json:"s"`
+	TradeID    int64  `json:"t"`
+	AggTradeID int64  `json:"a"`
+	Price      string `json:"p"`
+	Quantity   string `json:"q"`
+	TradeTime  int64  `json:"T"`
+	BuyerMaker bool   `json:"m"`
+	Ignore     bool   `json:"M"`
+}
+
+type bookTickerMessage struct {
+	UpdateID int64  `json:"u"`
+	Symbol   string `json:"s"`
+	Bid      string `json:"b"`
+	BidSize  string `json:"B"`
+	Ask      string `json:"a"`
+	AskSize  string `json:"A"`
+}
+
+type depthMessage struct {
+	Event     string     `json:"e"`
+	EventTime int64      `json:"E"`
+	Symbol    string     `json:"s"`
+	FirstID   int64      `json:"U"`
+	LastID    int64      `json:"u"`
+	Bids      [][]string `json:"b"`
+	Asks      [][]string `json:"a"`
+}
+
+// depthSnapshot is both the partial book stream payload and the REST
+// /api/v3/depth response.
+type depthSnapshot struct {
+	LastUpdateID int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
+type klineMessage struct {
+	Symbol string `json:"s"`
+	Kline  struct {
+		Start    int64  `json:"t"`
+		End      int64  `json:"T"`
+		Interval string `json:"i"`
+		Open     string `json:"o"`
+		Close    string `json:"c"`
+		High     string `json:"h"`
+		Low      string `json:"l"`
+		LastID   int64  `json:"L"`
+		Volume   string `json:"v"`
+		BuyBase  string `json:"V"`
+		Quote    string `json:"q"`
+		BuyQuote string `json:"Q"`
+		Trades   int64  `json:"n"`
+		Closed   bool   `json:"x"`
+	} `json:"k"`
+}
+
+// ErrUnknownStream is returned by Parse for streams the adapter does not
+// handle.
+var ErrUnknownStream = errors.New("binance: unknown stream")
+
+// Parse decodes one combined-stream frame into a normalized event. Control
+// replies such as {"result":null,"id":1} return ok == false and no error.
+func Parse(frame []byte, receivedAt time.Time) (ev feed.Event, ok bool, err error) {
+	var env envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return ev, false, err
+	}
+	if env.Stream == "" {
+		return ev, false, nil
+	}
+
+	product, kind, _ := strings.Cut(env.Stream, "@")
+	symbol, known := feed.Normalize(Name, product)
+	if !known {
+		return ev, false, fmt.Errorf("binance: unknown product %q", product)
+	}
+	ev = feed.Event{Venue: Name, Symbol: symbol}
+
+	switch {
+	case kind == "trade" || kind == "aggTrade":
+		var msg tradeMessage
+		if err := json.Unmarshal(env.Data, &msg); err != nil {
+			return ev, false, err
+		}
+		ev.Kind = feed.EventTrade
+		ev.Tick, err = msg.toTick(symbol, receivedAt)
+		ev.TradeID = ev.Tick.Sequence
+
+	case kind == "bookTicker":
+		var msg bookTickerMessage
+		if err := json.Unmarshal(env.Data, &msg); err != nil {
+			return ev, false, err
+		}
+		ev.Kind = feed.EventQuote
+		ev.Quote, err = msg.toQuote(receivedAt)
+
+	case strings.HasPrefix(kind, "depth"):
+		ev.Kind = feed.EventBook
+		// depth5/10/20 streams carry whole top-N books; plain depth carries
+		// diffs against the REST snapshot
+		if len(kind) > len("depth") && kind[len("depth")] >= '0' && kind[len("depth")] <= '9' {
+			var msg depthSnapshot
+			if err := json.Unmarshal(env.Data, &msg); err != nil {
+				return ev, false, err
+			}
+			ev.Book, err = msg.toUpdate(receivedAt)
+		} else {
+			var msg depthMessage
+			if err := json.Unmarshal(env.Data, &msg); err != nil {
+				return ev, false, err
+			}
+			ev.Book, err = msg.toUpdate(receivedAt)
+		}
+
+	case strings.HasPrefix(kind, "kline_"):
+		var msg klineMessage
+		if err := json.Unmarshal(env.Data, &msg); err != nil {
+			return ev, false, err
+		}
+		ev.Kind = feed.EventKline
+		ev.Kline, err = msg.toKline()
+
+	default:
+		return ev, false, fmt.Errorf("%w: %s", ErrUnknownStream, env.Stream)
+	}
+	if err != nil {
+		return ev, false, err
+	}
+	return ev, true, nil
+}
+
+func (msg tradeMessage) toTick(symbol string, receivedAt time.Time) (ringbuffer.Tick, error) {
+	var p parser
+	tick := ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       time.UnixMilli(msg.TradeTime),
+		ReceivedAt: receivedAt,
+		Price:      p.float(msg.Price),
+		Size:       p.float(msg.Quantity),
+		Sequence:   msg.TradeID,
+	}
+	if msg.Event == "aggTrade" {
+		tick.Sequence = msg.AggTradeID
+	}
+	if msg.TradeTime == 0 {
+		tick.Time = receivedAt
+	}
+	// The aggressor is whichever side was not resting on the book
+	if msg.BuyerMaker {
+		tick.Side = "sell"
+	} else {
+		tick.Side = "buy"
+	}
+	return tick, p.err
+}
+
+func (msg bookTickerMessage) toQuote(receivedAt time.Time) (feed.Quote, error) {
+	var p parser
+	quote := feed.Quote{
+		Time:     receivedAt,
+		Bid:      p.float(msg.Bid),
+		BidSize:  p.float(msg.BidSize),
+		Ask:      p.float(msg.Ask),
+		AskSize:  p.float(msg.AskSize),
+		Sequence: msg.UpdateID,
+	}
+	return quote, p.err
+}
+
+func (msg klineMessage) toKline() (feed.Kline, error) {
+	var p parser
+	k := msg.Kline
+	kline := feed.Kline{
+		Interval: k.Interval,
+		Start:    time.UnixMilli(k.Start),
+		End:      time.UnixMilli(k.End),
+		Open:     p.float(k.Open),
+		High:     p.float(k.High),
+		Low:      p.float(k.Low),
+		Close:    p.float(k.Close),
+		Volume:   p.float(k.Volume),
+		Notional: p.float(k.Quote),
+		Trades:   k.Trades,
+		Closed:   k.Closed,
+	}
+	return kline, p.err
+}
+
+// restKline is one row of /api/v3/klines: open time, open, high, low,
+// close, volume, close time, quote volume, trade count and taker volumes.
+type restKline []json.RawMessage
+
+func (row restKline) toKline(interval string, granularity time.Duration, now time.Time) (feed.Kline, error) {
+	if len(row) < 9 {
+		return feed.Kline{}, fmt.Errorf("binance: short kline row")
+	}
+	var (
+		start, trades                     int64
+		open, high, low, last, vol, quote string
+	)
+	for i, dst := range []interface{}{&start, &open, &high, &low, &last, &vol} {
+		if err := json.Unmarshal(row[i], dst); err != nil {
+			return feed.Kline{}, fmt.Errorf("binance: bad kline field %d: %w", i, err)
+		}
+	}
+	if err := json.Unmarshal(row[7], &quote); err != nil {
+		return feed.Kline{}, fmt.Errorf("binance: bad kline quote volume: %w", err)
+	}
+	if err := json.Unmarshal(row[8], &trades); err != nil {
+		return feed.Kline{}, fmt.Errorf("binance: bad kline trade count: %w", err)
+	}
+
+	var p parser
+	kline := feed.Kline{
+		Interval: interval,
+		Start:    time.UnixMilli(start),
+		End:      time.UnixMilli(start).Add(granularity),
+		Open:     p.float(open),
+		High:     p.float(high),
+		Low:      p.float(low),
+		Close:    p.float(last),
+		Volume:   p.float(vol),
+		Notional: p.float(quote),
+		Trades:   trades,
+	}
+	kline.Closed = !kline.End.After(now)
+	return kline, p.err
+}
+
+func (msg depthMessage) toUpdate(receivedAt time.Time) (orderbook.Update, error) {
+	var p parser
+	u := orderbook.Update{
+		Time:     receivedAt,
+		Bids:     p.levels(msg.Bids),
+		Asks:     p.levels(msg.Asks),
+		FirstSeq: msg.FirstID,
+		LastSeq:  msg.LastID,
+	}
+	if msg.EventTime != 0 {
+		u.Time = time.UnixMilli(msg.EventTime)
+	}
+	return u, p.err
+}
+
+func (msg depthSnapshot) toUpdate(receivedAt time.Time) (orderbook.Update, error) {
+	var p parser
+	u := orderbook.Update{
+		Snapshot: true,
+		Time:     receivedAt,
+		Bids:     p.levels(msg.Bids),
+		Asks:     p.levels(msg.Asks),
+		FirstSeq: msg.LastUpdateID,
+		LastSeq:  msg.LastUpdateID,
+	}
+	return u, p.err
+}
+
+// parser converts Binance's decimal strings, keeping the first error.
+type parser struct {
+	err error
+}
+
+func (p *parser) float(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("binance: bad decimal %q", s)
+	}
+	return v
+}
+
+func (p *parser) levels(raw [][]string) []orderbook.Level {
+	out := make([]orderbook.Level, 0, len(raw))
+	for _, l := range raw {
+		if len(l) < 2 {
+			p.float("")
+			continue
+		}
+		out = append(out, orderbook.Level{Price: p.float(l[0]), Size: p.float(l[1])})
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package bitstamp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+const (
+	Name       = "bitstamp"
+	DefaultURL = "wss://ws.bitstamp.net"
+)
+
+// DefaultChannels are subscribed for every pair when the config names none.
+// order_book sends the top 100 levels whole on every change, so the book
+// never needs resyncing.
+var DefaultChannels = []string{"live_trades", "order_book"}
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed is the Bitstamp WebSocket v2 adapter. Every pair shares one
+// connection, with one subscription per channel and pair.
+type Feed struct {
+	*feed.Stream
+	channels []string
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	url := cfg.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	channels := append([]string(nil), cfg.Streams...)
+	if len(channels) == 0 {
+		channels = append(channels, DefaultChannels...)
+	}
+	f := &Feed{channels: channels}
+	f.Stream = feed.NewStream(feed.StreamConfig{
+		Venue:    Name,
+		URL:      url,
+		Policy:   cfg.Policy,
+		Recorder: cfg.Recorder,
+	}, f)
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Send implements feed.Protocol with one request per channel and pair.
+func (f *Feed) Send(conn *websocket.Conn, subscribe bool, symbols []string) error {
+	event := "bts:subscribe"
+	if !subscribe {
+		event = "bts:unsubscribe"
+	}
+	for _, symbol := range symbols {
+		pair, _ := feed.VenueSymbol(Name, symbol)
+		for _, channel := range f.channels {
+			err := conn.WriteJSON(map[string]interface{}{
+				"event": event,
+				"data":  map[string]string{"channel": channel + "_" + pair},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// route splits a channel name into the channel and the subscribed symbol.
+func (f *Feed) route(channel string) (string, string, bool) {
+	for _, c := range f.channels {
+		pair, ok := strings.CutPrefix(channel, c+"_")
+		if !ok {
+			continue
+		}
+		symbol, ok := feed.Normalize(Name, pair)
+		if !ok || !f.Active(symbol) {
+			return "", "", false
+		}
+		return c, symbol, true
+	}
+	return "", "", false
+}
+
+// Session implements feed.Protocol. A bts:request_reconnect ends the
+// connection with feed.ErrReconnect, so maintenance is not an outage.
+func (f *Feed) Session() feed.Handler {
+	// Trade ids increase across all pairs, so they show order but not gaps
+	lastTrade := make(map[string]int64)
+
+	return func(ctx context.Context, message []byte, receivedAt time.Time) error {
+		var msg Message
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return nil
+		}
+
+		switch msg.Event {
+		case "trade":
+			_, symbol, ok := f.route(msg.Channel)
+			if !ok {
+				return nil
+			}
+			var t trade
+			if err := json.Unmarshal(msg.Data, &t); err != nil {
+				return nil
+			}
+			if t.ID <= lastTrade[symbol] {
+				f.Tracker().OutOfOrder()
+				return nil
+			}
+			lastTrade[symbol] = t.ID
+			f.Emit(ctx, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: t.tick(symbol, receivedAt), TradeID: t.ID})
+		case "data":
+			channel, symbol, ok := f.route(msg.Channel)
+			if !ok || channel != "order_book" {
+				return nil
+			}
+			var b book
+			if err := json.Unmarshal(msg.Data, &b); err != nil {
+				return nil
+			}
+			u := b.update(receivedAt)
+			if q, ok := quote(u); ok {
+				f.Emit(ctx, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: q})
+			}
+			f.Emit(ctx, feed.Event{Kind: feed.EventBook, Venue: Name, Symbol: symbol, Book: u})
+		case "bts:request_reconnect":
+			return fmt.Errorf("%w by server ahead of maintenance", feed.ErrReconnect)
+		case "bts:error":
+			var e errorData
+			json.Unmarshal(msg.Data, &e)
+			log.Printf("⚠️  Bitstamp error: %v %s", e.Code, e.Message)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package bitstamp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+)
+
+func TestParse(t *testing.T) {
+	receivedAt := time.Date(2024, 5, 1, 14, 0, 1, 0, time.UTC)
+
+	events, err := Parse([]byte(`{"event":"trade","channel":"live_trades_btcusd","data":{"id":351,"amount":0.05,"amount_str":"0.05","price":64000.5,"price_str":"64000.5","type":1,"timestamp":"1714572000","microtimestamp":"1714572000123456","buy_order_id":1,"sell_order_id":2}}`), receivedAt)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("trade: %+v %v", events, err)
+	}
+	ev := events[0]
+	if ev.Kind != feed.EventTrade || ev.Symbol != "btcusdt" || ev.TradeID != 351 {
+		t.Fatalf("trade %+v", ev)
+	}
+	tick := ev.Tick
+	if tick.Price != 64000.5 || tick.Size != 0.05 || tick.Side != "sell" || tick.Sequence != 351 || !tick.ReceivedAt.Equal(receivedAt) {
+		t.Errorf("tick %+v", tick)
+	}
+	if want := time.Date(2024, 5, 1, 14, 0, 0, 123456000, time.UTC); !tick.Time.Equal(want) {
+		t.Errorf("time %s, want %s", tick.Time, want)
+	}
+
+	events, err = Parse([]byte(`{"event":"data","channel":"order_book_ethusd","data":{"timestamp":"1714572000","microtimestamp":"1714572000500000","bids":[["3400.10","2.5"],["3400.00","1"],["bad"]],"asks":[["3400.20","0.75"]]}}`), receivedAt)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("book: %+v %v", events, err)
+	}
+	q, b := events[0], events[1]
+	at := time.Date(2024, 5, 1, 14, 0, 0, 500000000, time.UTC)
+	if q.Kind != feed.EventQuote || q.Symbol != "ethusdt" || !q.Quote.Time.Equal(at) {
+		t.Errorf("quote %+v", q)
+	}
+	if q.Quote.Bid != 3400.1 || q.Quote.BidSize != 2.5 || q.Quote.Ask != 3400.2 || q.Quote.AskSize != 0.75 {
+		t.Errorf("quote %+v", q)
+	}
+	if b.Kind != feed.EventBook || !b.Book.Snapshot || !b.Book.Time.Equal(at) {
+		t.Errorf("book %+v", b)
+	}
+	if got, want := fmt.Sprint(b.Book.Bids), fmt.Sprint([]orderbook.Level{{Price: 3400.1, Size: 2.5}, {Price: 3400, Size: 1}}); got != want {
+		t.Errorf("bids %s, want %s", got, want)
+	}
+
+	// A one-sided book has no top to quote
+	events, err = Parse([]byte(`{"event":"data","channel":"order_book_btcusd","data":{"microtimestamp":"","bids":[["64000","1"]],"asks":[]}}`), receivedAt)
+	if err != nil || len(events) != 1 || events[0].Kind != feed.EventBook || !events[0].Book.Time.Equal(receivedAt) {
+		t.Fatalf("one-sided book: %+v %v", events, err)
+	}
+
+	for _, frame := range []string{
+		`{"event":"bts:subscription_succeeded","channel":"live_trades_btcusd","data":{}}`,
+		`{"event":"trade","channel":"live_trades_dogeusd","data":{"id":1,"price":0.1}}`,
+		`{"event":"trade","channel":"live_orders_btcusd","data":{}}`,
+		`{"event":"bts:heartbeat","channel":"","data":{"status":"success"}}`,
+	} {
+		if events, err := Parse([]byte(frame), receivedAt); err != nil || len(events) != 0 {
+			t.Errorf("%s: %+v %v", frame, events, err)
+		}
+	}
+	for _, frame := range []string{`{"event":`, `{"event":"trade","channel":"live_trades_btcusd","data":{"price":"x"}}`} {
+		if _, err := Parse([]byte(frame), receivedAt); err == nil {
+			t.Errorf("%s parsed", frame)
+		}
+	}
+}
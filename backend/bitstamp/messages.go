@@ -0,0 +1,155 @@
+package bitstamp
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Message is the envelope around every Bitstamp event. The channel name
+// is "<channel>_<pair>", e.g. "live_trades_btcusd".
+type Message struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type trade struct {
+	ID             int64   `json:"id"`
+	Amount         float64 `json:"amount"`
+	Price          float64 `json:"price"`
+	Type           int     `json:"type"` // 0 buy, 1 sell; the taker's side
+	Microtimestamp string  `json:"microtimestamp"`
+}
+
+// book is the order_book channel payload: the top 100 levels per side,
+// sent whole on every change.
+type book struct {
+	Microtimestamp string     `json:"microtimestamp"`
+	Bids           [][]string `json:"bids"`
+	Asks           [][]string `json:"asks"`
+}
+
+type errorData struct {
+	Code    interface{} `json:"code"`
+	Message string      `json:"message"`
+}
+
+// Parse converts one recorded Bitstamp frame into events: trades become
+// trade events, and an order book yields its top as a quote followed by
+// the book itself. Frames that carry no market data and pairs that are not
+// listed yield none.
+func Parse(frame []byte, receivedAt time.Time) ([]feed.Event, error) {
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, err
+	}
+	switch msg.Event {
+	case "trade":
+		pair, ok := strings.CutPrefix(msg.Channel, "live_trades_")
+		if !ok {
+			return nil, nil
+		}
+		symbol, ok := feed.Normalize(Name, pair)
+		if !ok {
+			return nil, nil
+		}
+		var t trade
+		if err := json.Unmarshal(msg.Data, &t); err != nil {
+			return nil, err
+		}
+		return []feed.Event{{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: t.tick(symbol, receivedAt), TradeID: t.ID}}, nil
+	case "data":
+		pair, ok := strings.CutPrefix(msg.Channel, "order_book_")
+		if !ok {
+			return nil, nil
+		}
+		symbol, ok := feed.Normalize(Name, pair)
+		if !ok {
+			return nil, nil
+		}
+		var b book
+		if err := json.Unmarshal(msg.Data, &b); err != nil {
+			return nil, err
+		}
+		u := b.update(receivedAt)
+		var events []feed.Event
+		if q, ok := quote(u); ok {
+			events = append(events, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: q})
+		}
+		return append(events, feed.Event{Kind: feed.EventBook, Venue: Name, Symbol: symbol, Book: u}), nil
+	}
+	return nil, nil
+}
+
+// parseTime converts a microsecond Unix timestamp string.
+func parseTime(s string, receivedAt time.Time) time.Time {
+	us, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || us == 0 {
+		return receivedAt
+	}
+	return time.UnixMicro(us)
+}
+
+func (t trade) tick(symbol string, receivedAt time.Time) ringbuffer.Tick {
+	tick := ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       parseTime(t.Microtimestamp, receivedAt),
+		ReceivedAt: receivedAt,
+		Price:      t.Price,
+		Size:       t.Amount,
+		Sequence:   t.ID,
+	}
+	switch t.Type {
+	case 0:
+		tick.Side = "buy"
+	case 1:
+		tick.Side = "sell"
+	}
+	return tick
+}
+
+// update converts a book payload into an order book snapshot.
+func (b book) update(receivedAt time.Time) orderbook.Update {
+	return orderbook.Update{
+		Snapshot: true,
+		Time:     parseTime(b.Microtimestamp, receivedAt),
+		Bids:     levels(b.Bids),
+		Asks:     levels(b.Asks),
+	}
+}
+
+// quote is the top of a book update, if both sides have a level.
+func quote(u orderbook.Update) (feed.Quote, bool) {
+	if len(u.Bids) == 0 || len(u.Asks) == 0 {
+		return feed.Quote{}, false
+	}
+	return feed.Quote{
+		Time:    u.Time,
+		Bid:     u.Bids[0].Price,
+		BidSize: u.Bids[0].Size,
+		Ask:     u.Asks[0].Price,
+		AskSize: u.Asks[0].Size,
+	}, true
+}
+
+// levels parses [price, size] pairs.
+func levels(raw [][]string) []orderbook.Level {
+	out := make([]orderbook.Level, 0, len(raw))
+	for _, l := range raw {
+		if len(l) < 2 {
+			continue
+		}
+		price, err := strconv.ParseFloat(l[0], 64)
+		size, errSize := strconv.ParseFloat(l[1], 64)
+		if err == nil && errSize == nil {
+			out = append(out, orderbook.Level{Price: price, Size: size})
+		}
+	}
+	return out
+}
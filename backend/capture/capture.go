@@ -0,0 +1,285 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record is one line of a capture file: a frame exactly as a venue sent it
+// and when it arrived.
+type Record struct {
+	Venue    string          `json:"venue"`
+	Received time.Time       `json:"received"`
+	Frame    json.RawMessage `json:"frame"`
+}
+
+const (
+	filePrefix = "capture-"
+	fileExt    = ".jsonl.gz"
+	hourLayout = "2006-01-02T15"
+)
+
+type Options struct {
+	Retention     time.Duration // delete files older than this; 0 keeps all
+	FlushInterval time.Duration // how often buffered lines reach the disk
+	QueueSize     int           // frames held for the writer before dropping
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Retention:     3 * 24 * time.Hour,
+		FlushInterval: time.Second,
+		QueueSize:     8192,
+	}
+}
+
+type line struct {
+	hour time.Time
+	data []byte
+}
+
+// Recorder tees raw frames to gzipped JSON lines files under dir, one per
+// UTC hour of receipt, so names sort chronologically and replay can glob
+// them. A single writer goroutine does the I/O; when it falls behind, frames
+// are dropped rather than stalling the feeds, and Dropped counts them.
+type Recorder struct {
+	dir     string
+	opts    Options
+	lines   chan line
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+	frames  atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+func Open(dir string, opts Options) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultOptions().QueueSize
+	}
+	r := &Recorder{
+		dir:   dir,
+		opts:  opts,
+		lines: make(chan line, opts.QueueSize),
+		done:  make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.write()
+	return r, nil
+}
+
+// Record implements feed.Recorder.
+func (r *Recorder) Record(venue string, receivedAt time.Time, frame []byte) {
+	if !json.Valid(frame) {
+		// Nothing we could replay
+		r.errors.Add(1)
+		return
+	}
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	select {
+	case r.lines <- line{hour: receivedAt.UTC().Truncate(time.Hour), data: encode(venue, receivedAt, frame)}:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// encode builds a Record line by hand so the frame keeps its exact bytes;
+// json.Marshal would re-compact it. Only a frame spanning lines is
+// compacted, as it must fit on one.
+func encode(venue string, receivedAt time.Time, frame []byte) []byte {
+	venueJSON, _ := json.Marshal(venue)
+	var b bytes.Buffer
+	b.Grow(len(frame) + 80)
+	b.WriteString(`{"venue":`)
+	b.Write(venueJSON)
+	b.WriteString(`,"received":"`)
+	b.WriteString(receivedAt.UTC().Format(time.RFC3339Nano))
+	b.WriteString(`","frame":`)
+	if bytes.ContainsAny(frame, "\r\n") {
+		json.Compact(&b, frame)
+	} else {
+		b.Write(frame)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func (r *Recorder) write() {
+	defer r.wg.Done()
+
+	var (
+		hour time.Time
+		file *os.File
+		gz   *gzip.Writer
+		buf  *bufio.Writer
+	)
+	flush := func() {
+		if buf == nil {
+			return
+		}
+		if err := buf.Flush(); err != nil {
+			r.errors.Add(1)
+			return
+		}
+		if err := gz.Flush(); err != nil {
+			r.errors.Add(1)
+		}
+	}
+	closeFile := func() {
+		if file == nil {
+			return
+		}
+		if err := buf.Flush(); err != nil {
+			r.errors.Add(1)
+		}
+		if err := gz.Close(); err != nil {
+			r.errors.Add(1)
+		}
+		if err := file.Close(); err != nil {
+			r.errors.Add(1)
+		}
+		file, gz, buf = nil, nil, nil
+	}
+	defer closeFile()
+
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	put := func(l line) {
+		if !l.hour.Equal(hour) {
+			closeFile()
+			// A file that cannot be opened is retried next hour, not on
+			// every frame
+			hour = l.hour
+			// Appending after a restart within the hour adds a second gzip
+			// member, which readers decompress transparently
+			f, err := os.OpenFile(Path(r.dir, l.hour), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				log.Printf("⚠️  Capture file failed: %v", err)
+			} else {
+				file = f
+				gz = gzip.NewWriter(f)
+				buf = bufio.NewWriterSize(gz, 64<<10)
+			}
+			r.enforceRetention(time.Now(), hour)
+		}
+		if file == nil {
+			r.errors.Add(1)
+			return
+		}
+		if _, err := buf.Write(l.data); err != nil {
+			r.errors.Add(1)
+			return
+		}
+		r.frames.Add(1)
+	}
+
+	for {
+		select {
+		case l := <-r.lines:
+			put(l)
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			// Write out whatever was queued before Close
+			for {
+				select {
+				case l := <-r.lines:
+					put(l)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// enforceRetention deletes capture files for hours that ended before the
+// retention cutoff, never the current one.
+func (r *Recorder) enforceRetention(now, current time.Time) {
+	if r.opts.Retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-r.opts.Retention)
+	paths, _ := Files(r.dir)
+	for _, path := range paths {
+		hour, ok := fileHour(path)
+		if ok && hour.Before(current) && hour.Add(time.Hour).Before(cutoff) {
+			os.Remove(path)
+		}
+	}
+}
+
+// Close stops recording and flushes what is queued. Frames recorded after
+// Close are discarded.
+func (r *Recorder) Close() {
+	r.once.Do(func() { close(r.done) })
+	r.wg.Wait()
+}
+
+// Frames returns the number of frames written.
+func (r *Recorder) Frames() uint64 {
+	return r.frames.Load()
+}
+
+// Dropped returns the number of frames discarded because the writer was
+// behind.
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Errors returns the number of frames that could not be encoded or written.
+func (r *Recorder) Errors() uint64 {
+	return r.errors.Load()
+}
+
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// Path is the capture file for the UTC hour containing t.
+func Path(dir string, t time.Time) string {
+	return filepath.Join(dir, filePrefix+t.UTC().Format(hourLayout)+fileExt)
+}
+
+// Pattern is the glob matching every capture file in dir.
+func Pattern(dir string) string {
+	return filepath.Join(dir, filePrefix+"*"+fileExt)
+}
+
+// Files lists the capture files in dir, oldest first.
+func Files(dir string) ([]string, error) {
+	paths, err := filepath.Glob(Pattern(dir))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func fileHour(path string) (time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), filePrefix), fileExt)
+	t, err := time.Parse(hourLayout, name)
+	return t, err == nil
+}
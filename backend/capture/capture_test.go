@@ -0,0 +1,190 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var hour0 = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// readLines decompresses a capture file, every gzip member of it.
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestEncodeKeepsFrameBytes(t *testing.T) {
+	tests := []struct {
+		name, frame, want string
+	}{
+		{name: "compact", frame: `{"type":"match","price":"64000.10"}`, want: `{"type":"match","price":"64000.10"}`},
+		{name: "spacing and order", frame: `{ "z" : 1.50,  "a": [1e3, "é"] }`, want: `{ "z" : 1.50,  "a": [1e3, "é"] }`},
+		{name: "array", frame: `[42,{"a":"b"},"trade","XBT/USD"]`, want: `[42,{"a":"b"},"trade","XBT/USD"]`},
+		{name: "multi-line", frame: "{\r\n  \"a\": 1,\n  \"b\": \"x y\"\n}", want: `{"a":1,"b":"x y"}`},
+	}
+	received := hour0.Add(1234567 * time.Microsecond)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := encode(`co"in`, received.In(time.FixedZone("EST", -5*3600)), []byte(tt.frame))
+			want := `{"venue":"co\"in","received":"2024-05-01T14:00:01.234567Z","frame":` + tt.want + "}\n"
+			if string(line) != want {
+				t.Fatalf("got  %s\nwant %s", line, want)
+			}
+			var rec Record
+			if err := json.Unmarshal(line, &rec); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Venue != `co"in` || !rec.Received.Equal(received) || string(rec.Frame) != tt.want {
+				t.Fatalf("decoded %+v", rec)
+			}
+		})
+	}
+}
+
+func TestRecorderRotatesHourly(t *testing.T) {
+	dir := t.TempDir()
+	r, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record("coinbase", hour0.Add(-time.Second), []byte(`{"n":1}`))
+	r.Record("kraken", hour0, []byte(`{"n":2}`))
+	r.Record("coinbase", hour0.Add(59*time.Minute), []byte(`{"n":3}`))
+	r.Record("coinbase", hour0, []byte(`not json`))
+	r.Close()
+
+	// A restart appends to the current hour's file
+	r, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record("kraken", hour0.Add(time.Hour), []byte(`{"n":4}`))
+	r.Record("coinbase", hour0.Add(30*time.Minute), []byte(`{"n":5}`))
+	r.Close()
+	r.Record("coinbase", hour0, []byte(`{"n":6}`))
+
+	paths, err := Files(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{Path(dir, hour0.Add(-time.Hour)), Path(dir, hour0), Path(dir, hour0.Add(time.Hour))}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("files %v, want %v", paths, want)
+	}
+	if filepath.Base(want[1]) != "capture-2024-05-01T14.jsonl.gz" {
+		t.Fatalf("named %s", filepath.Base(want[1]))
+	}
+
+	frames := func(path string) []string {
+		var out []string
+		for _, line := range readLines(t, path) {
+			var rec Record
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, string(rec.Frame))
+		}
+		return out
+	}
+	if got := frames(paths[0]); !reflect.DeepEqual(got, []string{`{"n":1}`}) {
+		t.Errorf("13:00 holds %v", got)
+	}
+	if got := frames(paths[1]); !reflect.DeepEqual(got, []string{`{"n":2}`, `{"n":3}`, `{"n":5}`}) {
+		t.Errorf("14:00 holds %v", got)
+	}
+	if got := frames(paths[2]); !reflect.DeepEqual(got, []string{`{"n":4}`}) {
+		t.Errorf("15:00 holds %v", got)
+	}
+	if r.Frames() != 2 || r.Errors() != 0 || r.Dropped() != 0 {
+		t.Errorf("second recorder counted %d frames, %d errors, %d dropped", r.Frames(), r.Errors(), r.Dropped())
+	}
+}
+
+func TestRecorderDropsWhenBehind(t *testing.T) {
+	dir := t.TempDir()
+	// A recorder whose writer has not started yet stands in for one that
+	// has fallen behind
+	r := &Recorder{
+		dir:   dir,
+		opts:  Options{FlushInterval: time.Second, QueueSize: 2},
+		lines: make(chan line, 2),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < 5; i++ {
+		r.Record("coinbase", hour0.Add(time.Duration(i)*time.Second), []byte(`{}`))
+	}
+	if r.Dropped() != 3 {
+		t.Fatalf("%d dropped, want 3", r.Dropped())
+	}
+
+	r.wg.Add(1)
+	go r.write()
+	r.Close()
+	if r.Frames() != 2 {
+		t.Fatalf("%d frames written, want the 2 queued", r.Frames())
+	}
+	if lines := readLines(t, Path(dir, hour0)); len(lines) != 2 {
+		t.Fatalf("file holds %d lines", len(lines))
+	}
+}
+
+func TestEnforceRetention(t *testing.T) {
+	dir := t.TempDir()
+	for h := -5; h <= 0; h++ {
+		if err := os.WriteFile(Path(dir, hour0.Add(time.Duration(h)*time.Hour)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of hours ending before 11:30 go
+	r := &Recorder{dir: dir, opts: Options{Retention: 3 * time.Hour}}
+	r.enforceRetention(hour0.Add(30*time.Minute), hour0)
+	paths, _ := Files(dir)
+	want := []string{Path(dir, hour0.Add(-3*time.Hour)), Path(dir, hour0.Add(-2*time.Hour)), Path(dir, hour0.Add(-time.Hour)), Path(dir, hour0)}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("kept %v, want %v", paths, want)
+	}
+
+	// The current hour survives even when the clock says it expired
+	r.enforceRetention(hour0.Add(24*time.Hour), hour0)
+	if paths, _ := Files(dir); !reflect.DeepEqual(paths, want[3:]) {
+		t.Fatalf("kept %v, want %v", paths, want[3:])
+	}
+
+	// No retention keeps everything
+	r.opts.Retention = 0
+	r.enforceRetention(hour0.Add(48*time.Hour), hour0.Add(48*time.Hour))
+	if paths, _ := Files(dir); len(paths) != 1 {
+		t.Fatalf("kept %v", paths)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("removed a file that is not a capture: %v", err)
+	}
+}
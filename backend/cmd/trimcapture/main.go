@@ -0,0 +1,346 @@
+// Command trimcapture cuts raw frame captures down to test fixtures: it
+// keeps the frames of chosen venues, symbols and time range and masks
+// account and order identifiers.
+//
+//	go run ./cmd/trimcapture -in /data/capture -symbols btcusdt \
+//		-from 2024-05-01T14:00:00Z -to 2024-05-01T14:05:00Z -out testdata/btc.jsonl
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/capture"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+func main() {
+	in := flag.String("in", "", "capture directory or file glob (required)")
+	out := flag.String("out", "-", "output file, gzipped if it ends in .gz; - for stdout")
+	venues := flag.String("venues", "", "comma separated venues to keep; empty keeps all")
+	symbols := flag.String("symbols", "", "comma separated normalized symbols to keep; empty keeps all")
+	from := flag.String("from", "", "keep frames received at or after this RFC 3339 time")
+	to := flag.String("to", "", "keep frames received before this RFC 3339 time")
+	limit := flag.Int("limit", 0, "stop after this many frames; 0 keeps all")
+	anonymize := flag.Bool("anonymize", true, "drop and mask identifying fields")
+	drop := flag.String("drop", "user_id,profile_id", "fields removed when anonymizing")
+	mask := flag.String("mask", "maker_order_id,taker_order_id,order_id,client_oid,buy_order_id,sell_order_id", "fields replaced with stable pseudonyms when anonymizing")
+	flag.Parse()
+	log.SetFlags(0)
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	paths, err := inputs(*in)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	t := trimmer{
+		venues:  set(*venues),
+		symbols: split(*symbols),
+		limit:   *limit,
+	}
+	if t.from, err = parseTime(*from); err != nil {
+		log.Fatalf("❌ -from: %v", err)
+	}
+	if t.to, err = parseTime(*to); err != nil {
+		log.Fatalf("❌ -to: %v", err)
+	}
+	if *anonymize {
+		t.anon = &anonymizer{
+			drop:   set(*drop),
+			mask:   set(*mask),
+			seen:   make(map[string]json.Number),
+			seenID: make(map[string]string),
+		}
+	}
+
+	w, closeOut, err := output(*out)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	for _, path := range paths {
+		if err := t.file(path, w); err != nil {
+			log.Fatalf("❌ %s: %v", path, err)
+		}
+		if t.done() {
+			break
+		}
+	}
+	if err := closeOut(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	log.Printf("✅ Kept %d of %d frames from %d files", t.kept, t.read, len(paths))
+}
+
+type trimmer struct {
+	venues   map[string]bool
+	symbols  []string
+	from, to time.Time
+	limit    int
+	anon     *anonymizer
+
+	read, kept int
+}
+
+func (t *trimmer) done() bool {
+	return t.limit > 0 && t.kept >= t.limit
+}
+
+func (t *trimmer) file(path string, w io.Writer) error {
+	r, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() && !t.done() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		t.read++
+		var rec capture.Record
+		if err := json.Unmarshal(line, &rec); err != nil || len(rec.Frame) == 0 {
+			return fmt.Errorf("line %d is not a capture record", t.read)
+		}
+		if !t.keep(rec) {
+			continue
+		}
+		if t.anon != nil {
+			if line, err = t.anon.frame(line, rec); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		t.kept++
+	}
+	return scanner.Err()
+}
+
+func (t *trimmer) keep(rec capture.Record) bool {
+	if len(t.venues) > 0 && !t.venues[rec.Venue] {
+		return false
+	}
+	if !t.from.IsZero() && rec.Received.Before(t.from) {
+		return false
+	}
+	if !t.to.IsZero() && !rec.Received.Before(t.to) {
+		return false
+	}
+	if len(t.symbols) == 0 {
+		return true
+	}
+	// Venues name products in different places and cases, so look for the
+	// product id anywhere in the frame; heartbeats and acks naming no
+	// product are dropped
+	frame := bytes.ToLower(rec.Frame)
+	for _, symbol := range t.symbols {
+		if product, ok := feed.VenueSymbol(rec.Venue, symbol); ok && bytes.Contains(frame, []byte(strings.ToLower(product))) {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymizer removes and pseudonymizes fields by name wherever they occur
+// in a frame. The same value always maps to the same pseudonym, so a
+// fixture still links a maker order to its later fills.
+type anonymizer struct {
+	drop   map[string]bool
+	mask   map[string]bool
+	seen   map[string]json.Number
+	seenID map[string]string
+	next   int
+}
+
+// frame returns line with its frame anonymized, untouched if nothing in it
+// needed to change.
+func (a *anonymizer) frame(line []byte, rec capture.Record) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(rec.Frame))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, changed := a.walk(v)
+	if !changed {
+		return line, nil
+	}
+
+	var frame bytes.Buffer
+	enc := json.NewEncoder(&frame)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	rec.Frame = bytes.TrimSuffix(frame.Bytes(), []byte("\n"))
+	return json.Marshal(rec)
+}
+
+func (a *anonymizer) walk(v interface{}) (interface{}, bool) {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch {
+			case a.drop[key]:
+				delete(v, key)
+				changed = true
+			case a.mask[key]:
+				v[key] = a.pseudonym(value)
+				changed = true
+			default:
+				var c bool
+				if v[key], c = a.walk(value); c {
+					changed = true
+				}
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			var c bool
+			if v[i], c = a.walk(value); c {
+				changed = true
+			}
+		}
+	}
+	return v, changed
+}
+
+// pseudonym keeps the value's shape: numbers stay numbers and strings look
+// like the UUIDs venues use.
+func (a *anonymizer) pseudonym(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		n, ok := a.seen[value.String()]
+		if !ok {
+			a.next++
+			n = json.Number(strconv.Itoa(a.next))
+			a.seen[value.String()] = n
+		}
+		return n
+	case string:
+		if value == "" {
+			return value
+		}
+		id, ok := a.seenID[value]
+		if !ok {
+			a.next++
+			id = fmt.Sprintf("00000000-0000-4000-8000-%012d", a.next)
+			a.seenID[value] = id
+		}
+		return id
+	}
+	return value
+}
+
+func inputs(in string) ([]string, error) {
+	if info, err := os.Stat(in); err == nil && info.IsDir() {
+		in = capture.Pattern(in)
+	}
+	paths, err := filepath.Glob(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no captures match %s", in)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+func output(path string) (io.Writer, func() error, error) {
+	if path == "-" {
+		w := bufio.NewWriter(os.Stdout)
+		return w, w.Flush, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := bufio.NewWriter(f)
+	if !strings.HasSuffix(path, ".gz") {
+		return w, func() error {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			return f.Close()
+		}, nil
+	}
+	gz := gzip.NewWriter(w)
+	return gz, func() error {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func split(list string) []string {
+	var out []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func set(list string) map[string]bool {
+	m := make(map[string]bool)
+	for _, s := range split(list) {
+		m[s] = true
+	}
+	return m
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/capture"
+)
+
+var hour0 = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// writeCapture writes frames as a capture file, one second apart from
+// hour0, alternating between the venues given. Like the recorder it keeps
+// the frames' bytes.
+func writeCapture(t *testing.T, frames []string, venues ...string) string {
+	t.Helper()
+	var b bytes.Buffer
+	for i, frame := range frames {
+		received := hour0.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano)
+		fmt.Fprintf(&b, "{\"venue\":%q,\"received\":%q,\"frame\":%s}\n", venues[i%len(venues)], received, frame)
+	}
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// trim runs t over path and returns the frames it kept.
+func trim(t *testing.T, tr *trimmer, path string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := tr.file(path, &out); err != nil {
+		t.Fatal(err)
+	}
+	var frames []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec capture.Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, string(rec.Frame))
+	}
+	return frames
+}
+
+func TestTrim(t *testing.T) {
+	path := writeCapture(t, []string{
+		`{"type":"match","product_id":"BTC-USD","price":"1"}`,
+		`[1,{"c":["2"]},"ticker","XBT/USD"]`,
+		`{"type":"match","product_id":"ETH-USD","price":"3"}`,
+		`{"event":"heartbeat"}`,
+		`{"type":"match","product_id":"BTC-USD","price":"5"}`,
+		`[1,{"c":["6"]},"ticker","BTC/USD"]`,
+		`{"type":"match","product_id":"btc-usd","price":"7"}`,
+	}, "coinbase", "kraken")
+
+	tests := []struct {
+		name    string
+		trimmer trimmer
+		want    []int // indexes of the frames kept
+	}{
+		{name: "everything", want: []int{0, 1, 2, 3, 4, 5, 6}},
+		{name: "venue", trimmer: trimmer{venues: set("kraken")}, want: []int{1, 3, 5}},
+		// Kraken's XBT/USD is not the listed BTC/USD product
+		{name: "symbol", trimmer: trimmer{symbols: split("btcusdt")}, want: []int{0, 4, 5, 6}},
+		{name: "symbols and venue", trimmer: trimmer{venues: set("coinbase"), symbols: split("btcusdt,ethusdt")}, want: []int{0, 2, 4, 6}},
+		{name: "time range", trimmer: trimmer{from: hour0.Add(2 * time.Second), to: hour0.Add(5 * time.Second)}, want: []int{2, 3, 4}},
+		{name: "limit", trimmer: trimmer{symbols: split("btcusdt"), limit: 2}, want: []int{0, 4}},
+	}
+	all := trim(t, &trimmer{}, path)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trim(t, &tt.trimmer, path)
+			var want []string
+			for _, i := range tt.want {
+				want = append(want, all[i])
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("kept\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+			if tt.trimmer.kept != len(want) {
+				t.Errorf("counted %d kept", tt.trimmer.kept)
+			}
+		})
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.jsonl")
+	os.WriteFile(bad, []byte(`{"venue":"coinbase"}`+"\n"), 0o644)
+	if err := (&trimmer{}).file(bad, &bytes.Buffer{}); err == nil {
+		t.Error("a line without a frame was accepted")
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	path := writeCapture(t, []string{
+		`{"type":"received","order_id":"a1b2","user_id":"u-9","profile_id":"p-9","price":"64000.10"}`,
+		`{"type":"match","maker_order_id":"a1b2","taker_order_id":"c3d4","trade_id":123}`,
+		`{"type":"ticker", "price":"64000.10"}`,
+		`{"data":[{"buy_order_id":17,"sell_order_id":18},{"buy_order_id":17,"sell_order_id":""}]}`,
+	}, "coinbase")
+	tr := &trimmer{anon: &anonymizer{
+		drop:   set("user_id,profile_id"),
+		mask:   set("maker_order_id,taker_order_id,order_id,buy_order_id,sell_order_id"),
+		seen:   make(map[string]json.Number),
+		seenID: make(map[string]string),
+	}}
+	got := trim(t, tr, path)
+	want := []string{
+		`{"order_id":"00000000-0000-4000-8000-000000000001","price":"64000.10","type":"received"}`,
+		// The same order keeps its pseudonym across frames
+		`{"maker_order_id":"00000000-0000-4000-8000-000000000001","taker_order_id":"00000000-0000-4000-8000-000000000002","trade_id":123,"type":"match"}`,
+		// Frames with nothing to hide keep their bytes
+		`{"type":"ticker", "price":"64000.10"}`,
+		`{"data":[{"buy_order_id":3,"sell_order_id":4},{"buy_order_id":3,"sell_order_id":""}]}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, frame := range got {
+		if strings.Contains(frame, "a1b2") || strings.Contains(frame, "u-9") || strings.Contains(frame, "p-9") {
+			t.Errorf("identifier left in %s", frame)
+		}
+	}
+}
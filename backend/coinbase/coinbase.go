@@ -0,0 +1,278 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+const (
+	Name           = "coinbase"
+	DefaultURL     = "wss://ws-feed.exchange.coinbase.com"
+	DefaultRESTURL = "https://api.exchange.coinbase.com"
+)
+
+// maxCandles is the most candles /products/{id}/candles returns per
+// request.
+const maxCandles = 300
+
+// candleGranularities are the only candle sizes the REST API serves.
+var candleGranularities = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// DefaultChannels are subscribed for every product when the config names
+// none. Trades come from matches, which unlike ticker never skips any;
+// ticker then only supplies quotes and 24h statistics. level2_batch is the
+// unauthenticated level2 feed, batched every 50ms.
+var DefaultChannels = []string{"ticker", "matches", "level2_batch"}
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed is the Coinbase Exchange WebSocket adapter. Every product shares one
+// connection; messages are routed to symbols by product_id.
+type Feed struct {
+	*feed.Stream
+	restURL  string
+	client   *http.Client
+	channels []string
+	matches  bool   // trades come from the matches channel
+	level2   string // order book channel, "" if not subscribed
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	url := cfg.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	channels := append([]string(nil), cfg.Streams...)
+	if len(channels) == 0 {
+		channels = append(channels, DefaultChannels...)
+	}
+	restURL := cfg.RESTURL
+	if restURL == "" {
+		restURL = DefaultRESTURL
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	var level2 string
+	for _, channel := range channels {
+		if strings.HasPrefix(channel, "level2") {
+			level2 = channel
+		}
+	}
+	f := &Feed{
+		restURL:  strings.TrimSuffix(restURL, "/"),
+		client:   client,
+		level2:   level2,
+		channels: channels,
+		matches:  contains(channels, "matches"),
+	}
+	headers := http.Header{}
+	headers.Add("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	f.Stream = feed.NewStream(feed.StreamConfig{
+		Venue:    Name,
+		URL:      url,
+		Header:   headers,
+		Policy:   cfg.Policy,
+		Recorder: cfg.Recorder,
+	}, f)
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Feed) Granularities() []time.Duration {
+	return append([]time.Duration(nil), candleGranularities...)
+}
+
+func (f *Feed) MaxCandles() int {
+	return maxCandles
+}
+
+// Candles fetches /products/{id}/candles, which answers newest first and
+// leaves out intervals without trades.
+func (f *Feed) Candles(ctx context.Context, symbol string, granularity time.Duration, start, end time.Time) ([]feed.Kline, error) {
+	product, ok := feed.VenueSymbol(Name, symbol)
+	if !ok {
+		return nil, fmt.Errorf("coinbase: %s is not listed", symbol)
+	}
+	url := fmt.Sprintf("%s/products/%s/candles?granularity=%d&start=%s&end=%s",
+		f.restURL, product, int(granularity/time.Second),
+		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var rows []restCandle
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	klines := make([]feed.Kline, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		k := rows[i].toKline(granularity, now)
+		// end is inclusive on the wire
+		if k.Start.Before(start) || !k.Start.Before(end) {
+			continue
+		}
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
+
+// ResyncBook resubscribes the symbol's level2 channel, which makes
+// Coinbase send a fresh snapshot.
+func (f *Feed) ResyncBook(symbol string) error {
+	product, ok := feed.VenueSymbol(Name, symbol)
+	if !ok || f.level2 == "" {
+		return nil
+	}
+	return f.Write(func(conn *websocket.Conn) error {
+		for _, kind := range []string{"unsubscribe", "subscribe"} {
+			err := conn.WriteJSON(map[string]interface{}{
+				"type":        kind,
+				"product_ids": []string{product},
+				"channels":    []string{f.level2},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Send implements feed.Protocol.
+func (f *Feed) Send(conn *websocket.Conn, subscribe bool, symbols []string) error {
+	kind := "subscribe"
+	if !subscribe {
+		kind = "unsubscribe"
+	}
+	products := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		product, _ := feed.VenueSymbol(Name, symbol)
+		products = append(products, product)
+	}
+	return conn.WriteJSON(map[string]interface{}{
+		"type":        kind,
+		"product_ids": products,
+		"channels":    f.channels,
+	})
+}
+
+// Session implements feed.Protocol. Sequence positions are per connection;
+// a reconnect is already recorded as a gap in the buffers, so numbering
+// starts afresh.
+func (f *Feed) Session() feed.Handler {
+	tickers := make(map[string]*sequencer)
+	matches := make(map[string]*sequencer)
+
+	return func(ctx context.Context, message []byte, receivedAt time.Time) error {
+		var msg Message
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return nil
+		}
+
+		switch msg.Type {
+		case "ticker", "match", "last_match":
+			symbol, ok := feed.Normalize(Name, msg.ProductID)
+			if !ok || !f.Active(symbol) {
+				return nil
+			}
+			seq := tickers
+			if msg.Type != "ticker" {
+				seq = matches
+			}
+			s := seq[symbol]
+			if s == nil {
+				s = &sequencer{}
+				seq[symbol] = s
+			}
+			ok, missed := s.next(msg)
+			if !ok {
+				f.Tracker().OutOfOrder()
+				return nil
+			}
+			f.handle(ctx, symbol, msg, missed, receivedAt)
+		case "snapshot", "l2update":
+			symbol, ok := feed.Normalize(Name, msg.ProductID)
+			if !ok || !f.Active(symbol) {
+				return nil
+			}
+			f.Emit(ctx, feed.Event{Kind: feed.EventBook, Venue: Name, Symbol: symbol, Book: msg.book(receivedAt)})
+		case "error":
+			log.Printf("⚠️  Coinbase error: %s %s", msg.Message, msg.Reason)
+		}
+		return nil
+	}
+}
+
+// handle emits the events for one in-order ticker or match message.
+func (f *Feed) handle(ctx context.Context, symbol string, msg Message, missed int64, receivedAt time.Time) {
+	switch msg.Type {
+	case "ticker":
+		if quote, ok := msg.quote(receivedAt); ok {
+			f.Emit(ctx, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: quote, Day: msg.day()})
+		}
+		if f.matches {
+			return
+		}
+		// The ticker skips trades under load, so gaps here are expected
+		// but still worth counting
+		if missed > 0 {
+			f.Tracker().TradeGap(missed)
+		}
+		if tick, ok := msg.tick(symbol, receivedAt); ok {
+			f.Emit(ctx, feed.Event{Kind: feed.EventTick, Venue: Name, Symbol: symbol, Tick: tick, TradeID: msg.TradeID})
+		}
+
+	case "last_match":
+		// Replayed on subscribe; it may already be buffered from before a
+		// reconnect, so it only sets the trade id baseline
+
+	case "match":
+		if missed > 0 {
+			log.Printf("⚠️  Coinbase %s: %d trades missing before trade %d", symbol, missed, msg.TradeID)
+			f.Tracker().TradeGap(missed)
+		}
+		if tick, ok := msg.trade(symbol, receivedAt); ok {
+			f.Emit(ctx, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: tick, TradeID: msg.TradeID})
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+)
+
+// restFeed builds a feed whose REST calls go to srv.
+func restFeed(t *testing.T, srv *httptest.Server) *Feed {
+	t.Helper()
+	f, err := New(feed.Config{RESTURL: srv.URL, HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.(*Feed)
+}
+
+func TestCandles(t *testing.T) {
+	start := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Minute)
+
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products/BTC-USD/candles" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		// Newest first, with the inclusive end boundary and a gap at 14:01
+		w.Write([]byte(`[
+			[1714572180, 64000, 64100, 64050, 64080, 1.5],
+			[1714572120, 63900, 64060, 63950, 64050, 2.5],
+			[1714572000, 63800, 63990, 63850, 63900, 4]
+		]`))
+	}))
+	defer srv.Close()
+
+	klines, err := restFeed(t, srv).Candles(context.Background(), "btcusdt", time.Minute, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "granularity=60&start=2024-05-01T14:00:00Z&end=2024-05-01T14:03:00Z"; query != want {
+		t.Errorf("query %s, want %s", query, want)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("%d klines, want 2: %+v", len(klines), klines)
+	}
+	first, second := klines[0], klines[1]
+	if !first.Start.Equal(start) || !first.End.Equal(start.Add(time.Minute)) {
+		t.Errorf("first kline spans %s-%s", first.Start, first.End)
+	}
+	if first.Open != 63850 || first.High != 63990 || first.Low != 63800 || first.Close != 63900 || first.Volume != 4 {
+		t.Errorf("first kline %+v", first)
+	}
+	if !first.Closed || first.Interval != "60" {
+		t.Errorf("first kline %+v", first)
+	}
+	if !second.Start.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("second kline starts %s", second.Start)
+	}
+}
+
+func TestCandlesErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"slow down"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	f := restFeed(t, srv)
+	now := time.Now()
+
+	if _, err := f.Candles(context.Background(), "btcusdt", time.Minute, now.Add(-time.Hour), now); err == nil {
+		t.Error("HTTP 429 not reported")
+	}
+	if _, err := f.Candles(context.Background(), "bnbusdt", time.Minute, now.Add(-time.Hour), now); err == nil {
+		t.Error("unlisted symbol not reported")
+	}
+}
+
+func TestParserTickerFallback(t *testing.T) {
+	const (
+		ticker = `{"type":"ticker","sequence":5,"product_id":"BTC-USD","price":"64000.5","best_bid":"64000","best_bid_size":"1","best_ask":"64001","best_ask_size":"2","side":"buy","time":"2024-05-01T14:00:00.1Z","trade_id":7,"last_size":"0.1"}`
+		match  = `{"type":"match","trade_id":8,"sequence":6,"product_id":"BTC-USD","price":"64001","size":"0.2","side":"sell","time":"2024-05-01T14:00:00.2Z"}`
+	)
+	subscribed := func(channels ...string) string {
+		list := ""
+		for i, c := range channels {
+			if i > 0 {
+				list += ","
+			}
+			list += fmt.Sprintf(`{"name":%q,"product_ids":["BTC-USD"]}`, c)
+		}
+		return `{"type":"subscriptions","channels":[` + list + `]}`
+	}
+
+	tests := []struct {
+		name   string
+		frames []string
+		want   []feed.EventKind
+	}{
+		{"ticker only", []string{subscribed("ticker"), ticker}, []feed.EventKind{feed.EventQuote, feed.EventTick}},
+		{"with matches", []string{subscribed("ticker", "matches"), ticker, match}, []feed.EventKind{feed.EventQuote, feed.EventTrade}},
+		{"no subscriptions frame", []string{ticker, match, ticker}, []feed.EventKind{feed.EventQuote, feed.EventTick, feed.EventTrade, feed.EventQuote}},
+		{"subscriptions frame wins", []string{subscribed("ticker"), match, ticker}, []feed.EventKind{feed.EventTrade, feed.EventQuote, feed.EventTick}},
+		{"unlisted product", []string{`{"type":"ticker","product_id":"NOPE-USD","price":"1"}`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			var got []feed.Event
+			for _, frame := range tt.frames {
+				events, err := p.Parse([]byte(frame), time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, events...)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d events, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, ev := range got {
+				if ev.Kind != tt.want[i] {
+					t.Errorf("event %d is %v, want %v", i, ev.Kind, tt.want[i])
+				}
+				if ev.Kind == feed.EventTick && (ev.Tick.Price != 64000.5 || ev.Tick.Size != 0.1 || ev.Tick.Side != "buy" || ev.TradeID != 7) {
+					t.Errorf("ticker tick %+v", ev)
+				}
+			}
+		})
+	}
+}
+
+func TestLevel2Book(t *testing.T) {
+	frames := []string{
+		`{"type":"snapshot","product_id":"BTC-USD","bids":[["64000.00","1.5"],["63999.50","2"]],"asks":[["64001.00","0.5"],["64002.00","3"]]}`,
+		`{"type":"l2update","product_id":"BTC-USD","time":"2024-05-01T14:00:00.3Z","changes":[["buy","64000.50","0.7"],["sell","64001.00","0"]]}`,
+		`{"type":"l2update","product_id":"BTC-USD","time":"2024-05-01T14:00:00.4Z","changes":[["buy","63999.50","0"],["sell","64001.50","1.25"],["bad"]]}`,
+	}
+	// Snapshots carry no time, so they are stamped on receipt
+	receivedAt := time.Date(2024, 5, 1, 14, 0, 0, 200000000, time.UTC)
+	book := orderbook.NewBook(Name, "btcusdt")
+	var p Parser
+	for i, frame := range frames {
+		events, err := p.Parse([]byte(frame), receivedAt)
+		if err != nil || len(events) != 1 || events[0].Kind != feed.EventBook {
+			t.Fatalf("frame %d: %+v %v", i, events, err)
+		}
+		if err := book.Apply(events[0].Book); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+
+	d := book.Depth(0)
+	if got, want := fmt.Sprint(d.Bids), fmt.Sprint([]orderbook.Level{{Price: 64000.5, Size: 0.7}, {Price: 64000, Size: 1.5}}); got != want {
+		t.Errorf("bids %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(d.Asks), fmt.Sprint([]orderbook.Level{{Price: 64001.5, Size: 1.25}, {Price: 64002, Size: 3}}); got != want {
+		t.Errorf("asks %s, want %s", got, want)
+	}
+	if want := time.Date(2024, 5, 1, 14, 0, 0, 400000000, time.UTC); !d.Updated.Equal(want) {
+		t.Errorf("updated %s, want %s", d.Updated, want)
+	}
+}
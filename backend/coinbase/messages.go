@@ -0,0 +1,290 @@
+package coinbase
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Message is the union of the ticker and matches channel payloads. Decimal
+// fields are strings on the wire.
+type Message struct {
+	Type      string `json:"type"`
+	ProductID string `json:"product_id"`
+	Sequence  int64  `json:"sequence"`
+	Time      string `json:"time"`
+	TradeID   int64  `json:"trade_id"`
+	Price     string `json:"price"`
+	Side      string `json:"side"`
+
+	// ticker
+	LastSize    string `json:"last_size"`
+	BestBid     string `json:"best_bid"`
+	BestBidSize string `json:"best_bid_size"`
+	BestAsk     string `json:"best_ask"`
+	BestAskSize string `json:"best_ask_size"`
+	Open24h     string `json:"open_24h"`
+	High24h     string `json:"high_24h"`
+	Low24h      string `json:"low_24h"`
+	Volume24h   string `json:"volume_24h"`
+	Volume30d   string `json:"volume_30d"`
+
+	// match, last_match
+	Size         string `json:"size"`
+	MakerOrderID string `json:"maker_order_id"`
+	TakerOrderID string `json:"taker_order_id"`
+
+	// snapshot, l2update
+	Bids    [][]string `json:"bids"`
+	Asks    [][]string `json:"asks"`
+	Changes [][]string `json:"changes"`
+
+	// subscriptions
+	Channels []struct {
+		Name string `json:"name"`
+	} `json:"channels"`
+
+	// error
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// Parser converts recorded Coinbase frames into events: tickers become
+// quotes, matches trades and level2 messages book updates. Like the live
+// feed, tickers also yield ticks when the matches channel was not
+// subscribed, which the capture's subscriptions frame tells; without one,
+// the first match frame does. Unlike the live feed it keeps no sequence
+// state, so frames are taken as recorded. The zero value is ready to use.
+type Parser struct {
+	matches bool
+	known   bool // a subscriptions frame or match settled matches
+}
+
+// Parse converts one frame. Frames that carry no market data and unlisted
+// products yield no events.
+func (p *Parser) Parse(frame []byte, receivedAt time.Time) ([]feed.Event, error) {
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Type == "subscriptions" {
+		p.matches, p.known = false, true
+		for _, c := range msg.Channels {
+			if c.Name == "matches" {
+				p.matches = true
+			}
+		}
+		return nil, nil
+	}
+	symbol, known := feed.Normalize(Name, msg.ProductID)
+	if !known {
+		return nil, nil
+	}
+
+	var events []feed.Event
+	switch msg.Type {
+	case "ticker":
+		if quote, ok := msg.quote(receivedAt); ok {
+			events = append(events, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: quote, Day: msg.day()})
+		}
+		if p.matches {
+			break
+		}
+		if tick, ok := msg.tick(symbol, receivedAt); ok {
+			events = append(events, feed.Event{Kind: feed.EventTick, Venue: Name, Symbol: symbol, Tick: tick, TradeID: msg.TradeID})
+		}
+	case "match":
+		if !p.known {
+			p.matches, p.known = true, true
+		}
+		if tick, ok := msg.trade(symbol, receivedAt); ok {
+			events = append(events, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: tick, TradeID: msg.TradeID})
+		}
+	case "snapshot", "l2update":
+		events = append(events, feed.Event{Kind: feed.EventBook, Venue: Name, Symbol: symbol, Book: msg.book(receivedAt)})
+	}
+	return events, nil
+}
+
+func (msg Message) time(receivedAt time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, msg.Time); err == nil {
+		return t
+	}
+	return receivedAt
+}
+
+// tick converts a ticker message. The ticker's side is already the
+// aggressor's.
+func (msg Message) tick(symbol string, receivedAt time.Time) (ringbuffer.Tick, bool) {
+	price, ok := decimal(msg.Price)
+	if !ok {
+		return ringbuffer.Tick{}, false
+	}
+	size, _ := decimal(msg.LastSize)
+	return ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       msg.time(receivedAt),
+		ReceivedAt: receivedAt,
+		Price:      price,
+		Size:       size,
+		Side:       msg.Side,
+		Sequence:   msg.Sequence,
+	}, true
+}
+
+// trade converts a match message. Coinbase reports the maker's side, so the
+// aggressor is the opposite one.
+func (msg Message) trade(symbol string, receivedAt time.Time) (ringbuffer.Tick, bool) {
+	price, ok := decimal(msg.Price)
+	if !ok {
+		return ringbuffer.Tick{}, false
+	}
+	size, _ := decimal(msg.Size)
+	tick := ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       msg.time(receivedAt),
+		ReceivedAt: receivedAt,
+		Price:      price,
+		Size:       size,
+		Sequence:   msg.Sequence,
+	}
+	switch msg.Side {
+	case "buy":
+		tick.Side = "sell"
+	case "sell":
+		tick.Side = "buy"
+	}
+	return tick, true
+}
+
+// quote extracts the best bid and offer from a ticker message.
+func (msg Message) quote(receivedAt time.Time) (feed.Quote, bool) {
+	bid, okBid := decimal(msg.BestBid)
+	ask, okAsk := decimal(msg.BestAsk)
+	if !okBid || !okAsk {
+		return feed.Quote{}, false
+	}
+	bidSize, _ := decimal(msg.BestBidSize)
+	askSize, _ := decimal(msg.BestAskSize)
+	return feed.Quote{
+		Time:     msg.time(receivedAt),
+		Bid:      bid,
+		BidSize:  bidSize,
+		Ask:      ask,
+		AskSize:  askSize,
+		Sequence: msg.Sequence,
+	}, true
+}
+
+func (msg Message) day() feed.DayStats {
+	var d feed.DayStats
+	d.Open, _ = decimal(msg.Open24h)
+	d.High, _ = decimal(msg.High24h)
+	d.Low, _ = decimal(msg.Low24h)
+	d.Volume, _ = decimal(msg.Volume24h)
+	d.Volume30d, _ = decimal(msg.Volume30d)
+	return d
+}
+
+// book converts a level2 snapshot or l2update. Coinbase does not number
+// level2 messages; the connection guarantees their order.
+func (msg Message) book(receivedAt time.Time) orderbook.Update {
+	u := orderbook.Update{Snapshot: msg.Type == "snapshot", Time: msg.time(receivedAt)}
+	if u.Snapshot {
+		u.Bids = levels(msg.Bids)
+		u.Asks = levels(msg.Asks)
+		return u
+	}
+	for _, c := range msg.Changes {
+		if len(c) < 3 {
+			continue
+		}
+		price, ok := decimal(c[1])
+		size, okSize := decimal(c[2])
+		if !ok || !okSize {
+			continue
+		}
+		switch c[0] {
+		case "buy":
+			u.Bids = append(u.Bids, orderbook.Level{Price: price, Size: size})
+		case "sell":
+			u.Asks = append(u.Asks, orderbook.Level{Price: price, Size: size})
+		}
+	}
+	return u
+}
+
+// levels parses [price, size] pairs.
+func levels(raw [][]string) []orderbook.Level {
+	out := make([]orderbook.Level, 0, len(raw))
+	for _, l := range raw {
+		if len(l) < 2 {
+			continue
+		}
+		price, ok := decimal(l[0])
+		size, okSize := decimal(l[1])
+		if ok && okSize {
+			out = append(out, orderbook.Level{Price: price, Size: size})
+		}
+	}
+	return out
+}
+
+func decimal(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// sequencer tracks one product's position on one channel for a single
+// connection.
+type sequencer struct {
+	sequence int64
+	tradeID  int64
+}
+
+// next checks msg against what was seen before. It reports whether msg is
+// new, and how many trade ids were skipped since the previous message when
+// trade ids are contiguous on the channel.
+func (s *sequencer) next(msg Message) (ok bool, missed int64) {
+	if s.sequence != 0 && msg.Sequence <= s.sequence {
+		return false, 0
+	}
+	s.sequence = msg.Sequence
+
+	if msg.TradeID == 0 {
+		return true, 0
+	}
+	if s.tradeID != 0 && msg.TradeID > s.tradeID+1 {
+		missed = msg.TradeID - s.tradeID - 1
+	}
+	if msg.TradeID > s.tradeID {
+		s.tradeID = msg.TradeID
+	}
+	return true, missed
+}
+
+// restCandle is one row of /products/{id}/candles: start time in Unix
+// seconds, low, high, open, close, volume.
+type restCandle [6]float64
+
+func (row restCandle) toKline(granularity time.Duration, now time.Time) feed.Kline {
+	start := time.Unix(int64(row[0]), 0)
+	return feed.Kline{
+		Interval: strconv.Itoa(int(granularity / time.Second)),
+		Start:    start,
+		End:      start.Add(granularity),
+		Low:      row[1],
+		High:     row[2],
+		Open:     row[3],
+		Close:    row[4],
+		Volume:   row[5],
+		Closed:   !start.Add(granularity).After(now),
+	}
+}
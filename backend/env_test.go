@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLookupEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 2 * time.Second, true},
+		{"750ms", 750 * time.Millisecond, true},
+		{"5", 2 * time.Second, false}, // no unit
+		{"abc", 2 * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Setenv("FILTER_MAX_LATENESS", tt.value)
+		got, err := lookupEnv("FILTER_MAX_LATENESS", 2*time.Second, time.ParseDuration)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("%q: %s, %v; want %s, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+
+	t.Setenv("FILTER_MAX_DEVIATION", "abc")
+	if _, err := lookupEnv("FILTER_MAX_DEVIATION", 0.05, parseFloat); err == nil {
+		t.Error("FILTER_MAX_DEVIATION=abc accepted")
+	}
+	t.Setenv("FILTER_WINDOW", "1.5")
+	if _, err := lookupEnv("FILTER_WINDOW", 50, strconv.Atoi); err == nil {
+		t.Error("FILTER_WINDOW=1.5 accepted")
+	}
+}
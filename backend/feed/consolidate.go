@@ -0,0 +1,246 @@
+package feed
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Ways of combining venue prices into one.
+const (
+	// MethodMedian takes the median of the venues' last prices, so one
+	// venue printing nonsense cannot move the composite.
+	MethodMedian = "median"
+	// MethodVWAP weights each venue's last price by its recent traded
+	// volume. Venues that only send ticker prices carry no weight.
+	MethodVWAP = "vwap"
+)
+
+type ConsolidateConfig struct {
+	Method string
+	// Venues without a tick for StaleAfter are left out of the composite.
+	StaleAfter time.Duration
+	// VolumeWindow is the time constant of the decaying volume used by
+	// MethodVWAP.
+	VolumeWindow time.Duration
+}
+
+func DefaultConsolidateConfig() ConsolidateConfig {
+	return ConsolidateConfig{
+		Method:       MethodMedian,
+		StaleAfter:   10 * time.Second,
+		VolumeWindow: time.Minute,
+	}
+}
+
+// VenuePrice is one venue's contribution to a composite price.
+type VenuePrice struct {
+	Venue      string    `json:"venue"`
+	Price      float64   `json:"price"`
+	Time       time.Time `json:"time"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	Volume     float64   `json:"volume"` // decayed over VolumeWindow
+	Stale      bool      `json:"stale"`
+}
+
+// Consolidated is the composite price of a symbol and the venue prices it
+// was built from.
+type Consolidated struct {
+	Symbol string       `json:"symbol"`
+	Method string       `json:"method"`
+	Price  float64      `json:"price"`
+	Time   time.Time    `json:"time"`
+	Venues []VenuePrice `json:"venues"`
+}
+
+// Consolidator turns the ticks of several venues into one composite tick
+// stream per symbol. Each venue's raw ticks are kept in buffers of their
+// own for inspection.
+type Consolidator struct {
+	cfg  ConsolidateConfig
+	opts ringbuffer.Options
+
+	mu      sync.RWMutex
+	raw     map[string]*ringbuffer.Registry // venue -> raw tick buffers
+	symbols map[string]*composite
+}
+
+type composite struct {
+	price  float64
+	time   time.Time
+	venues map[string]*VenuePrice
+	order  []string
+}
+
+func NewConsolidator(cfg ConsolidateConfig, opts ringbuffer.Options) (*Consolidator, error) {
+	if cfg.Method != MethodMedian && cfg.Method != MethodVWAP {
+		return nil, fmt.Errorf("feed: unknown consolidation method %q", cfg.Method)
+	}
+	return &Consolidator{
+		cfg:     cfg,
+		opts:    opts,
+		raw:     make(map[string]*ringbuffer.Registry),
+		symbols: make(map[string]*composite),
+	}, nil
+}
+
+// Update records a venue tick and returns the composite tick to write in
+// its place. The composite keeps the tick's size and side, so volume still
+// reflects every trade, and its time never goes backwards even when venue
+// clocks disagree. Update is called by the router only.
+func (c *Consolidator) Update(venue string, tick ringbuffer.Tick) ringbuffer.Tick {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	raw := c.raw[venue]
+	if raw == nil {
+		raw = ringbuffer.NewRegistry(nil, c.opts)
+		c.raw[venue] = raw
+	}
+	raw.Add(tick.Symbol).WriteTick(tick)
+
+	s := c.symbols[tick.Symbol]
+	if s == nil {
+		s = &composite{venues: make(map[string]*VenuePrice)}
+		c.symbols[tick.Symbol] = s
+	}
+	now := tick.ReceivedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	v := s.venues[venue]
+	if v == nil {
+		v = &VenuePrice{Venue: venue}
+		s.venues[venue] = v
+		s.order = append(s.order, venue)
+	}
+	v.Volume = c.decay(v, now) + tick.Size
+	v.Price, v.Time, v.ReceivedAt = tick.Price, tick.Time, now
+
+	s.price = c.combine(s, now)
+	if tick.Time.Before(s.time) {
+		tick.Time = s.time
+	}
+	s.time = tick.Time
+
+	tick.Price = s.price
+	tick.Sequence = 0
+	return tick
+}
+
+// Drop forgets a venue's prices for symbols, e.g. when it disconnects.
+func (c *Consolidator) Drop(venue string, symbols []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, symbol := range symbols {
+		s, ok := c.symbols[symbol]
+		if !ok {
+			continue
+		}
+		delete(s.venues, venue)
+		kept := s.order[:0]
+		for _, v := range s.order {
+			if v != venue {
+				kept = append(kept, v)
+			}
+		}
+		s.order = kept
+	}
+}
+
+// Remove forgets symbol: its composite and every venue's raw buffer, so a
+// symbol tracked again starts afresh. It reports whether anything was
+// dropped.
+func (c *Consolidator) Remove(symbol string) bool {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.symbols[symbol]
+	delete(c.symbols, symbol)
+	for _, raw := range c.raw {
+		if raw.Remove(symbol) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// combine computes the composite from the venues that are not stale.
+func (c *Consolidator) combine(s *composite, now time.Time) float64 {
+	var prices []float64
+	var weighted, volume float64
+	for _, v := range s.venues {
+		if now.Sub(v.ReceivedAt) > c.cfg.StaleAfter {
+			continue
+		}
+		prices = append(prices, v.Price)
+		w := c.decay(v, now)
+		weighted += v.Price * w
+		volume += w
+	}
+	if len(prices) == 0 {
+		return s.price
+	}
+	if c.cfg.Method == MethodVWAP && volume > 0 {
+		return weighted / volume
+	}
+	sort.Float64s(prices)
+	return middle(prices)
+}
+
+// decay returns v's volume decayed from its last update to now.
+func (c *Consolidator) decay(v *VenuePrice, now time.Time) float64 {
+	if v.ReceivedAt.IsZero() || c.cfg.VolumeWindow <= 0 {
+		return v.Volume
+	}
+	return v.Volume * math.Exp(-now.Sub(v.ReceivedAt).Seconds()/c.cfg.VolumeWindow.Seconds())
+}
+
+// Get returns symbol's composite and venue prices as of now.
+func (c *Consolidator) Get(symbol string) (Consolidated, bool) {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s, ok := c.symbols[symbol]
+	if !ok {
+		return Consolidated{}, false
+	}
+	now := time.Now()
+	out := Consolidated{Symbol: symbol, Method: c.cfg.Method, Price: s.price, Time: s.time}
+	for _, venue := range s.order {
+		v := s.venues[venue]
+		vp := *v
+		vp.Volume = c.decay(v, now)
+		vp.Stale = now.Sub(v.ReceivedAt) > c.cfg.StaleAfter
+		out.Venues = append(out.Venues, vp)
+	}
+	return out, true
+}
+
+// Raw returns the buffer holding a venue's unconsolidated ticks for symbol.
+func (c *Consolidator) Raw(venue, symbol string) (*ringbuffer.TickBuffer, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	raw, ok := c.raw[venue]
+	if !ok {
+		return nil, false
+	}
+	return raw.Get(symbol)
+}
+
+// Venues lists the venues that have sent ticks, in name order.
+func (c *Consolidator) Venues() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	venues := make([]string, 0, len(c.raw))
+	for venue := range c.raw {
+		venues = append(venues, venue)
+	}
+	sort.Strings(venues)
+	return venues
+}
@@ -0,0 +1,199 @@
+package feed
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// venueTick is a tick from venue received at seconds after filterEpoch.
+type venueTick struct {
+	venue   string
+	price   float64
+	size    float64
+	seconds int
+}
+
+func TestConsolidatorCombines(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		ticks  []venueTick
+		drop   string // venue dropped before the last tick
+		want   float64
+	}{
+		{
+			name:   "single venue",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}},
+			want:   100,
+		},
+		{
+			name:   "median ignores an outlier",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"kraken", 150, 1, 0}, {"binance", 101, 1, 0}},
+			want:   101,
+		},
+		{
+			name:   "median of two is their mean",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"binance", 102, 1, 0}},
+			want:   101,
+		},
+		{
+			name:   "vwap weights by volume",
+			method: MethodVWAP,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"binance", 110, 3, 0}},
+			want:   107.5,
+		},
+		{
+			name:   "vwap without volume falls back to median",
+			method: MethodVWAP,
+			ticks:  []venueTick{{"coinbase", 100, 0, 0}, {"kraken", 104, 0, 0}, {"binance", 101, 0, 0}},
+			want:   101,
+		},
+		{
+			name:   "vwap volume decays",
+			method: MethodVWAP,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"binance", 110, 1, 5}},
+			// coinbase's volume has decayed by e^(-5/60) by the second tick
+			want: (100*math.Exp(-5.0/60) + 110) / (math.Exp(-5.0/60) + 1),
+		},
+		{
+			name:   "stale venue left out",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"kraken", 101, 1, 0}, {"binance", 200, 1, 11}},
+			want:   200,
+		},
+		{
+			name:   "venue within StaleAfter kept",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"binance", 200, 1, 10}},
+			want:   150,
+		},
+		{
+			name:   "stale venue left out of vwap",
+			method: MethodVWAP,
+			ticks:  []venueTick{{"coinbase", 100, 100, 0}, {"binance", 200, 1, 30}},
+			want:   200,
+		},
+		{
+			name:   "dropped venue falls back to the one left",
+			method: MethodMedian,
+			ticks:  []venueTick{{"coinbase", 100, 1, 0}, {"kraken", 150, 1, 0}, {"coinbase", 102, 1, 1}},
+			drop:   "kraken",
+			want:   102,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConsolidateConfig()
+			cfg.Method = tt.method
+			c, err := NewConsolidator(cfg, ringbuffer.Options{Size: 16})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got ringbuffer.Tick
+			for i, vt := range tt.ticks {
+				if tt.drop != "" && i == len(tt.ticks)-1 {
+					c.Drop(tt.drop, []string{"btcusdt"})
+				}
+				at := filterEpoch.Add(time.Duration(vt.seconds) * time.Second)
+				got = c.Update(vt.venue, ringbuffer.Tick{Symbol: "btcusdt", Time: at, ReceivedAt: at, Price: vt.price, Size: vt.size, Sequence: int64(i + 1)})
+			}
+			if math.Abs(got.Price-tt.want) > 1e-9 {
+				t.Errorf("composite %g, want %g", got.Price, tt.want)
+			}
+			if got.Sequence != 0 {
+				t.Errorf("composite sequence %d, want 0", got.Sequence)
+			}
+		})
+	}
+}
+
+func TestConsolidatorKeepsTimeMonotonic(t *testing.T) {
+	c, _ := NewConsolidator(DefaultConsolidateConfig(), ringbuffer.Options{Size: 16})
+	c.Update("coinbase", ringbuffer.Tick{Symbol: "btcusdt", Time: filterEpoch, ReceivedAt: filterEpoch, Price: 100, Size: 2})
+
+	// A venue whose clock runs behind cannot move the composite back
+	got := c.Update("kraken", ringbuffer.Tick{Symbol: "btcusdt", Time: filterEpoch.Add(-time.Second), ReceivedAt: filterEpoch, Price: 102, Size: 1})
+	if !got.Time.Equal(filterEpoch) {
+		t.Errorf("time %s, want %s", got.Time, filterEpoch)
+	}
+	if got.Size != 1 {
+		t.Errorf("size %g, want the venue tick's 1", got.Size)
+	}
+
+	raw, ok := c.Raw("kraken", "btcusdt")
+	if !ok {
+		t.Fatal("no raw kraken buffer")
+	}
+	if last := raw.ReadLastTicks(1); len(last) != 1 || last[0].Price != 102 {
+		t.Errorf("raw kraken ticks %+v, want one at 102", last)
+	}
+	if venues := c.Venues(); len(venues) != 2 || venues[0] != "coinbase" || venues[1] != "kraken" {
+		t.Errorf("venues %v", venues)
+	}
+}
+
+func TestConsolidatorGetMarksStaleVenues(t *testing.T) {
+	c, _ := NewConsolidator(DefaultConsolidateConfig(), ringbuffer.Options{Size: 16})
+	now := time.Now()
+	old := now.Add(-time.Minute)
+	c.Update("kraken", ringbuffer.Tick{Symbol: "btcusdt", Time: old, ReceivedAt: old, Price: 90, Size: 1})
+	c.Update("coinbase", ringbuffer.Tick{Symbol: "btcusdt", Time: now, ReceivedAt: now, Price: 100, Size: 1})
+
+	got, ok := c.Get("BTCUSDT")
+	if !ok {
+		t.Fatal("no composite for btcusdt")
+	}
+	if got.Price != 100 || len(got.Venues) != 2 {
+		t.Fatalf("composite %+v", got)
+	}
+	if !got.Venues[0].Stale || got.Venues[0].Venue != "kraken" {
+		t.Errorf("kraken %+v, want stale", got.Venues[0])
+	}
+	if got.Venues[1].Stale {
+		t.Errorf("coinbase %+v, want fresh", got.Venues[1])
+	}
+	if _, err := NewConsolidator(ConsolidateConfig{Method: "mean"}, ringbuffer.Options{}); err == nil {
+		t.Error("NewConsolidator accepted method mean")
+	}
+}
+
+func TestConsolidatorRemove(t *testing.T) {
+	c, _ := NewConsolidator(DefaultConsolidateConfig(), ringbuffer.Options{Size: 16})
+	for _, vt := range []venueTick{{"coinbase", 100, 1, 0}, {"kraken", 104, 1, 0}} {
+		c.Update(vt.venue, ringbuffer.Tick{Symbol: "btcusdt", Time: filterEpoch, ReceivedAt: filterEpoch, Price: vt.price, Size: vt.size})
+	}
+	c.Update("coinbase", ringbuffer.Tick{Symbol: "ethusdt", Time: filterEpoch, ReceivedAt: filterEpoch, Price: 3400, Size: 1})
+
+	if !c.Remove("BTCUSDT") {
+		t.Fatal("btcusdt not removed")
+	}
+	if _, ok := c.Get("btcusdt"); ok {
+		t.Error("btcusdt composite kept")
+	}
+	for _, venue := range []string{"coinbase", "kraken"} {
+		if _, ok := c.Raw(venue, "btcusdt"); ok {
+			t.Errorf("%s raw btcusdt buffer kept", venue)
+		}
+	}
+	if _, ok := c.Get("ethusdt"); !ok {
+		t.Error("ethusdt composite removed too")
+	}
+	if c.Remove("btcusdt") {
+		t.Error("btcusdt removed twice")
+	}
+
+	// Tracked again, it does not combine with the old venue prices
+	got := c.Update("kraken", ringbuffer.Tick{Symbol: "btcusdt", Time: filterEpoch.Add(-time.Minute), ReceivedAt: filterEpoch, Price: 110, Size: 1})
+	if got.Price != 110 || !got.Time.Equal(filterEpoch.Add(-time.Minute)) {
+		t.Errorf("tick %+v after re-adding", got)
+	}
+	if s, _ := c.Get("btcusdt"); len(s.Venues) != 1 {
+		t.Errorf("venues %+v after re-adding", s.Venues)
+	}
+}
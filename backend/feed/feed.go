@@ -0,0 +1,214 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// MarketDataFeed is an exchange adapter that turns a venue's wire protocol
+// into normalized Events.
+type MarketDataFeed interface {
+	// Name is the venue name used in configuration and events.
+	Name() string
+	// Start connects and keeps the feed running until ctx is cancelled or
+	// Stop is called. It does not block.
+	Start(ctx context.Context) error
+	// Stop disconnects and closes the Events channel.
+	Stop() error
+	// Subscribe adds normalized symbols (e.g. "btcusdt"); symbols the venue
+	// does not list are ignored. It may be called before or after Start.
+	Subscribe(symbols []string) error
+	// Unsubscribe stops delivering symbols and reports them disconnected.
+	Unsubscribe(symbols []string) error
+	// Events delivers everything the feed receives, in arrival order.
+	Events() <-chan Event
+	Health() Health
+}
+
+// EventKind tells which of an Event's payload fields is set.
+type EventKind int
+
+const (
+	// EventTick is a ticker update carrying the last trade price.
+	EventTick EventKind = iota
+	// EventTrade is an individual trade print.
+	EventTrade
+	// EventStatus is a connection state change for Symbols.
+	EventStatus
+	// EventQuote is a top-of-book update.
+	EventQuote
+	// EventKline is an exchange-built candle.
+	EventKline
+	// EventBook is a level 2 order book snapshot or delta.
+	EventBook
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventTick:
+		return "tick"
+	case EventTrade:
+		return "trade"
+	case EventStatus:
+		return "status"
+	case EventQuote:
+		return "quote"
+	case EventKline:
+		return "kline"
+	case EventBook:
+		return "book"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is one normalized message from a feed.
+type Event struct {
+	Kind   EventKind
+	Venue  string
+	Symbol string // normalized symbol; empty for status events
+
+	// EventTick, EventTrade
+	Tick    ringbuffer.Tick
+	TradeID int64 // venue trade id, 0 if unknown
+
+	// EventStatus
+	Connected bool
+	Symbols   []string
+
+	// EventQuote
+	Quote Quote
+	Day   DayStats // rolling 24h summary, zero unless the venue sends one
+
+	// EventKline
+	Kline Kline
+
+	// EventBook
+	Book orderbook.Update
+}
+
+// BookResyncer is implemented by feeds that deliver order books. The
+// router calls ResyncBook when a symbol's book has lost sync; the feed
+// answers asynchronously with a snapshot event.
+type BookResyncer interface {
+	ResyncBook(symbol string) error
+}
+
+// CandleSource is implemented by feeds whose venue serves historical
+// candles over REST. main uses it to backfill bar series on startup.
+type CandleSource interface {
+	// Granularities lists the candle intervals the venue serves, ascending.
+	Granularities() []time.Duration
+	// MaxCandles is the most candles one request returns.
+	MaxCandles() int
+	// Candles returns symbol's candles of the given granularity that start
+	// in [start, end), oldest first. The last may still be forming.
+	Candles(ctx context.Context, symbol string, granularity time.Duration, start, end time.Time) ([]Kline, error)
+}
+
+// Quote is the best bid and offer at Time.
+type Quote struct {
+	Time     time.Time
+	Bid      float64
+	BidSize  float64
+	Ask      float64
+	AskSize  float64
+	Sequence int64
+}
+
+// DayStats is a venue's rolling 24 hour summary for a product.
+type DayStats struct {
+	Open      float64
+	High      float64
+	Low       float64
+	Volume    float64
+	Volume30d float64
+}
+
+// Kline is a candle built by the venue. Closed is false while the interval
+// is still forming.
+type Kline struct {
+	Interval string
+	Start    time.Time
+	End      time.Time
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	Volume   float64
+	Notional float64 // quote volume, 0 if the venue does not report it
+	Trades   int64   // 0 if the venue does not report it
+	Closed   bool
+}
+
+// Config selects and configures an adapter.
+type Config struct {
+	Venue   string   // registered adapter name, e.g. "coinbase"
+	Symbols []string // normalized symbols to subscribe to on start
+	URL     string   // endpoint override; empty uses the adapter default
+	RESTURL string   // REST endpoint override, for adapters that need one
+	Streams []string // venue channel names; empty uses the adapter default
+	Policy  Policy   // reconnect and heartbeat policy; zero fields use defaults
+	// HTTPClient makes REST requests; nil uses a default with a timeout.
+	HTTPClient *http.Client
+	// Params holds adapter specific settings, e.g. the synthetic feed's
+	// seed. main fills it from <VENUE>_* environment variables.
+	Params map[string]string
+	// Recorder, if set, is handed every raw frame the adapter reads.
+	Recorder Recorder
+}
+
+// Recorder captures raw venue frames exactly as received. Record is called
+// from adapters' read loops, so it must not block; the frame is not
+// modified afterwards and may be retained.
+type Recorder interface {
+	Record(venue string, receivedAt time.Time, frame []byte)
+}
+
+// Constructor builds an adapter from its configuration.
+type Constructor func(cfg Config) (MarketDataFeed, error)
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = make(map[string]Constructor)
+)
+
+// Register makes an adapter available to New under name. Adapter packages
+// call it from init, so importing an adapter package enables it.
+func Register(name string, constructor Constructor) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	if _, dup := adapters[name]; dup {
+		panic("feed: Register called twice for " + name)
+	}
+	adapters[name] = constructor
+}
+
+// New builds the adapter named by cfg.Venue.
+func New(cfg Config) (MarketDataFeed, error) {
+	adaptersMu.RLock()
+	constructor, ok := adapters[cfg.Venue]
+	adaptersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("feed: unknown adapter %q (have %v)", cfg.Venue, Adapters())
+	}
+	return constructor(cfg)
+}
+
+// Adapters lists the registered adapter names.
+func Adapters() []string {
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,315 @@
+package feed
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Reasons a tick is rejected by the Filter.
+const (
+	RejectInvalidPrice = "invalid_price" // zero, negative, NaN or Inf
+	RejectInvalidSize  = "invalid_size"  // negative, NaN or Inf
+	RejectDuplicate    = "duplicate"     // trade id already seen
+	RejectOutOfOrder   = "out_of_order"  // older than MaxLateness behind the last tick
+	RejectOutlier      = "outlier"       // too far from the recent median
+)
+
+var RejectReasons = []string{
+	RejectInvalidPrice,
+	RejectInvalidSize,
+	RejectDuplicate,
+	RejectOutOfOrder,
+	RejectOutlier,
+}
+
+// madScale turns a median absolute deviation into a standard deviation
+// estimate for normally distributed prices.
+const madScale = 1.4826
+
+type FilterConfig struct {
+	// Window is how many accepted prices the outlier check looks at, and
+	// MinSamples how many it needs before it rejects anything.
+	Window     int
+	MinSamples int
+	// A price is an outlier when it is more than MaxDeviation robust
+	// standard deviations and more than MinMove (a fraction of the price)
+	// away from the median. MinMove keeps a calm market, whose MAD is
+	// tiny, from rejecting ordinary moves.
+	MaxDeviation float64
+	MinMove      float64
+	// ConfirmAfter consecutive outliers that agree with each other are
+	// taken as a genuine jump: the window restarts from them.
+	ConfirmAfter int
+	// DedupeWindow is how many recent trade ids are remembered.
+	DedupeWindow int
+	// Ticks up to MaxLateness older than the last accepted one are kept
+	// with their time clamped, so buffers stay time-ordered; older ones are
+	// rejected.
+	MaxLateness time.Duration
+}
+
+func DefaultFilterConfig() FilterConfig {
+	return FilterConfig{
+		Window:       50,
+		MinSamples:   20,
+		MaxDeviation: 10,
+		MinMove:      0.01,
+		ConfirmAfter: 3,
+		DedupeWindow: 1024,
+		MaxLateness:  2 * time.Second,
+	}
+}
+
+// FilterStats counts what the filter did for one venue and symbol.
+type FilterStats struct {
+	Venue    string            `json:"venue"`
+	Symbol   string            `json:"symbol"`
+	Accepted uint64            `json:"accepted"`
+	Clamped  uint64            `json:"clamped"` // accepted with the time moved forward
+	Jumps    uint64            `json:"jumps"`   // confirmed price jumps
+	Rejected map[string]uint64 `json:"rejected"`
+}
+
+// Filter screens trades and ticker updates before they reach the buffers.
+// Each venue and symbol is screened on its own, since venues quote slightly
+// different prices and number their trades independently.
+type Filter struct {
+	cfg     FilterConfig
+	mu      sync.Mutex
+	streams map[[2]string]*filterStream
+	order   [][2]string
+}
+
+type filterStream struct {
+	stats FilterStats
+
+	// Accepted prices in arrival order (ring) and sorted, for the median
+	prices  []float64
+	next    int
+	sorted  []float64
+	pending []float64 // consecutive outliers awaiting confirmation
+
+	last time.Time
+
+	ids     map[int64]struct{}
+	idRing  []int64
+	idNext  int
+	scratch []float64
+}
+
+// NewFilter checks cfg and returns a filter using it. A Window smaller than
+// MinSamples would never reject an outlier, so it is an error too.
+func NewFilter(cfg FilterConfig) (*Filter, error) {
+	switch {
+	case cfg.MinSamples < 1:
+		return nil, fmt.Errorf("feed: filter needs MinSamples >= 1, have %d", cfg.MinSamples)
+	case cfg.Window < cfg.MinSamples:
+		return nil, fmt.Errorf("feed: filter Window %d is smaller than MinSamples %d", cfg.Window, cfg.MinSamples)
+	case cfg.ConfirmAfter < 1:
+		return nil, fmt.Errorf("feed: filter needs ConfirmAfter >= 1, have %d", cfg.ConfirmAfter)
+	case !(cfg.MaxDeviation > 0):
+		return nil, fmt.Errorf("feed: filter needs MaxDeviation > 0, have %g", cfg.MaxDeviation)
+	case !(cfg.MinMove >= 0):
+		return nil, fmt.Errorf("feed: filter needs MinMove >= 0, have %g", cfg.MinMove)
+	case cfg.DedupeWindow < 0:
+		return nil, fmt.Errorf("feed: filter needs DedupeWindow >= 0, have %d", cfg.DedupeWindow)
+	case cfg.MaxLateness < 0:
+		return nil, fmt.Errorf("feed: filter needs MaxLateness >= 0, have %s", cfg.MaxLateness)
+	}
+	return &Filter{cfg: cfg, streams: make(map[[2]string]*filterStream)}, nil
+}
+
+// Check screens one tick. It returns "" if the tick may be written, after
+// possibly adjusting its time, or the reason it was rejected.
+func (f *Filter) Check(venue string, tick *ringbuffer.Tick, tradeID int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := [2]string{venue, tick.Symbol}
+	s := f.streams[key]
+	if s == nil {
+		s = &filterStream{
+			stats: FilterStats{Venue: venue, Symbol: tick.Symbol, Rejected: make(map[string]uint64)},
+			ids:   make(map[int64]struct{}),
+		}
+		f.streams[key] = s
+		f.order = append(f.order, key)
+	}
+
+	reason := f.check(s, tick, tradeID)
+	if reason != "" {
+		s.stats.Rejected[reason]++
+	}
+	return reason
+}
+
+func (f *Filter) check(s *filterStream, tick *ringbuffer.Tick, tradeID int64) string {
+	if tick.Price <= 0 || math.IsNaN(tick.Price) || math.IsInf(tick.Price, 0) {
+		return RejectInvalidPrice
+	}
+	if tick.Size < 0 || math.IsNaN(tick.Size) || math.IsInf(tick.Size, 0) {
+		return RejectInvalidSize
+	}
+
+	if tradeID != 0 {
+		if _, seen := s.ids[tradeID]; seen {
+			return RejectDuplicate
+		}
+	}
+
+	clamped := false
+	if !s.last.IsZero() && tick.Time.Before(s.last) {
+		if s.last.Sub(tick.Time) > f.cfg.MaxLateness {
+			return RejectOutOfOrder
+		}
+		tick.Time = s.last
+		clamped = true
+	}
+
+	if f.outlier(s, tick.Price) {
+		if !f.confirm(s, tick.Price) {
+			return RejectOutlier
+		}
+		s.stats.Jumps++
+		log.Printf("⚠️  %s %s price jump to %.8g confirmed after %d ticks", s.stats.Venue, s.stats.Symbol, tick.Price, f.cfg.ConfirmAfter)
+	}
+	s.pending = s.pending[:0]
+
+	f.remember(s, tradeID)
+	f.push(s, tick.Price)
+	s.last = tick.Time
+	s.stats.Accepted++
+	if clamped {
+		s.stats.Clamped++
+	}
+	return ""
+}
+
+// outlier applies the median/MAD test against the accepted window.
+func (f *Filter) outlier(s *filterStream, price float64) bool {
+	n := len(s.sorted)
+	if n < f.cfg.MinSamples {
+		return false
+	}
+	median := middle(s.sorted)
+	dev := math.Abs(price - median)
+	// Cheap test first: almost every tick is well inside MinMove
+	if dev <= f.cfg.MinMove*median {
+		return false
+	}
+
+	s.scratch = s.scratch[:0]
+	for _, p := range s.sorted {
+		s.scratch = append(s.scratch, math.Abs(p-median))
+	}
+	sort.Float64s(s.scratch)
+	mad := madScale * middle(s.scratch)
+	return dev > f.cfg.MaxDeviation*mad
+}
+
+// confirm collects consecutive outliers. Once ConfirmAfter of them agree
+// within MinMove, the market has moved: the window restarts from them and
+// the current tick is accepted.
+func (f *Filter) confirm(s *filterStream, price float64) bool {
+	s.pending = append(s.pending, price)
+	if len(s.pending) < f.cfg.ConfirmAfter {
+		return false
+	}
+	lo, hi := s.pending[0], s.pending[0]
+	for _, p := range s.pending {
+		lo, hi = math.Min(lo, p), math.Max(hi, p)
+	}
+	if hi-lo > f.cfg.MinMove*hi {
+		// Scattered bad prints, not a new level; keep only the newest
+		s.pending = append(s.pending[:0], s.pending[len(s.pending)-f.cfg.ConfirmAfter+1:]...)
+		return false
+	}
+
+	confirmed := append([]float64(nil), s.pending[:len(s.pending)-1]...)
+	s.prices, s.sorted, s.next = s.prices[:0], s.sorted[:0], 0
+	for _, p := range confirmed {
+		f.push(s, p)
+	}
+	return true
+}
+
+// push adds an accepted price to the window, evicting the oldest.
+func (f *Filter) push(s *filterStream, price float64) {
+	if len(s.prices) < f.cfg.Window {
+		s.prices = append(s.prices, price)
+	} else {
+		old := s.prices[s.next]
+		s.prices[s.next] = price
+		s.next = (s.next + 1) % f.cfg.Window
+		i := sort.SearchFloat64s(s.sorted, old)
+		s.sorted = append(s.sorted[:i], s.sorted[i+1:]...)
+	}
+	i := sort.SearchFloat64s(s.sorted, price)
+	s.sorted = append(s.sorted, 0)
+	copy(s.sorted[i+1:], s.sorted[i:])
+	s.sorted[i] = price
+}
+
+func (f *Filter) remember(s *filterStream, tradeID int64) {
+	if tradeID == 0 || f.cfg.DedupeWindow <= 0 {
+		return
+	}
+	if len(s.idRing) < f.cfg.DedupeWindow {
+		s.idRing = append(s.idRing, tradeID)
+	} else {
+		delete(s.ids, s.idRing[s.idNext])
+		s.idRing[s.idNext] = tradeID
+		s.idNext = (s.idNext + 1) % f.cfg.DedupeWindow
+	}
+	s.ids[tradeID] = struct{}{}
+}
+
+// Remove forgets every venue's stream of symbol, counters included, so a
+// symbol tracked again is screened afresh. It reports whether any existed.
+func (f *Filter) Remove(symbol string) bool {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kept := f.order[:0]
+	for _, key := range f.order {
+		if key[1] == symbol {
+			delete(f.streams, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	removed := len(kept) < len(f.order)
+	f.order = kept
+	return removed
+}
+
+// Stats returns the counters per venue and symbol, in first-seen order.
+func (f *Filter) Stats() []FilterStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]FilterStats, 0, len(f.order))
+	for _, key := range f.order {
+		stats := f.streams[key].stats
+		stats.Rejected = make(map[string]uint64, len(stats.Rejected))
+		for reason, n := range f.streams[key].stats.Rejected {
+			stats.Rejected[reason] = n
+		}
+		out = append(out, stats)
+	}
+	return out
+}
+
+// middle is the median of sorted values.
+func middle(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
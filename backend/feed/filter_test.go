@@ -0,0 +1,213 @@
+package feed
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var filterEpoch = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// filterTick is the i-th tick of a calm market around 100.
+func filterTick(i int, price float64) ringbuffer.Tick {
+	return ringbuffer.Tick{Symbol: "btcusdt", Time: filterEpoch.Add(time.Duration(i) * time.Second), Price: price, Size: 1}
+}
+
+// warmFilter feeds MinSamples calm ticks with ids 1..n so the outlier test
+// is armed, and returns the index of the next tick.
+func warmFilter(t *testing.T, f *Filter) int {
+	t.Helper()
+	n := DefaultFilterConfig().MinSamples
+	for i := 0; i < n; i++ {
+		tick := filterTick(i, 100+0.01*float64(i%5))
+		if reason := f.Check("coinbase", &tick, int64(i+1)); reason != "" {
+			t.Fatalf("warm-up tick %d rejected: %s", i, reason)
+		}
+	}
+	return n
+}
+
+func TestFilterRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		price   float64
+		size    float64
+		tradeID int64 // ids 1..MinSamples were used by the warm-up
+		late    time.Duration
+		want    string
+	}{
+		{name: "ok", price: 100.02, size: 1, tradeID: 1000, want: ""},
+		{name: "zero price", price: 0, size: 1, want: RejectInvalidPrice},
+		{name: "negative price", price: -1, size: 1, want: RejectInvalidPrice},
+		{name: "NaN price", price: math.NaN(), size: 1, want: RejectInvalidPrice},
+		{name: "Inf price", price: math.Inf(1), size: 1, want: RejectInvalidPrice},
+		{name: "negative size", price: 100, size: -1, want: RejectInvalidSize},
+		{name: "NaN size", price: 100, size: math.NaN(), want: RejectInvalidSize},
+		{name: "duplicate id", price: 100, size: 1, tradeID: 3, want: RejectDuplicate},
+		{name: "too late", price: 100, size: 1, late: time.Minute, want: RejectOutOfOrder},
+		{name: "late but tolerated", price: 100, size: 1, late: time.Second, want: ""},
+		{name: "outlier", price: 150, size: 1, want: RejectOutlier},
+		{name: "small move", price: 100.5, size: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(DefaultFilterConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := warmFilter(t, f)
+			tick := filterTick(next-1, tt.price)
+			tick.Time = tick.Time.Add(-tt.late)
+			tick.Size = tt.size
+			if got := f.Check("coinbase", &tick, tt.tradeID); got != tt.want {
+				t.Fatalf("Check = %q, want %q", got, tt.want)
+			}
+
+			stats := f.Stats()[0]
+			if tt.want == "" {
+				if stats.Accepted != uint64(next+1) {
+					t.Errorf("accepted %d, want %d", stats.Accepted, next+1)
+				}
+			} else if stats.Rejected[tt.want] != 1 {
+				t.Errorf("rejected %v, want one %s", stats.Rejected, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterClampsLateTicks(t *testing.T) {
+	f, _ := NewFilter(DefaultFilterConfig())
+	next := warmFilter(t, f)
+	last := filterTick(next-1, 100).Time
+
+	tick := filterTick(next-2, 100)
+	if reason := f.Check("coinbase", &tick, 0); reason != "" {
+		t.Fatalf("rejected: %s", reason)
+	}
+	if !tick.Time.Equal(last) {
+		t.Errorf("time %s, want clamped to %s", tick.Time, last)
+	}
+	if got := f.Stats()[0].Clamped; got != 1 {
+		t.Errorf("clamped %d, want 1", got)
+	}
+}
+
+func TestFilterConfirmsJumps(t *testing.T) {
+	cfg := DefaultFilterConfig()
+	f, _ := NewFilter(cfg)
+	next := warmFilter(t, f)
+
+	// A genuine move to 150 is rejected until ConfirmAfter ticks agree
+	for i := 0; i < cfg.ConfirmAfter; i++ {
+		tick := filterTick(next+i, 150+0.01*float64(i))
+		want := RejectOutlier
+		if i == cfg.ConfirmAfter-1 {
+			want = ""
+		}
+		if got := f.Check("coinbase", &tick, 0); got != want {
+			t.Fatalf("jump tick %d: Check = %q, want %q", i, got, want)
+		}
+	}
+	next += cfg.ConfirmAfter
+
+	// The new level is now normal and the old one is not
+	tick := filterTick(next, 150.02)
+	if got := f.Check("coinbase", &tick, 0); got != "" {
+		t.Errorf("tick at new level: %q", got)
+	}
+	if got := f.Stats()[0].Jumps; got != 1 {
+		t.Errorf("jumps %d, want 1", got)
+	}
+
+	// Scattered bad prints never confirm
+	f, _ = NewFilter(cfg)
+	next = warmFilter(t, f)
+	for i, price := range []float64{150, 60, 170, 40, 200} {
+		tick := filterTick(next+i, price)
+		if got := f.Check("coinbase", &tick, 0); got != RejectOutlier {
+			t.Fatalf("bad print %g: Check = %q, want outlier", price, got)
+		}
+	}
+}
+
+func TestFilterKeepsVenuesApart(t *testing.T) {
+	f, _ := NewFilter(DefaultFilterConfig())
+	warmFilter(t, f)
+
+	// The same id from another venue is not a duplicate
+	tick := filterTick(100, 100)
+	if got := f.Check("kraken", &tick, 3); got != "" {
+		t.Errorf("kraken tick: %q", got)
+	}
+	if got := len(f.Stats()); got != 2 {
+		t.Errorf("%d streams, want 2", got)
+	}
+}
+
+func TestNewFilterRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*FilterConfig)
+		want   string
+	}{
+		{"zero window", func(c *FilterConfig) { c.Window = 0 }, "Window"},
+		{"negative window", func(c *FilterConfig) { c.Window = -5 }, "Window"},
+		{"window below min samples", func(c *FilterConfig) { c.Window = 10 }, "Window"},
+		{"zero min samples", func(c *FilterConfig) { c.MinSamples = 0 }, "MinSamples"},
+		{"zero confirm", func(c *FilterConfig) { c.ConfirmAfter = 0 }, "ConfirmAfter"},
+		{"zero deviation", func(c *FilterConfig) { c.MaxDeviation = 0 }, "MaxDeviation"},
+		{"NaN deviation", func(c *FilterConfig) { c.MaxDeviation = math.NaN() }, "MaxDeviation"},
+		{"negative min move", func(c *FilterConfig) { c.MinMove = -0.1 }, "MinMove"},
+		{"negative dedupe", func(c *FilterConfig) { c.DedupeWindow = -1 }, "DedupeWindow"},
+		{"negative lateness", func(c *FilterConfig) { c.MaxLateness = -time.Second }, "MaxLateness"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultFilterConfig()
+			tt.change(&cfg)
+			f, err := NewFilter(cfg)
+			if err == nil || f != nil {
+				t.Fatalf("NewFilter accepted %+v", cfg)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %s", err, tt.want)
+			}
+		})
+	}
+
+	cfg := DefaultFilterConfig()
+	cfg.Window = cfg.MinSamples
+	if _, err := NewFilter(cfg); err != nil {
+		t.Errorf("Window == MinSamples rejected: %v", err)
+	}
+}
+
+func TestFilterRemove(t *testing.T) {
+	f, _ := NewFilter(DefaultFilterConfig())
+	next := warmFilter(t, f)
+	for _, venue := range []string{"kraken", "binance"} {
+		tick := filterTick(next, 100)
+		f.Check(venue, &tick, 1)
+	}
+	eth := ringbuffer.Tick{Symbol: "ethusdt", Time: filterEpoch, Price: 3400, Size: 1}
+	f.Check("coinbase", &eth, 1)
+
+	if !f.Remove("BTCUSDT") {
+		t.Fatal("btcusdt not removed")
+	}
+	if stats := f.Stats(); len(stats) != 1 || stats[0].Symbol != "ethusdt" {
+		t.Fatalf("stats %+v after removing btcusdt", stats)
+	}
+	if f.Remove("btcusdt") {
+		t.Error("btcusdt removed twice")
+	}
+
+	// Tracked again, old trade ids and prices no longer count against it
+	tick := filterTick(0, 5000)
+	if got := f.Check("coinbase", &tick, 1); got != "" {
+		t.Errorf("first tick after re-adding rejected: %s", got)
+	}
+}
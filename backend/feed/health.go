@@ -0,0 +1,189 @@
+package feed
+
+import (
+	"sync"
+	"time"
+)
+
+// Connection states reported in Health.
+const (
+	StateIdle         = "idle"
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected"
+	StateBackoff      = "backoff"   // waiting to retry after a failure
+	StateUnhealthy    = "unhealthy" // retries exhausted, circuit open
+	StateHalfOpen     = "half-open" // circuit open, probing with one attempt
+	StateStopped      = "stopped"
+)
+
+// maxTransitions is how many recent state changes Health keeps.
+const maxTransitions = 16
+
+// Transition is one change of connection state.
+type Transition struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Health is a point-in-time view of an adapter's connection.
+type Health struct {
+	Venue       string       `json:"venue"`
+	State       string       `json:"state"`
+	Since       time.Time    `json:"since"`       // when State was entered
+	Connections int          `json:"connections"` // open WebSocket connections
+	Symbols     []string     `json:"symbols"`
+	LastMessage time.Time    `json:"lastMessage"`
+	Messages    uint64       `json:"messages"`
+	Reconnects  uint64       `json:"reconnects"`
+	Failures    int          `json:"consecutiveFailures"`
+	NextRetry   time.Time    `json:"nextRetry"`
+	Transitions uint64       `json:"transitions"`
+	History     []Transition `json:"history"`      // most recent last
+	TradeGaps   uint64       `json:"tradeGaps"`    // jumps in trade ids
+	Missed      uint64       `json:"missedTrades"` // trades skipped by those jumps
+	OutOfOrder  uint64       `json:"outOfOrder"`   // stale or duplicate messages dropped
+	LastError   string       `json:"lastError,omitempty"`
+}
+
+// Healthy reports whether the feed is usable or still trying to be; only
+// an open circuit, probed or not, or a stopped feed is unhealthy.
+func (h Health) Healthy() bool {
+	return h.State != StateUnhealthy && h.State != StateHalfOpen && h.State != StateStopped
+}
+
+// HealthTracker is embedded by adapters to maintain their Health.
+type HealthTracker struct {
+	mu     sync.Mutex
+	health Health
+	now    func() time.Time
+}
+
+func NewHealthTracker(venue string) *HealthTracker {
+	return &HealthTracker{health: Health{Venue: venue, State: StateIdle, Since: time.Now()}, now: time.Now}
+}
+
+// setState records a transition. Callers hold h.mu.
+func (h *HealthTracker) setState(state string, err error) {
+	if h.health.State == state {
+		return
+	}
+	t := Transition{From: h.health.State, To: state, At: h.now()}
+	if err != nil {
+		t.Error = err.Error()
+	}
+	if len(h.health.History) == maxTransitions {
+		h.health.History = append(h.health.History[:0], h.health.History[1:]...)
+	}
+	h.health.History = append(h.health.History, t)
+	h.health.Transitions++
+	h.health.State = state
+	h.health.Since = t.At
+}
+
+// Connecting records a connection attempt; with the circuit open it is the
+// probe that either closes it or opens it again.
+func (h *HealthTracker) Connecting() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.health.Connections > 0 {
+		return
+	}
+	if h.health.State == StateUnhealthy {
+		h.setState(StateHalfOpen, nil)
+	} else if h.health.State != StateHalfOpen {
+		h.setState(StateConnecting, nil)
+	}
+}
+
+func (h *HealthTracker) Connected() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.Connections++
+	h.health.Failures = 0
+	h.health.NextRetry = time.Time{}
+	h.setState(StateConnected, nil)
+}
+
+// Disconnected records a dropped connection and the error that caused it.
+func (h *HealthTracker) Disconnected(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.health.Connections > 0 {
+		h.health.Connections--
+	}
+	h.health.Reconnects++
+	if err != nil {
+		h.health.LastError = err.Error()
+	}
+	if h.health.Connections == 0 {
+		h.setState(StateDisconnected, err)
+	}
+}
+
+// Failed records a connection attempt that did not get through.
+func (h *HealthTracker) Failed(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.LastError = err.Error()
+}
+
+// Retrying records the wait before the next attempt. open means the policy
+// ran out of attempts and the feed is now unhealthy.
+func (h *HealthTracker) Retrying(failures int, delay time.Duration, open bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.Failures = failures
+	h.health.NextRetry = h.now().Add(delay)
+	if open {
+		h.setState(StateUnhealthy, nil)
+	} else if h.health.State != StateUnhealthy && h.health.State != StateHalfOpen {
+		h.setState(StateBackoff, nil)
+	}
+}
+
+func (h *HealthTracker) Message(at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.Messages++
+	h.health.LastMessage = at
+}
+
+// TradeGap records a jump in trade ids that skipped missed trades.
+func (h *HealthTracker) TradeGap(missed int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.TradeGaps++
+	h.health.Missed += uint64(missed)
+}
+
+func (h *HealthTracker) OutOfOrder() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.OutOfOrder++
+}
+
+func (h *HealthTracker) SetSymbols(symbols []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.Symbols = append([]string(nil), symbols...)
+}
+
+func (h *HealthTracker) Stopped() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health.Connections = 0
+	h.health.NextRetry = time.Time{}
+	h.setState(StateStopped, nil)
+}
+
+func (h *HealthTracker) Health() Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	health := h.health
+	health.Symbols = append([]string(nil), h.health.Symbols...)
+	health.History = append([]Transition(nil), h.health.History...)
+	return health
+}
@@ -0,0 +1,131 @@
+package feed
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// manualClock is a HealthTracker clock moved by hand.
+type manualClock struct{ at time.Time }
+
+func (c *manualClock) now() time.Time          { return c.at }
+func (c *manualClock) advance(d time.Duration) { c.at = c.at.Add(d) }
+
+func newTestTracker() (*HealthTracker, *manualClock) {
+	clock := &manualClock{at: time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)}
+	h := NewHealthTracker("coinbase")
+	h.now = clock.now
+	return h, clock
+}
+
+func TestHealthTransitions(t *testing.T) {
+	h, clock := newTestTracker()
+	start := clock.at
+	drop := errors.New("read: connection reset")
+
+	h.Connecting()
+	clock.advance(time.Second)
+	h.Connected()
+	clock.advance(time.Minute)
+	h.Disconnected(drop)
+	h.Retrying(1, 500*time.Millisecond, false)
+	if got := h.Health(); got.State != StateBackoff || !got.Healthy() || got.NextRetry != clock.at.Add(500*time.Millisecond) {
+		t.Fatalf("after a drop: %+v", got)
+	}
+
+	// Retries run out and the circuit opens
+	clock.advance(time.Second)
+	h.Connecting()
+	h.Failed(errors.New("dial: refused"))
+	h.Retrying(10, 2*time.Minute, true)
+	if got := h.Health(); got.State != StateUnhealthy || got.Healthy() || got.Failures != 10 {
+		t.Fatalf("after the circuit opened: %+v", got)
+	}
+
+	// A failed probe opens it again, a successful one closes it
+	clock.advance(2 * time.Minute)
+	h.Connecting()
+	if got := h.Health(); got.State != StateHalfOpen || got.Healthy() {
+		t.Fatalf("probing: %+v", got)
+	}
+	h.Retrying(11, 2*time.Minute, true)
+	clock.advance(2 * time.Minute)
+	h.Connecting()
+	clock.advance(time.Second)
+	h.Connected()
+
+	got := h.Health()
+	if got.State != StateConnected || !got.Healthy() || got.Failures != 0 || !got.NextRetry.IsZero() {
+		t.Fatalf("after recovering: %+v", got)
+	}
+	if got.Since != clock.at || got.Reconnects != 1 || got.LastError != "dial: refused" {
+		t.Fatalf("after recovering: %+v", got)
+	}
+
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	want := []Transition{
+		{From: StateIdle, To: StateConnecting, At: at(0)},
+		{From: StateConnecting, To: StateConnected, At: at(time.Second)},
+		{From: StateConnected, To: StateDisconnected, At: at(61 * time.Second), Error: drop.Error()},
+		{From: StateDisconnected, To: StateBackoff, At: at(61 * time.Second)},
+		{From: StateBackoff, To: StateConnecting, At: at(62 * time.Second)},
+		{From: StateConnecting, To: StateUnhealthy, At: at(62 * time.Second)},
+		{From: StateUnhealthy, To: StateHalfOpen, At: at(182 * time.Second)},
+		{From: StateHalfOpen, To: StateUnhealthy, At: at(182 * time.Second)},
+		{From: StateUnhealthy, To: StateHalfOpen, At: at(302 * time.Second)},
+		{From: StateHalfOpen, To: StateConnected, At: at(303 * time.Second)},
+	}
+	if !reflect.DeepEqual(got.History, want) {
+		t.Fatalf("history\n%+v\nwant\n%+v", got.History, want)
+	}
+	if got.Transitions != uint64(len(want)) {
+		t.Fatalf("%d transitions, want %d", got.Transitions, len(want))
+	}
+}
+
+func TestHealthSharedConnections(t *testing.T) {
+	h, _ := newTestTracker()
+	h.Connected()
+	h.Connected()
+
+	// One of two connections dropping leaves the feed connected
+	h.Disconnected(errors.New("eof"))
+	h.Connecting()
+	if got := h.Health(); got.State != StateConnected || got.Connections != 1 {
+		t.Fatalf("after one drop: %+v", got)
+	}
+	h.Disconnected(nil)
+	if got := h.Health(); got.State != StateDisconnected || got.Connections != 0 || got.Reconnects != 2 {
+		t.Fatalf("after both dropped: %+v", got)
+	}
+
+	h.Stopped()
+	if got := h.Health(); got.State != StateStopped || got.Healthy() {
+		t.Fatalf("after stopping: %+v", got)
+	}
+}
+
+func TestHealthHistoryCap(t *testing.T) {
+	h, clock := newTestTracker()
+	for i := 0; i < 20; i++ {
+		clock.advance(time.Second)
+		h.Connected()
+		clock.advance(time.Second)
+		h.Disconnected(nil)
+	}
+	got := h.Health()
+	if len(got.History) != maxTransitions || got.Transitions != 40 {
+		t.Fatalf("%d kept of %d transitions", len(got.History), got.Transitions)
+	}
+	if last := got.History[maxTransitions-1]; last.To != StateDisconnected || last.At != clock.at {
+		t.Fatalf("last transition %+v, want the latest drop", last)
+	}
+
+	// Health hands out copies
+	got.History[0].To = "mangled"
+	if h.Health().History[0].To == "mangled" {
+		t.Fatal("Health shares its history")
+	}
+}
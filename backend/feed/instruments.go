@@ -0,0 +1,102 @@
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Instrument maps one normalized symbol to each venue's product id.
+type Instrument struct {
+	Symbol string            `json:"symbol"` // normalized, e.g. "btcusdt"
+	Base   string            `json:"base"`
+	Quote  string            `json:"quote"`
+	Venues map[string]string `json:"venues"` // venue name -> venue product id
+}
+
+var instrumentsMu sync.RWMutex
+
+// instruments is the single source of truth for symbol naming across
+// venues. Coinbase, Kraken and Bitstamp quote these pairs in USD rather
+// than USDT.
+var instruments = []Instrument{
+	{Symbol: "btcusdt", Base: "BTC", Quote: "USDT", Venues: map[string]string{
+		"coinbase":  "BTC-USD",
+		"binance":   "BTCUSDT",
+		"kraken":    "BTC/USD",
+		"bitstamp":  "btcusd",
+		"synthetic": "btcusdt",
+	}},
+	{Symbol: "ethusdt", Base: "ETH", Quote: "USDT", Venues: map[string]string{
+		"coinbase":  "ETH-USD",
+		"binance":   "ETHUSDT",
+		"kraken":    "ETH/USD",
+		"bitstamp":  "ethusd",
+		"synthetic": "ethusdt",
+	}},
+	{Symbol: "solusdt", Base: "SOL", Quote: "USDT", Venues: map[string]string{
+		"coinbase":  "SOL-USD",
+		"binance":   "SOLUSDT",
+		"kraken":    "SOL/USD",
+		"bitstamp":  "solusd",
+		"synthetic": "solusdt",
+	}},
+	{Symbol: "bnbusdt", Base: "BNB", Quote: "USDT", Venues: map[string]string{
+		"binance":   "BNBUSDT",
+		"synthetic": "bnbusdt",
+	}},
+}
+
+// Lookup returns the instrument for a normalized symbol.
+func Lookup(symbol string) (Instrument, bool) {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	instrumentsMu.RLock()
+	defer instrumentsMu.RUnlock()
+	for _, inst := range instruments {
+		if inst.Symbol == symbol {
+			return inst, true
+		}
+	}
+	return Instrument{}, false
+}
+
+// VenueSymbol translates a normalized symbol to the venue's product id.
+func VenueSymbol(venue, symbol string) (string, bool) {
+	inst, ok := Lookup(symbol)
+	if !ok {
+		return "", false
+	}
+	product, ok := inst.Venues[venue]
+	return product, ok
+}
+
+// Normalize translates a venue product id back to the normalized symbol.
+// Matching is case-insensitive, since venues disagree on case.
+func Normalize(venue, product string) (string, bool) {
+	instrumentsMu.RLock()
+	defer instrumentsMu.RUnlock()
+	for _, inst := range instruments {
+		if p, ok := inst.Venues[venue]; ok && strings.EqualFold(p, product) {
+			return inst.Symbol, true
+		}
+	}
+	return "", false
+}
+
+// AddInstrument lists a new instrument at runtime. Symbols that already
+// exist cannot be redefined.
+func AddInstrument(inst Instrument) error {
+	inst.Symbol = ringbuffer.NormalizeSymbol(inst.Symbol)
+	if inst.Symbol == "" || len(inst.Venues) == 0 {
+		return fmt.Errorf("feed: instrument needs a symbol and at least one venue")
+	}
+	if _, ok := Lookup(inst.Symbol); ok {
+		return fmt.Errorf("feed: instrument %s already exists", inst.Symbol)
+	}
+	instrumentsMu.Lock()
+	defer instrumentsMu.Unlock()
+	instruments = append(instruments, inst)
+	return nil
+}
@@ -0,0 +1,199 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Policy governs how an adapter reconnects and detects dead connections.
+// Zero fields take their DefaultPolicy values.
+type Policy struct {
+	InitialDelay time.Duration // first retry delay
+	MaxDelay     time.Duration // cap on the exponential delay
+	Multiplier   float64       // growth per consecutive failure
+	Jitter       float64       // +/- fraction applied to every delay
+	// MaxAttempts consecutive failures open the circuit: the feed is
+	// reported unhealthy and retried only every CircuitOpen.
+	MaxAttempts int
+	CircuitOpen time.Duration
+	// StableAfter is how long a connection must last before its drop no
+	// longer counts as a failure.
+	StableAfter time.Duration
+
+	ReadTimeout  time.Duration // no frame (data or pong) for this long kills the connection
+	PingInterval time.Duration // client heartbeat
+	WriteTimeout time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxAttempts:  10,
+		CircuitOpen:  2 * time.Minute,
+		StableAfter:  30 * time.Second,
+		ReadTimeout:  60 * time.Second,
+		PingInterval: 20 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+// WithDefaults fills zero fields from DefaultPolicy.
+func (p Policy) WithDefaults() Policy {
+	d := DefaultPolicy()
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = d.InitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = d.Multiplier
+	}
+	if p.Jitter <= 0 || p.Jitter >= 1 {
+		p.Jitter = d.Jitter
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.CircuitOpen <= 0 {
+		p.CircuitOpen = d.CircuitOpen
+	}
+	if p.StableAfter <= 0 {
+		p.StableAfter = d.StableAfter
+	}
+	if p.ReadTimeout <= 0 {
+		p.ReadTimeout = d.ReadTimeout
+	}
+	if p.PingInterval <= 0 {
+		p.PingInterval = d.PingInterval
+	}
+	if p.WriteTimeout <= 0 {
+		p.WriteTimeout = d.WriteTimeout
+	}
+	return p
+}
+
+// Backoff counts consecutive failures under a Policy. It is owned by one
+// connection loop and not safe for concurrent use.
+type Backoff struct {
+	policy   Policy
+	failures int
+	random   func() float64 // in [0, 1), for jitter
+}
+
+func NewBackoff(p Policy) *Backoff {
+	return &Backoff{policy: p.WithDefaults(), random: rand.Float64}
+}
+
+// Next records a failure and returns the delay before the next attempt.
+// open is true once MaxAttempts consecutive failures have been reached.
+func (b *Backoff) Next() (delay time.Duration, open bool) {
+	b.failures++
+	if b.failures >= b.policy.MaxAttempts {
+		return b.jitter(b.policy.CircuitOpen), true
+	}
+	d := float64(b.policy.InitialDelay) * math.Pow(b.policy.Multiplier, float64(b.failures-1))
+	if d > float64(b.policy.MaxDelay) {
+		d = float64(b.policy.MaxDelay)
+	}
+	return b.jitter(time.Duration(d)), false
+}
+
+func (b *Backoff) jitter(d time.Duration) time.Duration {
+	f := 1 - b.policy.Jitter + 2*b.policy.Jitter*b.random()
+	return time.Duration(float64(d) * f)
+}
+
+// Reset clears the failure count after a connection proved stable.
+func (b *Backoff) Reset() {
+	b.failures = 0
+}
+
+func (b *Backoff) Failures() int {
+	return b.failures
+}
+
+// Ended classifies a connection that was up for lived: a stable one resets
+// the failure count, a short-lived one counts as a failure.
+func (b *Backoff) Ended(lived time.Duration) {
+	if lived >= b.policy.StableAfter {
+		b.Reset()
+	}
+}
+
+// Wait records a failure on h and sleeps out the backoff delay. It returns
+// false if ctx was cancelled meanwhile.
+func Wait(ctx context.Context, b *Backoff, h *HealthTracker) bool {
+	delay, open := b.Next()
+	h.Retrying(b.Failures(), delay, open)
+	if open {
+		log.Printf("❌ %s unhealthy after %d failed attempts, retrying in %s",
+			h.Health().Venue, b.Failures(), delay.Round(time.Second))
+	}
+	return Sleep(ctx, delay)
+}
+
+// Sleep waits for d and reports whether it did so without ctx being
+// cancelled.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// KeepAlive arms conn's read deadline, extends it on every pong and server
+// ping, and pings the server every PingInterval. Adapters call Touch on each
+// message. The returned stop function ends the pinger.
+func (p Policy) KeepAlive(conn *websocket.Conn) (stop func()) {
+	p = p.WithDefaults()
+	p.Touch(conn)
+
+	conn.SetPongHandler(func(string) error {
+		p.Touch(conn)
+		return nil
+	})
+	ping := conn.PingHandler()
+	conn.SetPingHandler(func(data string) error {
+		p.Touch(conn)
+		err := ping(data)
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(p.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// A failed ping surfaces as a read error soon enough
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(p.WriteTimeout))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Touch pushes conn's read deadline ReadTimeout into the future.
+func (p Policy) Touch(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(p.ReadTimeout))
+}
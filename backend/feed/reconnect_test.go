@@ -0,0 +1,104 @@
+package feed
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedBackoff returns a Backoff whose jitter draws r every time.
+func fixedBackoff(p Policy, r float64) *Backoff {
+	b := NewBackoff(p)
+	b.random = func() float64 { return r }
+	return b
+}
+
+func TestBackoffGrowthAndCap(t *testing.T) {
+	p := Policy{InitialDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second, Multiplier: 2, MaxAttempts: 8, CircuitOpen: time.Minute}
+	// 0.5 makes the jitter factor exactly 1
+	b := fixedBackoff(p, 0.5)
+
+	want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		delay, open := b.Next()
+		if delay != w || open {
+			t.Fatalf("failure %d: delay %s open %v, want %s closed", i+1, delay, open, w)
+		}
+		if b.Failures() != i+1 {
+			t.Fatalf("failure %d counted as %d", i+1, b.Failures())
+		}
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	p := Policy{InitialDelay: time.Second, MaxDelay: time.Minute, Multiplier: 2, Jitter: 0.25}
+	tests := []struct {
+		random float64
+		want   time.Duration
+	}{
+		{random: 0, want: 750 * time.Millisecond},
+		{random: 0.5, want: time.Second},
+		{random: 0.999999, want: 1249999500 * time.Nanosecond},
+	}
+	for _, tt := range tests {
+		delay, _ := fixedBackoff(p, tt.random).Next()
+		if delay != tt.want {
+			t.Errorf("random %v: delay %s, want %s", tt.random, delay, tt.want)
+		}
+	}
+
+	// The real source stays within +/- Jitter at every step
+	b := NewBackoff(p)
+	for i := 0; i < 8; i++ {
+		base := time.Second << i
+		if base > p.MaxDelay {
+			base = p.MaxDelay
+		}
+		delay, _ := b.Next()
+		if delay < base*3/4 || delay > base*5/4 {
+			t.Fatalf("failure %d: delay %s outside %s +/- 25%%", i+1, delay, base)
+		}
+	}
+}
+
+func TestBackoffCircuit(t *testing.T) {
+	p := Policy{InitialDelay: time.Second, MaxDelay: 10 * time.Second, MaxAttempts: 3, CircuitOpen: 2 * time.Minute, StableAfter: 30 * time.Second}
+	b := fixedBackoff(p, 0.5)
+
+	for i := 1; i < 3; i++ {
+		if _, open := b.Next(); open {
+			t.Fatalf("circuit open after %d failures", i)
+		}
+	}
+	// Once open it stays open, probing every CircuitOpen
+	for i := 0; i < 3; i++ {
+		delay, open := b.Next()
+		if !open || delay != 2*time.Minute {
+			t.Fatalf("failure %d: delay %s open %v, want 2m0s open", b.Failures(), delay, open)
+		}
+	}
+
+	// A short-lived connection does not close it
+	b.Ended(10 * time.Second)
+	if _, open := b.Next(); !open {
+		t.Fatal("circuit closed by a connection shorter than StableAfter")
+	}
+	// A stable one does, and the delays start over
+	b.Ended(30 * time.Second)
+	if b.Failures() != 0 {
+		t.Fatalf("%d failures after a stable connection", b.Failures())
+	}
+	if delay, open := b.Next(); delay != time.Second || open {
+		t.Fatalf("first failure after reset: delay %s open %v", delay, open)
+	}
+}
+
+func TestPolicyWithDefaults(t *testing.T) {
+	d := DefaultPolicy()
+	if got := (Policy{}).WithDefaults(); got != d {
+		t.Fatalf("zero policy became %+v, want %+v", got, d)
+	}
+	p := Policy{Multiplier: 0.5, Jitter: 1, MaxDelay: time.Second}.WithDefaults()
+	if p.Multiplier != d.Multiplier || p.Jitter != d.Jitter || p.MaxDelay != time.Second {
+		t.Fatalf("got %+v", p)
+	}
+}
@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Router writes feed events into the per-symbol buffers and order books.
+// All feeds are funnelled through one goroutine, so each buffer keeps a
+// single writer (required by the lock-free buffer mode) no matter how many
+// venues feed it. Trades and ticker updates pass through the filter, if
+// any, before they are written, and are replaced by the cross-venue
+// composite when a consolidator is set.
+type Router struct {
+	buffers *ringbuffer.Registry
+	books   *orderbook.Registry
+	filter  *Filter
+	merged  *Consolidator
+	feeds   map[string]MarketDataFeed
+	live    map[string]map[string]bool // symbol -> connected venues
+	events  chan Event
+	wg      sync.WaitGroup
+}
+
+func NewRouter(buffers *ringbuffer.Registry, books *orderbook.Registry, filter *Filter) *Router {
+	return &Router{
+		buffers: buffers,
+		books:   books,
+		filter:  filter,
+		feeds:   make(map[string]MarketDataFeed),
+		live:    make(map[string]map[string]bool),
+		events:  make(chan Event, 4096),
+	}
+}
+
+// Consolidate makes the router write composite prices from c into the
+// buffers instead of each venue's own. Call it before Run.
+func (r *Router) Consolidate(c *Consolidator) {
+	r.merged = c
+}
+
+// Attach forwards a feed's events to the router until the feed closes its
+// Events channel.
+func (r *Router) Attach(f MarketDataFeed) {
+	r.feeds[f.Name()] = f
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for ev := range f.Events() {
+			r.events <- ev
+		}
+	}()
+}
+
+// Run applies events until every attached feed has stopped. Attach all
+// feeds before calling Run.
+func (r *Router) Run() {
+	go func() {
+		r.wg.Wait()
+		close(r.events)
+	}()
+
+	for ev := range r.events {
+		r.apply(ev)
+	}
+}
+
+// apply writes trades and ticker updates into the symbol's buffer and
+// book updates into the venue's book. Quotes and klines are not part of the
+// tick stream and are left to other consumers.
+func (r *Router) apply(ev Event) {
+	switch ev.Kind {
+	case EventTick, EventTrade:
+		buffer, ok := r.buffers.Get(ev.Symbol)
+		if !ok {
+			return
+		}
+		if r.filter != nil && r.filter.Check(ev.Venue, &ev.Tick, ev.TradeID) != "" {
+			return
+		}
+		if r.merged != nil {
+			ev.Tick = r.merged.Update(ev.Venue, ev.Tick)
+		}
+		buffer.WriteTick(ev.Tick)
+	case EventBook:
+		if _, ok := r.buffers.Get(ev.Symbol); !ok {
+			return
+		}
+		if err := r.books.Book(ev.Venue, ev.Symbol).Apply(ev.Book); err != nil {
+			r.resync(ev.Venue, ev.Symbol, err)
+		}
+	case EventStatus:
+		if r.merged != nil && !ev.Connected {
+			r.merged.Drop(ev.Venue, ev.Symbols)
+		}
+		now := time.Now()
+		for _, symbol := range ev.Symbols {
+			buffer, ok := r.buffers.Get(symbol)
+			if !ok {
+				continue
+			}
+			venues := r.live[symbol]
+			if venues == nil {
+				venues = make(map[string]bool)
+				r.live[symbol] = venues
+			}
+			if ev.Connected {
+				venues[ev.Venue] = true
+				buffer.MarkConnected(now)
+				continue
+			}
+			// Deltas missed while disconnected make the book stale
+			if book, ok := r.books.Get(symbol, ev.Venue); ok {
+				book.Reset()
+			}
+			// The buffer only has a gap once no venue is feeding it
+			delete(venues, ev.Venue)
+			if len(venues) == 0 {
+				buffer.MarkDisconnected(now)
+			}
+		}
+	}
+}
+
+func (r *Router) resync(venue, symbol string, cause error) {
+	f, ok := r.feeds[venue].(BookResyncer)
+	if !ok {
+		return
+	}
+	log.Printf("🔄 Resyncing %s %s order book: %v", venue, symbol, cause)
+	if err := f.ResyncBook(symbol); err != nil {
+		log.Printf("⚠️  Order book resync failed for %s %s: %v", venue, symbol, err)
+	}
+}
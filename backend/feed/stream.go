@@ -0,0 +1,413 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// ErrReconnect ends a connection without counting it as an outage: the
+// stream reconnects at once and reports no disconnect. Handlers return it,
+// wrapped, when the venue asks clients to reconnect.
+var ErrReconnect = errors.New("reconnect requested")
+
+var errAgeLimit = fmt.Errorf("%w at the connection age limit", ErrReconnect)
+
+// Protocol is the venue-specific half of a WebSocket adapter: how to ask
+// for symbols and how to read frames. Stream does everything else.
+type Protocol interface {
+	// Send writes a subscribe request for symbols to conn, or an
+	// unsubscribe request if subscribe is false. Stream holds its lock,
+	// which serialises writes, and has set the write deadline.
+	Send(conn *websocket.Conn, subscribe bool, symbols []string) error
+	// Session returns the handler for the frames of one new connection.
+	// State that is only valid per connection, such as trade id baselines,
+	// belongs in its closure.
+	Session() Handler
+}
+
+// Handler processes one frame. An error ends the connection.
+type Handler func(ctx context.Context, frame []byte, receivedAt time.Time) error
+
+type StreamConfig struct {
+	Venue    string
+	URL      string
+	Header   http.Header // sent with the handshake
+	Policy   Policy
+	Recorder Recorder
+	// MaxAge rotates connections before the venue closes them; 0 keeps
+	// them for as long as they last.
+	MaxAge time.Duration
+}
+
+// Stream runs a WebSocket adapter's connection: it dials with backoff,
+// subscribes every symbol on each connection, keeps the connection alive,
+// records raw frames and reports health and status events. Adapters embed
+// it, so its methods complete their MarketDataFeed.
+type Stream struct {
+	venue    string
+	title    string // venue name for logs
+	url      string
+	header   http.Header
+	policy   Policy
+	recorder Recorder
+	maxAge   time.Duration
+	protocol Protocol
+	events   chan Event
+	health   *HealthTracker
+
+	mu      sync.Mutex
+	symbols []string
+	conn    *websocket.Conn
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+func NewStream(cfg StreamConfig, protocol Protocol) *Stream {
+	return &Stream{
+		venue:    cfg.Venue,
+		title:    strings.ToUpper(cfg.Venue[:1]) + cfg.Venue[1:],
+		url:      cfg.URL,
+		header:   cfg.Header,
+		policy:   cfg.Policy.WithDefaults(),
+		recorder: cfg.Recorder,
+		maxAge:   cfg.MaxAge,
+		protocol: protocol,
+		events:   make(chan Event, 1024),
+		health:   NewHealthTracker(cfg.Venue),
+	}
+}
+
+func (s *Stream) Name() string {
+	return s.venue
+}
+
+func (s *Stream) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Stream) Health() Health {
+	return s.health.Health()
+}
+
+// Tracker is the health tracker, for adapters to count trade gaps and
+// out-of-order messages.
+func (s *Stream) Tracker() *HealthTracker {
+	return s.health
+}
+
+func (s *Stream) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil || s.stopped {
+		return fmt.Errorf("%s: feed already started", s.venue)
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(s.ctx)
+	}()
+	return nil
+}
+
+func (s *Stream) Stop() error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	close(s.events)
+	s.health.Stopped()
+	return nil
+}
+
+// Subscribe adds symbols, subscribing on the live connection if there is
+// one. Reconnects resubscribe everything.
+func (s *Stream) Subscribe(symbols []string) error {
+	s.mu.Lock()
+	var added []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if contains(s.symbols, symbol) || contains(added, symbol) {
+			continue
+		}
+		if _, ok := VenueSymbol(s.venue, symbol); !ok {
+			log.Printf("⚠️  %s does not list %s, skipping", s.title, symbol)
+			continue
+		}
+		added = append(added, symbol)
+	}
+	var err error
+	live := s.conn != nil && len(added) > 0
+	if live {
+		err = s.send(s.conn, true, added)
+	}
+	if err == nil {
+		s.symbols = append(s.symbols, added...)
+		s.health.SetSymbols(s.symbols)
+	}
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("%s: subscribe: %w", s.venue, err)
+	}
+	if live {
+		log.Printf("➕ %s subscribed: %s", s.title, strings.Join(added, ", "))
+		s.Emit(ctx, Event{Kind: EventStatus, Venue: s.venue, Connected: true, Symbols: added})
+	}
+	return nil
+}
+
+// Unsubscribe drops symbols. Their buffers are marked disconnected so
+// strategies stop trusting the data that is left.
+func (s *Stream) Unsubscribe(symbols []string) error {
+	s.mu.Lock()
+	var removed []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if contains(s.symbols, symbol) && !contains(removed, symbol) {
+			removed = append(removed, symbol)
+		}
+	}
+	var err error
+	if s.conn != nil && len(removed) > 0 {
+		err = s.send(s.conn, false, removed)
+	}
+	// Forget the symbols even if the request failed; frames for them are
+	// dropped and the next reconnect will not resubscribe them
+	kept := s.symbols[:0]
+	for _, symbol := range s.symbols {
+		if !contains(removed, symbol) {
+			kept = append(kept, symbol)
+		}
+	}
+	s.symbols = kept
+	s.health.SetSymbols(s.symbols)
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if len(removed) > 0 && ctx != nil {
+		log.Printf("➖ %s unsubscribed: %s", s.title, strings.Join(removed, ", "))
+		s.Emit(ctx, Event{Kind: EventStatus, Venue: s.venue, Connected: false, Symbols: removed})
+	}
+	if err != nil {
+		return fmt.Errorf("%s: unsubscribe: %w", s.venue, err)
+	}
+	return nil
+}
+
+// Active reports whether symbol is still subscribed, so frames that were
+// in flight when it was dropped are ignored.
+func (s *Stream) Active(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return contains(s.symbols, symbol)
+}
+
+// Emit delivers ev unless ctx is cancelled first.
+func (s *Stream) Emit(ctx context.Context, ev Event) {
+	select {
+	case s.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// Write runs fn on the live connection with the write deadline set and
+// writes serialised. It does nothing while disconnected.
+func (s *Stream) Write(fn func(conn *websocket.Conn) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.policy.WriteTimeout))
+	return fn(s.conn)
+}
+
+// Go runs fn in the background if the stream is running. Stop cancels ctx
+// and waits for fn to return.
+func (s *Stream) Go(fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil || s.stopped {
+		return
+	}
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn(ctx)
+	}()
+}
+
+// send forwards a request to the protocol. Callers hold s.mu.
+func (s *Stream) send(conn *websocket.Conn, subscribe bool, symbols []string) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+	conn.SetWriteDeadline(time.Now().Add(s.policy.WriteTimeout))
+	return s.protocol.Send(conn, subscribe, symbols)
+}
+
+func (s *Stream) run(ctx context.Context) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+	}
+
+	backoff := NewBackoff(s.policy)
+	for ctx.Err() == nil {
+		s.health.Connecting()
+		conn, resp, err := dialer.DialContext(ctx, s.url, s.header)
+		if err != nil {
+			if resp != nil {
+				log.Printf("❌ %s connection failed: HTTP %d - %v", s.title, resp.StatusCode, err)
+			} else {
+				log.Printf("❌ %s connection failed: %v", s.title, err)
+			}
+			s.health.Failed(err)
+			Wait(ctx, backoff, s.health)
+			continue
+		}
+
+		s.mu.Lock()
+		s.conn = conn
+		symbols := append([]string(nil), s.symbols...)
+		err = s.send(conn, true, symbols)
+		s.mu.Unlock()
+		if err != nil {
+			log.Printf("❌ %s subscribe failed: %v", s.title, err)
+			s.health.Failed(err)
+			s.detach(conn)
+			Wait(ctx, backoff, s.health)
+			continue
+		}
+
+		log.Printf("✅ Connected to %s: %s", s.title, strings.Join(symbols, ", "))
+		s.health.Connected()
+		s.Emit(ctx, Event{Kind: EventStatus, Venue: s.venue, Connected: true, Symbols: symbols})
+
+		connectedAt := time.Now()
+		err = s.read(ctx, conn)
+		s.detach(conn)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Planned reconnects happen at once and are not reported as
+		// outages; the frames lost in between are at most a handshake's
+		// worth
+		if errors.Is(err, ErrReconnect) {
+			log.Printf("🔄 Reconnecting to %s: %v", s.title, err)
+			s.health.Disconnected(nil)
+			backoff.Reset()
+			continue
+		}
+
+		s.mu.Lock()
+		symbols = append([]string(nil), s.symbols...)
+		s.mu.Unlock()
+
+		log.Printf("⚠️  %s connection lost: %v", s.title, err)
+		s.health.Disconnected(err)
+		s.Emit(ctx, Event{Kind: EventStatus, Venue: s.venue, Connected: false, Symbols: symbols})
+
+		// A connection that dropped soon after opening counts towards the
+		// circuit breaker like a failed dial
+		backoff.Ended(time.Since(connectedAt))
+		log.Printf("🔄 Reconnecting to %s", s.title)
+		Wait(ctx, backoff, s.health)
+	}
+}
+
+// read hands frames to a new session until the connection fails, the
+// session ends it, it reaches its age limit or ctx is cancelled.
+func (s *Stream) read(ctx context.Context, conn *websocket.Conn) error {
+	var rotate <-chan time.Time
+	if s.maxAge > 0 {
+		timer := time.NewTimer(s.maxAge)
+		defer timer.Stop()
+		rotate = timer.C
+	}
+
+	// Unblock ReadMessage on shutdown or rotation
+	done := make(chan struct{})
+	defer close(done)
+	rotated := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-rotate:
+			close(rotated)
+		case <-done:
+			return
+		}
+		conn.Close()
+	}()
+
+	// Client pings keep quiet connections provably alive; server pings are
+	// answered with their payload and count as proof of life too
+	stop := s.policy.KeepAlive(conn)
+	defer stop()
+
+	handle := s.protocol.Session()
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-rotated:
+				return errAgeLimit
+			default:
+			}
+			return err
+		}
+
+		receivedAt := time.Now()
+		s.policy.Touch(conn)
+		s.health.Message(receivedAt)
+		if s.recorder != nil {
+			s.recorder.Record(s.venue, receivedAt, frame)
+		}
+		if err := handle(ctx, frame, receivedAt); err != nil {
+			return err
+		}
+	}
+}
+
+// detach closes conn and stops Subscribe from writing to it.
+func (s *Stream) detach(conn *websocket.Conn) {
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+	conn.Close()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
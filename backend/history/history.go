@@ -0,0 +1,326 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/journal"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Tier is one resolution of downsampled history.
+type Tier struct {
+	Interval time.Duration
+	Bars     int // retention, in bars
+}
+
+// DefaultTiers keep a week of 1-minute bars, three months of 15-minute bars
+// and two years of daily bars on top of the raw ticks in the ring buffer.
+func DefaultTiers() []Tier {
+	return []Tier{
+		{Interval: time.Minute, Bars: 7 * 24 * 60},
+		{Interval: 15 * time.Minute, Bars: 90 * 24 * 4},
+		{Interval: 24 * time.Hour, Bars: 2 * 365},
+	}
+}
+
+// Resolution name used for raw ticks in query results.
+const RawResolution = "tick"
+
+// Result is the answer to a history query. Raw ticks are returned as flat
+// bars (open = high = low = close) so every resolution has the same shape.
+type Result struct {
+	Resolution string     `json:"resolution"`
+	Bars       []bars.Bar `json:"bars"`
+	Truncated  bool       `json:"truncated"` // older points were dropped to fit the budget
+}
+
+// Store keeps one symbol's history: the raw tick buffer plus OHLCV rings of
+// increasing interval and retention, all fed from the same tick stream.
+type Store struct {
+	buffer  *ringbuffer.TickBuffer
+	config  []Tier
+	tiers   []*bars.Series // finest first
+	builder *bars.Builder
+}
+
+func NewStore(buffer *ringbuffer.TickBuffer, tiers []Tier) *Store {
+	s := &Store{buffer: buffer, config: tiers}
+	for _, tier := range tiers {
+		s.tiers = append(s.tiers, bars.NewSeries(bars.Spec{Kind: bars.Time, Interval: tier.Interval}, tier.Bars))
+	}
+	s.builder = bars.NewBuilderFromSeries(buffer, s.tiers...)
+	return s
+}
+
+// SetClock sets the clock that closes the tiers' bars; see bars.Builder.
+// It must be called before Run.
+func (s *Store) SetClock(clock bars.Clock) {
+	s.builder.SetClock(clock)
+}
+
+// Run keeps the tiers up to date; see bars.Builder.Run.
+func (s *Store) Run(ctx context.Context) {
+	s.builder.Run(ctx)
+}
+
+// Restore rebuilds the tiers from the ticks of symbol journaled under dir,
+// so history survives a restart as far back as the journal retains ticks.
+// Ticks from the oldest buffered one onwards are left to Run, which starts
+// with the buffer. It must be called before Run and returns how many
+// journaled ticks were used; a missing journal is not an error.
+func (s *Store) Restore(dir, symbol string) (int, error) {
+	asOf := time.Now()
+	if oldest, ok := s.buffer.GetOldestTick(); ok {
+		asOf = oldest.Time
+	}
+	var from time.Time
+	for _, tier := range s.config {
+		if start := asOf.Add(-tier.Interval * time.Duration(tier.Bars)); from.IsZero() || start.Before(from) {
+			from = start
+		}
+	}
+
+	// Aggregate into scratch series, then seed the real ones so that live
+	// ticks stamped before asOf, which the journal already holds, are dropped
+	scratch := make([]*bars.Series, len(s.tiers))
+	for i, tier := range s.tiers {
+		scratch[i] = bars.NewSeries(tier.Spec(), s.config[i].Bars)
+	}
+	n := 0
+	err := journal.Read(dir, symbol, from, asOf, func(t ringbuffer.Tick) error {
+		for _, series := range scratch {
+			series.Add(t)
+		}
+		n++
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	if n == 0 {
+		return 0, err
+	}
+
+	for i, series := range scratch {
+		history := series.ReadLast(series.GetCount())
+		if cur, ok := series.Current(); ok {
+			history = append(history, cur)
+		}
+		s.tiers[i].Seed(history, asOf)
+	}
+	return n, err
+}
+
+// Query returns history for [from, to) in the finest resolution that both
+// reaches back to from and fits in maxPoints. If no resolution reaches back
+// that far (history is still filling up), the finest one that fits the
+// budget is used. If even the coarsest tier exceeds the budget, only its
+// newest maxPoints bars are returned.
+func (s *Store) Query(from, to time.Time, maxPoints int) Result {
+	type candidate struct {
+		name    string
+		covers  bool
+		points  int
+		collect func() []bars.Bar
+	}
+
+	span := to.Sub(from)
+	var candidates []candidate
+
+	// The raw tier is at most one buffer's worth, so collect it up front
+	raw := s.tickBars(from, to)
+	oldestTick, ok := s.buffer.GetOldestTick()
+	candidates = append(candidates, candidate{
+		name:    RawResolution,
+		covers:  ok && !oldestTick.Time.After(from),
+		points:  len(raw),
+		collect: func() []bars.Bar { return raw },
+	})
+
+	for _, tier := range s.tiers {
+		tier := tier
+		oldest, ok := tier.GetOldest()
+		candidates = append(candidates, candidate{
+			name:    tier.Spec().Name(),
+			covers:  ok && !oldest.Start.After(from),
+			points:  int(span/tier.Spec().Interval) + 1,
+			collect: func() []bars.Bar { return tier.ReadRange(from, to) },
+		})
+	}
+
+	chosen := -1
+	for i, c := range candidates {
+		if c.covers && c.points <= maxPoints {
+			chosen = i
+			break
+		}
+	}
+	if chosen < 0 {
+		for i, c := range candidates {
+			if c.points <= maxPoints {
+				chosen = i
+				break
+			}
+		}
+	}
+	if chosen < 0 {
+		chosen = len(candidates) - 1
+	}
+
+	result := Result{
+		Resolution: candidates[chosen].name,
+		Bars:       candidates[chosen].collect(),
+	}
+	if len(result.Bars) > maxPoints {
+		result.Bars = result.Bars[len(result.Bars)-maxPoints:]
+		result.Truncated = true
+	}
+	return result
+}
+
+func (s *Store) tickBars(from, to time.Time) []bars.Bar {
+	var result []bars.Bar
+	for _, t := range s.buffer.ReadSince(from) {
+		if !t.Time.Before(to) {
+			continue
+		}
+		result = append(result, bars.Bar{
+			Start:    t.Time,
+			End:      t.Time,
+			Open:     t.Price,
+			High:     t.Price,
+			Low:      t.Price,
+			Close:    t.Price,
+			Volume:   t.Size,
+			Notional: t.Price * t.Size,
+			Trades:   1,
+		})
+	}
+	return result
+}
+
+// Registry holds one Store per symbol.
+type Registry struct {
+	stores  map[string]*Store
+	tiers   []Tier
+	journal string          // restore from this journal directory if set
+	clock   bars.Clock      // nil keeps the stores' default
+	ctx     context.Context // set by Run
+	stop    map[string]context.CancelFunc
+	mu      sync.RWMutex
+}
+
+func NewRegistry(buffers *ringbuffer.Registry, tiers []Tier) *Registry {
+	r := &Registry{stores: make(map[string]*Store), stop: make(map[string]context.CancelFunc), tiers: tiers}
+	for _, symbol := range buffers.Symbols() {
+		buffer, _ := buffers.Get(symbol)
+		r.Add(symbol, buffer)
+	}
+	return r
+}
+
+// Add creates the store for a symbol added at runtime, starting it if the
+// registry is already running.
+func (r *Registry) Add(symbol string, buffer *ringbuffer.TickBuffer) *Store {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.stores[symbol]; ok {
+		return s
+	}
+	s := NewStore(buffer, r.tiers)
+	if r.clock != nil {
+		s.SetClock(r.clock)
+	}
+	r.stores[symbol] = s
+	if r.ctx != nil {
+		go r.run(r.start(symbol), symbol, s)
+	}
+	return s
+}
+
+// Remove stops the store for symbol and forgets it, reporting whether it
+// existed. Its journaled ticks remain, so adding the symbol again rebuilds
+// the history.
+func (r *Registry) Remove(symbol string) bool {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.stores[symbol]; !ok {
+		return false
+	}
+	if stop, ok := r.stop[symbol]; ok {
+		stop()
+		delete(r.stop, symbol)
+	}
+	delete(r.stores, symbol)
+	return true
+}
+
+// start derives the context a symbol's store runs under, so that Remove
+// can stop it alone. r.mu must be held.
+func (r *Registry) start(symbol string) context.Context {
+	ctx, stop := context.WithCancel(r.ctx)
+	r.stop[symbol] = stop
+	return ctx
+}
+
+// SetJournal makes every store rebuild its tiers from the journal under
+// dir before it starts, including stores added at runtime. It must be
+// called before Run.
+func (r *Registry) SetJournal(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = dir
+}
+
+// SetClock sets the clock of every store, including those added later. It
+// must be called before Run.
+func (r *Registry) SetClock(clock bars.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = clock
+	for _, s := range r.stores {
+		s.SetClock(clock)
+	}
+}
+
+func (r *Registry) Get(symbol string) (*Store, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.stores[ringbuffer.NormalizeSymbol(symbol)]
+	return s, ok
+}
+
+// Run starts every store in its own goroutine.
+func (r *Registry) Run(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ctx = ctx
+	for symbol, s := range r.stores {
+		go r.run(r.start(symbol), symbol, s)
+	}
+}
+
+func (r *Registry) run(ctx context.Context, symbol string, s *Store) {
+	if r.journal != "" {
+		n, err := s.Restore(r.journal, symbol)
+		if err != nil {
+			log.Printf("⚠️  History for %s not fully restored: %v", symbol, err)
+		}
+		if n > 0 {
+			log.Printf("⏪ Rebuilt %s history from %d journaled ticks", symbol, n)
+		}
+	}
+	s.Run(ctx)
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/journal"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+func TestRestoreFromJournal(t *testing.T) {
+	dir := t.TempDir()
+	// Midday yesterday, so the ticks share a daily bar
+	base := time.Now().Truncate(24 * time.Hour).Add(-12 * time.Hour)
+	tick := func(seconds int, price float64) ringbuffer.Tick {
+		at := base.Add(time.Duration(seconds) * time.Second)
+		return ringbuffer.Tick{Symbol: "btcusdt", Time: at, ReceivedAt: at, Price: price, Size: 1}
+	}
+
+	// The previous run journaled four ticks
+	j, err := journal.Open(dir, journal.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions())
+	j.Attach("btcusdt", previous)
+	for _, tk := range []ringbuffer.Tick{tick(10, 100), tick(70, 102), tick(75, 101), tick(130, 105)} {
+		previous.WriteTick(tk)
+	}
+	j.Close()
+
+	// The snapshot brought back only the last one
+	buffer := ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions())
+	buffer.WriteTick(tick(130, 105))
+	s := NewStore(buffer, DefaultTiers())
+
+	n, err := s.Restore(dir, "btcusdt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("restored %d ticks, want the 3 before the buffer", n)
+	}
+
+	minute := s.tiers[0]
+	got := minute.ReadLast(10)
+	if len(got) != 2 {
+		t.Fatalf("%d minute bars, want 2: %+v", len(got), got)
+	}
+	if got[0].Close != 100 || got[1].Open != 102 || got[1].Close != 101 || got[1].Volume != 2 {
+		t.Errorf("minute bars %+v", got)
+	}
+	if daily, ok := s.tiers[2].Current(); !ok || daily.Volume != 3 {
+		t.Errorf("forming daily bar %+v %v, want volume 3 kept open", daily, ok)
+	}
+
+	// Run then adds what is buffered; a journaled tick seen again is dropped
+	minute.Add(tick(75, 101))
+	minute.Add(tick(130, 105))
+	if cur, ok := minute.Current(); !ok || cur.Open != 105 || cur.Volume != 1 {
+		t.Errorf("forming minute bar %+v %v, want the buffered tick only", cur, ok)
+	}
+	if got := minute.ReadLast(10); len(got) != 2 || got[1].Volume != 2 {
+		t.Errorf("minute bars after live ticks %+v", got)
+	}
+}
+
+func TestRestoreWithoutJournal(t *testing.T) {
+	s := NewStore(ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions()), DefaultTiers())
+	n, err := s.Restore(t.TempDir(), "btcusdt")
+	if n != 0 || err != nil {
+		t.Errorf("Restore = %d, %v; want 0, nil", n, err)
+	}
+	if _, ok := s.tiers[0].GetOldest(); ok {
+		t.Error("tier has bars without a journal")
+	}
+}
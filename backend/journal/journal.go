@@ -0,0 +1,139 @@
+package journal
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Journal appends every tick written to the attached buffers to per-symbol
+// segment files under dir. Each symbol has its own writer goroutine that
+// follows the buffer with a Cursor, so RingBuffer writes never wait on disk
+// I/O. A writer that falls more than a buffer's worth behind loses the
+// overwritten ticks; Lapped counts them.
+type Journal struct {
+	dir    string
+	opts   Options
+	done   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	detach map[string]writer // by symbol
+	lapped atomic.Uint64
+	errors atomic.Uint64
+}
+
+func Open(dir string, opts Options) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Journal{
+		dir:    dir,
+		opts:   opts,
+		done:   make(chan struct{}),
+		detach: make(map[string]writer),
+	}, nil
+}
+
+// writer is how Detach stops one symbol's writer and waits for it.
+type writer struct {
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Attach starts journaling ticks written to buffer from now on. Ticks that
+// are already buffered (for example restored from a snapshot) are skipped.
+func (j *Journal) Attach(symbol string, buffer *ringbuffer.TickBuffer) {
+	sub := buffer.Subscribe(1)
+	cursor := buffer.NewCursor()
+	cursor.SeekLatest()
+
+	w := newSegmentWriter(filepath.Join(j.dir, symbol), j.opts)
+	ctl := writer{stop: make(chan struct{}), stopped: make(chan struct{})}
+	j.mu.Lock()
+	j.detach[symbol] = ctl
+	j.mu.Unlock()
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer close(ctl.stopped)
+		defer sub.Close()
+
+		ticks := make([]ringbuffer.Tick, 256)
+		stopping := false
+		for {
+			for {
+				n, missed := cursor.Read(ticks)
+				if missed > 0 {
+					j.lapped.Add(missed)
+					log.Printf("⚠️  Journal lapped on %s: %d ticks lost", symbol, missed)
+				}
+				if n == 0 {
+					break
+				}
+				for _, t := range ticks[:n] {
+					if err := w.append(t); err != nil {
+						j.errors.Add(1)
+						log.Printf("⚠️  Journal write failed for %s: %v", symbol, err)
+					}
+				}
+			}
+			if err := w.flush(); err != nil {
+				j.errors.Add(1)
+				log.Printf("⚠️  Journal flush failed for %s: %v", symbol, err)
+			}
+
+			if stopping {
+				w.close()
+				return
+			}
+			select {
+			case <-sub.C:
+			case <-j.done:
+				// One more pass to pick up anything written meanwhile
+				stopping = true
+			case <-ctl.stop:
+				stopping = true
+			}
+		}
+	}()
+}
+
+// Detach stops journaling symbol once what is buffered has been written.
+// The segments stay on disk for Read.
+func (j *Journal) Detach(symbol string) {
+	j.mu.Lock()
+	ctl, ok := j.detach[symbol]
+	delete(j.detach, symbol)
+	j.mu.Unlock()
+	if !ok {
+		return
+	}
+	close(ctl.stop)
+	<-ctl.stopped
+}
+
+// Close stops all writers after they have flushed what is buffered.
+func (j *Journal) Close() {
+	close(j.done)
+	j.wg.Wait()
+}
+
+// Lapped returns the number of ticks that were overwritten in a buffer
+// before the journal could write them.
+func (j *Journal) Lapped() uint64 {
+	return j.lapped.Load()
+}
+
+// Errors returns the number of failed writes and flushes.
+func (j *Journal) Errors() uint64 {
+	return j.errors.Load()
+}
+
+func (j *Journal) Dir() string {
+	return j.dir
+}
@@ -0,0 +1,281 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var epoch = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// tickAt is a btcusdt tick the given number of seconds after epoch.
+func tickAt(seconds int) ringbuffer.Tick {
+	at := epoch.Add(time.Duration(seconds) * time.Second)
+	return ringbuffer.Tick{Symbol: "btcusdt", Time: at, ReceivedAt: at.Add(time.Millisecond), Price: 64000 + float64(seconds), Size: 0.1, Side: "buy", Sequence: int64(seconds)}
+}
+
+// writeSegments journals ticks every step seconds over span seconds
+// straight through a segment writer and returns the journal directory.
+func writeSegments(t *testing.T, opts Options, span, step int) string {
+	t.Helper()
+	dir := t.TempDir()
+	w := newSegmentWriter(filepath.Join(dir, "btcusdt"), opts)
+	for s := 0; s < span; s += step {
+		if err := w.append(tickAt(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// readSeconds returns the seconds after epoch of the ticks Read yields.
+func readSeconds(t *testing.T, dir string, from, to time.Time) ([]int, error) {
+	t.Helper()
+	var got []int
+	err := Read(dir, "btcusdt", from, to, func(tick ringbuffer.Tick) error {
+		got = append(got, int(tick.Time.Sub(epoch)/time.Second))
+		return nil
+	})
+	return got, err
+}
+
+func segments(t *testing.T, dir string) []segment {
+	t.Helper()
+	segs, err := listSegments(filepath.Join(dir, "btcusdt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return segs
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions())
+	buffer.WriteTick(tickAt(0)) // buffered before Attach, so skipped
+	j.Attach("btcusdt", buffer)
+	for s := 1; s <= 50; s++ {
+		buffer.WriteTick(tickAt(s))
+	}
+	j.Close()
+	if j.Lapped() != 0 || j.Errors() != 0 {
+		t.Errorf("lapped %d, errors %d", j.Lapped(), j.Errors())
+	}
+
+	var got []ringbuffer.Tick
+	err = Read(dir, "BTCUSDT", time.Time{}, time.Time{}, func(tick ringbuffer.Tick) error {
+		got = append(got, tick)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 50 {
+		t.Fatalf("read %d ticks, want 50", len(got))
+	}
+	for i, g := range got {
+		w := tickAt(i + 1)
+		if g.Symbol != w.Symbol || !g.Time.Equal(w.Time) || !g.ReceivedAt.Equal(w.ReceivedAt) ||
+			g.Price != w.Price || g.Size != w.Size || g.Side != w.Side || g.Sequence != w.Sequence {
+			t.Fatalf("tick %d: %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	buffer := ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions())
+	j.Attach("btcusdt", buffer)
+	for s := 1; s <= 10; s++ {
+		buffer.WriteTick(tickAt(s))
+	}
+
+	// Detach flushes what was written before it and nothing after
+	j.Detach("btcusdt")
+	j.Detach("btcusdt")
+	for s := 11; s <= 20; s++ {
+		buffer.WriteTick(tickAt(s))
+	}
+	if n := buffer.GetSubscribers(); n != 0 {
+		t.Errorf("%d subscribers after Detach", n)
+	}
+	got, err := readSeconds(t, dir, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 10 || got[0] != 1 || got[9] != 10 {
+		t.Errorf("journaled %v, want 1 to 10", got)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []int // seconds at which segments start
+	}{
+		{"by duration", Options{SegmentSize: 1 << 20, SegmentDuration: time.Minute}, []int{0, 60, 120, 180, 240}},
+		// Each record is 8 + 41 bytes; the third fills a 147-byte segment
+		{"by size", Options{SegmentSize: 3 * (recordHeaderSize + ringbuffer.TickBinarySize), SegmentDuration: time.Hour}, []int{0, 90, 180, 270}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSegments(t, tt.opts, 300, 30)
+			segs := segments(t, dir)
+			if len(segs) != len(tt.want) {
+				t.Fatalf("%d segments, want %d", len(segs), len(tt.want))
+			}
+			for i, seg := range segs {
+				if want := epoch.Add(time.Duration(tt.want[i]) * time.Second); !seg.start.Equal(want) {
+					t.Errorf("segment %d starts %s, want %s", i, seg.start, want)
+				}
+			}
+			got, err := readSeconds(t, dir, time.Time{}, time.Time{})
+			if err != nil || len(got) != 10 {
+				t.Errorf("read %v, %v; want all 10 ticks", got, err)
+			}
+		})
+	}
+}
+
+func TestReadRangeSpansSegments(t *testing.T) {
+	dir := writeSegments(t, Options{SegmentSize: 1 << 20, SegmentDuration: time.Minute}, 300, 30)
+
+	tests := []struct {
+		name     string
+		from, to int // seconds; to < 0 means no upper bound
+		want     []int
+	}{
+		{"inside one segment", 60, 90, []int{60}},
+		{"across segments", 75, 195, []int{90, 120, 150, 180}},
+		{"open ended", 200, -1, []int{210, 240, 270}},
+		{"before the journal", -100, 30, []int{0}},
+		{"after the journal", 400, -1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var to time.Time
+			if tt.to >= 0 {
+				to = epoch.Add(time.Duration(tt.to) * time.Second)
+			}
+			got, err := readSeconds(t, dir, epoch.Add(time.Duration(tt.from)*time.Second), to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("read %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("read %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetention(t *testing.T) {
+	opts := Options{SegmentSize: 1 << 20, SegmentDuration: time.Minute}
+	dir := writeSegments(t, opts, 300, 30)
+
+	// At 4m30s with 2m retention, segments holding only ticks before 2m30s
+	// go; the one starting at 2m still holds ticks after the cutoff
+	opts.Retention = 2 * time.Minute
+	w := newSegmentWriter(filepath.Join(dir, "btcusdt"), opts)
+	w.enforceRetention(epoch.Add(270 * time.Second))
+
+	segs := segments(t, dir)
+	if len(segs) != 3 || !segs[0].start.Equal(epoch.Add(2*time.Minute)) {
+		t.Fatalf("%d segments left starting %v, want 3 from 2m", len(segs), segs)
+	}
+	got, err := readSeconds(t, dir, time.Time{}, time.Time{})
+	if err != nil || len(got) != 6 || got[0] != 120 {
+		t.Errorf("read %v, %v; want the 6 ticks from 2m", got, err)
+	}
+
+	// The active segment is never removed, however old
+	w.enforceRetention(epoch.Add(24 * time.Hour))
+	if segs := segments(t, dir); len(segs) != 1 {
+		t.Errorf("%d segments left, want the last one", len(segs))
+	}
+}
+
+func TestTruncatedRecordRecovered(t *testing.T) {
+	for _, cut := range []int{1, 20, recordHeaderSize + ringbuffer.TickBinarySize - 3} {
+		dir := writeSegments(t, DefaultOptions(), 10, 1)
+		path := segments(t, dir)[0].path
+		info, _ := os.Stat(path)
+		if err := os.Truncate(path, info.Size()-int64(cut)); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := readSeconds(t, dir, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("cut %d bytes: %v", cut, err)
+		}
+		if len(got) != 9 || got[8] != 8 {
+			t.Errorf("cut %d bytes: read %v, want the first 9 ticks", cut, got)
+		}
+	}
+}
+
+func TestCorruptRecordRejected(t *testing.T) {
+	dir := writeSegments(t, DefaultOptions(), 10, 1)
+	path := segments(t, dir)[0].path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a bit in the third record's payload
+	data[2*(recordHeaderSize+ringbuffer.TickBinarySize)+recordHeaderSize+5] ^= 1
+	os.WriteFile(path, data, 0o644)
+
+	got, err := readSeconds(t, dir, time.Time{}, time.Time{})
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Read error %v, want ErrCorrupt", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("read %v before the corrupt record, want 2 ticks", got)
+	}
+
+	// A damaged length prefix is caught before it is trusted
+	data[0], data[1], data[2], data[3] = 0xff, 0xff, 0xff, 0x7f
+	os.WriteFile(path, data, 0o644)
+	if _, err := readSeconds(t, dir, time.Time{}, time.Time{}); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Read error %v, want ErrCorrupt", err)
+	}
+}
+
+func TestReadStopsOnCallbackError(t *testing.T) {
+	dir := writeSegments(t, Options{SegmentSize: 1 << 20, SegmentDuration: time.Minute}, 300, 30)
+	stop := errors.New("enough")
+	n := 0
+	err := Read(dir, "btcusdt", time.Time{}, time.Time{}, func(ringbuffer.Tick) error {
+		n++
+		if n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Errorf("Read = %v after %d ticks, want the callback's error after 3", err, n)
+	}
+	if err := Read(dir, "ethusdt", time.Time{}, time.Time{}, func(ringbuffer.Tick) error { return nil }); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read of an unjournaled symbol = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,99 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// ErrCorrupt is returned when a record fails its checksum.
+var ErrCorrupt = errors.New("journal: corrupt record")
+
+// maxRecordSize bounds the length prefix so a damaged header cannot make
+// the reader allocate arbitrarily much.
+const maxRecordSize = 1 << 16
+
+// Read calls fn for every journaled tick of symbol with an exchange time in
+// [from, to), in the order they were written. A zero to means no upper
+// bound. A record cut short at the end of a segment (a crash mid-write) ends
+// that segment silently; a checksum mismatch returns ErrCorrupt. Returning
+// an error from fn stops the iteration and Read returns that error.
+func Read(dir, symbol string, from, to time.Time, fn func(ringbuffer.Tick) error) error {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	segments, err := listSegments(filepath.Join(dir, symbol))
+	if err != nil {
+		return err
+	}
+
+	for i, seg := range segments {
+		// Segments are named by their first tick, so one can only hold
+		// ticks before from if its successor starts after from.
+		if i+1 < len(segments) && !segments[i+1].start.After(from) {
+			continue
+		}
+		if !to.IsZero() && !seg.start.Before(to) {
+			break
+		}
+		if err := readSegment(seg.path, symbol, from, to, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSegment(path, symbol string, from, to time.Time, fn func(ringbuffer.Tick) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, recordHeaderSize)
+	payload := make([]byte, maxRecordSize)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:])
+		sum := binary.LittleEndian.Uint32(header[4:])
+		if length > maxRecordSize {
+			return fmt.Errorf("%w: %s: record length %d", ErrCorrupt, path, length)
+		}
+
+		body := payload[:length]
+		if _, err := io.ReadFull(r, body); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		if crc32.Checksum(body, crcTable) != sum {
+			return fmt.Errorf("%w: %s", ErrCorrupt, path)
+		}
+
+		tick := ringbuffer.Tick{Symbol: symbol}
+		if err := tick.UnmarshalBinary(body); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrCorrupt, path, err)
+		}
+		if tick.Time.Before(from) || (!to.IsZero() && !tick.Time.Before(to)) {
+			continue
+		}
+		if err := fn(tick); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Segment files are named after the exchange time of their first tick in
+// zero-padded unix nanoseconds, so lexical order is chronological:
+//
+//	<dir>/<symbol>/01712345678901234567.seg
+//
+// and hold a sequence of records:
+//
+//	length  uint32  payload length
+//	crc     uint32  CRC-32C of payload
+//	payload         ringbuffer.Tick.AppendBinary encoding
+const segmentExt = ".seg"
+
+const recordHeaderSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Options struct {
+	SegmentSize     int64         // rotate once a segment reaches this many bytes
+	SegmentDuration time.Duration // rotate once a segment spans this long
+	Retention       time.Duration // delete segments older than this; 0 keeps all
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SegmentSize:     64 << 20,
+		SegmentDuration: time.Hour,
+		Retention:       7 * 24 * time.Hour,
+	}
+}
+
+// segmentWriter owns the active segment of one symbol.
+type segmentWriter struct {
+	dir   string
+	opts  Options
+	f     *os.File
+	w     *bufio.Writer
+	size  int64
+	start time.Time
+	buf   []byte
+}
+
+func newSegmentWriter(dir string, opts Options) *segmentWriter {
+	return &segmentWriter{
+		dir:  dir,
+		opts: opts,
+		buf:  make([]byte, recordHeaderSize, recordHeaderSize+ringbuffer.TickBinarySize),
+	}
+}
+
+func (s *segmentWriter) append(t ringbuffer.Tick) error {
+	if s.f == nil || s.size >= s.opts.SegmentSize || t.Time.Sub(s.start) >= s.opts.SegmentDuration {
+		if err := s.rotate(t.Time); err != nil {
+			return err
+		}
+	}
+
+	record := t.AppendBinary(s.buf[:recordHeaderSize])
+	payload := record[recordHeaderSize:]
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, crcTable))
+
+	n, err := s.w.Write(record)
+	s.size += int64(n)
+	return err
+}
+
+func (s *segmentWriter) rotate(start time.Time) error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(segmentPath(s.dir, start), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.f = f
+	s.w = bufio.NewWriter(f)
+	s.size = info.Size()
+	s.start = start
+
+	s.enforceRetention(time.Now())
+	return nil
+}
+
+func (s *segmentWriter) flush() error {
+	if s.w == nil {
+		return nil
+	}
+	return s.w.Flush()
+}
+
+func (s *segmentWriter) close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f, s.w = nil, nil
+	return err
+}
+
+// enforceRetention deletes closed segments whose successor started before
+// the retention cutoff, i.e. segments holding only ticks older than it.
+func (s *segmentWriter) enforceRetention(now time.Time) {
+	if s.opts.Retention <= 0 {
+		return
+	}
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-s.opts.Retention)
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i+1].start.Before(cutoff) {
+			os.Remove(segments[i].path)
+		}
+	}
+}
+
+type segment struct {
+	path  string
+	start time.Time
+}
+
+func segmentPath(dir string, start time.Time) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", start.UnixNano(), segmentExt))
+}
+
+// listSegments returns a symbol directory's segments, oldest first.
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(dir, name), start: time.Unix(0, ns)})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+	return segments, nil
+}
@@ -0,0 +1,169 @@
+package kraken
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+const (
+	Name       = "kraken"
+	DefaultURL = "wss://ws.kraken.com/v2"
+)
+
+// DefaultChannels are subscribed for every pair when the config names none.
+// As with Coinbase, trades come from the trade channel and ticker only
+// supplies quotes and 24h statistics.
+var DefaultChannels = []string{"trade", "ticker"}
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed is the Kraken WebSocket v2 adapter. Every pair shares one
+// connection; messages are routed to symbols by their pair name.
+type Feed struct {
+	*feed.Stream
+	channels []string
+	trades   bool  // trades come from the trade channel
+	nextID   int64 // request id; guarded by the Stream's write lock
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	url := cfg.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	channels := append([]string(nil), cfg.Streams...)
+	if len(channels) == 0 {
+		channels = append(channels, DefaultChannels...)
+	}
+	f := &Feed{
+		channels: channels,
+		trades:   contains(channels, "trade"),
+	}
+	f.Stream = feed.NewStream(feed.StreamConfig{
+		Venue:    Name,
+		URL:      url,
+		Policy:   cfg.Policy,
+		Recorder: cfg.Recorder,
+	}, f)
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Send implements feed.Protocol with one request per channel, since v2
+// requests name a single channel.
+func (f *Feed) Send(conn *websocket.Conn, subscribe bool, symbols []string) error {
+	method := "subscribe"
+	if !subscribe {
+		method = "unsubscribe"
+	}
+	pairs := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		pair, _ := feed.VenueSymbol(Name, symbol)
+		pairs = append(pairs, pair)
+	}
+	for _, channel := range f.channels {
+		params := map[string]interface{}{
+			"channel": channel,
+			"symbol":  pairs,
+		}
+		// The trade snapshot replays recent trades that may already be
+		// buffered from before a reconnect
+		if channel == "trade" && subscribe {
+			params["snapshot"] = false
+		}
+		f.nextID++
+		err := conn.WriteJSON(map[string]interface{}{
+			"method": method,
+			"params": params,
+			"req_id": f.nextID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Session implements feed.Protocol. Kraken also sends a heartbeat every
+// second, which keeps the connection's read deadline fresh.
+func (f *Feed) Session() feed.Handler {
+	// Last trade id per pair on this connection; trade ids are contiguous
+	// per pair, so jumps are missed trades
+	lastTrade := make(map[string]int64)
+
+	return func(ctx context.Context, message []byte, receivedAt time.Time) error {
+		var msg Message
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return nil
+		}
+		if msg.Success != nil && !*msg.Success {
+			log.Printf("⚠️  Kraken %s failed: %s", msg.Method, msg.Error)
+			return nil
+		}
+
+		switch msg.Channel {
+		case "trade":
+			var trades []trade
+			if err := json.Unmarshal(msg.Data, &trades); err != nil {
+				return nil
+			}
+			for _, t := range trades {
+				symbol, ok := feed.Normalize(Name, t.Symbol)
+				if !ok || !f.Active(symbol) {
+					continue
+				}
+				last := lastTrade[symbol]
+				if last != 0 && t.TradeID <= last {
+					f.Tracker().OutOfOrder()
+					continue
+				}
+				if last != 0 && t.TradeID > last+1 {
+					missed := t.TradeID - last - 1
+					log.Printf("⚠️  Kraken %s: %d trades missing before trade %d", symbol, missed, t.TradeID)
+					f.Tracker().TradeGap(missed)
+				}
+				lastTrade[symbol] = t.TradeID
+				f.Emit(ctx, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: t.tick(symbol, receivedAt), TradeID: t.TradeID})
+			}
+		case "ticker":
+			var tickers []ticker
+			if err := json.Unmarshal(msg.Data, &tickers); err != nil {
+				return nil
+			}
+			for _, t := range tickers {
+				symbol, ok := feed.Normalize(Name, t.Symbol)
+				if !ok || !f.Active(symbol) {
+					continue
+				}
+				if quote, ok := t.quote(receivedAt); ok {
+					f.Emit(ctx, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: quote, Day: t.day()})
+				}
+				if f.trades {
+					continue
+				}
+				if tick, ok := t.tick(symbol, receivedAt); ok {
+					f.Emit(ctx, feed.Event{Kind: feed.EventTick, Venue: Name, Symbol: symbol, Tick: tick})
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package kraken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+const (
+	tradeFrame  = `{"channel":"trade","type":"update","data":[{"symbol":"BTC/USD","side":"sell","price":64000.5,"qty":0.25,"ord_type":"market","trade_id":41,"timestamp":"2024-05-01T14:00:00.123456Z"},{"symbol":"ETH/USD","side":"buy","price":3400,"qty":1,"trade_id":7,"timestamp":"2024-05-01T14:00:00.2Z"},{"symbol":"DOGE/USD","side":"buy","price":0.1,"qty":5,"trade_id":3,"timestamp":"2024-05-01T14:00:00.2Z"}]}`
+	tickerFrame = `{"channel":"ticker","type":"update","data":[{"symbol":"BTC/USD","bid":64000,"bid_qty":1.5,"ask":64001,"ask_qty":2,"last":64000.5,"volume":1200,"vwap":63900,"low":63000,"high":65000,"change":500,"change_pct":0.79}]}`
+)
+
+func ack(channel string) string {
+	return `{"method":"subscribe","result":{"channel":"` + channel + `","snapshot":true,"symbol":"BTC/USD"},"success":true,"time_in":"2024-05-01T13:59:59.9Z","time_out":"2024-05-01T13:59:59.91Z","req_id":1}`
+}
+
+func TestParse(t *testing.T) {
+	receivedAt := time.Date(2024, 5, 1, 14, 0, 1, 0, time.UTC)
+	var p Parser
+	events, err := p.Parse([]byte(tradeFrame), receivedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// DOGE/USD is not listed
+	if len(events) != 2 {
+		t.Fatalf("%d events, want 2: %+v", len(events), events)
+	}
+	btc := events[0]
+	if btc.Kind != feed.EventTrade || btc.Symbol != "btcusdt" || btc.TradeID != 41 {
+		t.Fatalf("trade %+v", btc)
+	}
+	tick := btc.Tick
+	if tick.Price != 64000.5 || tick.Size != 0.25 || tick.Side != "sell" || tick.Sequence != 41 || !tick.ReceivedAt.Equal(receivedAt) {
+		t.Errorf("tick %+v", tick)
+	}
+	if want := time.Date(2024, 5, 1, 14, 0, 0, 123456000, time.UTC); !tick.Time.Equal(want) {
+		t.Errorf("time %s, want %s", tick.Time, want)
+	}
+	if events[1].Symbol != "ethusdt" || events[1].Tick.Side != "buy" {
+		t.Errorf("second trade %+v", events[1])
+	}
+
+	events, err = p.Parse([]byte(tickerFrame), receivedAt)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("ticker: %+v %v", events, err)
+	}
+	q := events[0]
+	if q.Kind != feed.EventQuote || q.Quote.Bid != 64000 || q.Quote.BidSize != 1.5 || q.Quote.Ask != 64001 || q.Quote.AskSize != 2 {
+		t.Errorf("quote %+v", q)
+	}
+	// Tickers carry no timestamp, so they are stamped on receipt
+	if !q.Quote.Time.Equal(receivedAt) {
+		t.Errorf("quote time %s, want %s", q.Quote.Time, receivedAt)
+	}
+	if q.Day != (feed.DayStats{Open: 63500.5, High: 65000, Low: 63000, Volume: 1200}) {
+		t.Errorf("day %+v", q.Day)
+	}
+
+	for _, frame := range []string{
+		`{"channel":"heartbeat"}`,
+		`{"channel":"status","type":"update","data":[{"system":"online"}]}`,
+		`{"method":"subscribe","success":false,"error":"Currency pair not supported"}`,
+		`{"channel":"ticker","type":"update","data":[{"symbol":"BTC/USD","last":1}]}`,
+	} {
+		if events, err := p.Parse([]byte(frame), receivedAt); err != nil || len(events) != 0 {
+			t.Errorf("%s: %+v %v", frame, events, err)
+		}
+	}
+	for _, frame := range []string{`{"channel":`, `{"channel":"trade","data":{"symbol":"BTC/USD"}}`} {
+		if _, err := p.Parse([]byte(frame), receivedAt); err == nil {
+			t.Errorf("%s parsed", frame)
+		}
+	}
+}
+
+func TestParserTickerFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []string
+		want   []feed.EventKind
+	}{
+		{"ticker only", []string{ack("ticker"), tickerFrame}, []feed.EventKind{feed.EventQuote, feed.EventTick}},
+		{"with trades", []string{ack("trade"), ack("ticker"), tickerFrame, tradeFrame}, []feed.EventKind{feed.EventQuote, feed.EventTrade, feed.EventTrade}},
+		{"no acknowledgements", []string{tickerFrame, tradeFrame, tickerFrame}, []feed.EventKind{feed.EventQuote, feed.EventTick, feed.EventTrade, feed.EventTrade, feed.EventQuote}},
+		{"acknowledgements win", []string{tradeFrame, ack("ticker"), tickerFrame}, []feed.EventKind{feed.EventTrade, feed.EventTrade, feed.EventQuote, feed.EventTick}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			var got []feed.Event
+			for _, frame := range tt.frames {
+				events, err := p.Parse([]byte(frame), time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, events...)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d events, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, ev := range got {
+				if ev.Kind != tt.want[i] {
+					t.Errorf("event %d is %v, want %v", i, ev.Kind, tt.want[i])
+				}
+				if ev.Kind == feed.EventTick && (ev.Symbol != "btcusdt" || ev.Tick.Price != 64000.5 || ev.Tick.Size != 0) {
+					t.Errorf("ticker tick %+v", ev)
+				}
+			}
+		})
+	}
+}
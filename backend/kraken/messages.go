@@ -0,0 +1,178 @@
+package kraken
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Message is a v2 channel message or method response. Unlike Coinbase,
+// Kraken sends numbers as JSON numbers.
+type Message struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"` // "snapshot" or "update"
+	Data    json.RawMessage `json:"data"`
+
+	// method responses
+	Method  string          `json:"method"`
+	Result  json.RawMessage `json:"result"`
+	Success *bool           `json:"success"`
+	Error   string          `json:"error"`
+}
+
+// subscription is the result of a successful subscribe request.
+type subscription struct {
+	Channel string `json:"channel"`
+	Symbol  string `json:"symbol"`
+}
+
+type trade struct {
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"` // taker side
+	Price     float64 `json:"price"`
+	Qty       float64 `json:"qty"`
+	TradeID   int64   `json:"trade_id"`
+	Timestamp string  `json:"timestamp"`
+}
+
+type ticker struct {
+	Symbol    string  `json:"symbol"`
+	Bid       float64 `json:"bid"`
+	BidQty    float64 `json:"bid_qty"`
+	Ask       float64 `json:"ask"`
+	AskQty    float64 `json:"ask_qty"`
+	Last      float64 `json:"last"`
+	Volume    float64 `json:"volume"`
+	Low       float64 `json:"low"`
+	High      float64 `json:"high"`
+	Change    float64 `json:"change"` // last minus the price 24h ago
+	Timestamp string  `json:"timestamp"`
+}
+
+// Parser converts recorded Kraken frames into events. A frame batches
+// trades or tickers of several pairs, so it may yield more than one: trades
+// become trade events and tickers quotes. Like the live feed, tickers also
+// yield ticks when the trade channel was not subscribed, which the
+// capture's subscribe acknowledgements tell; without them, the first trade
+// frame does. The zero value is ready to use.
+type Parser struct {
+	trades bool
+	known  bool // an acknowledgement or trade settled trades
+	acked  bool // acknowledgements, unlike a trade, settle it for good
+}
+
+// Parse converts one frame. Frames that carry no market data and pairs
+// that are not listed yield no events.
+func (p *Parser) Parse(frame []byte, receivedAt time.Time) ([]feed.Event, error) {
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Method == "subscribe" && msg.Success != nil && *msg.Success {
+		// One acknowledgement per channel and pair
+		var sub subscription
+		if json.Unmarshal(msg.Result, &sub) == nil {
+			if !p.acked {
+				p.trades, p.acked = false, true
+			}
+			if sub.Channel == "trade" {
+				p.trades = true
+			}
+			p.known = true
+		}
+		return nil, nil
+	}
+	var events []feed.Event
+	switch msg.Channel {
+	case "trade":
+		if !p.known {
+			p.trades, p.known = true, true
+		}
+		var trades []trade
+		if err := json.Unmarshal(msg.Data, &trades); err != nil {
+			return nil, err
+		}
+		for _, t := range trades {
+			if symbol, ok := feed.Normalize(Name, t.Symbol); ok {
+				events = append(events, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: t.tick(symbol, receivedAt), TradeID: t.TradeID})
+			}
+		}
+	case "ticker":
+		var tickers []ticker
+		if err := json.Unmarshal(msg.Data, &tickers); err != nil {
+			return nil, err
+		}
+		for _, t := range tickers {
+			symbol, ok := feed.Normalize(Name, t.Symbol)
+			if !ok {
+				continue
+			}
+			if quote, ok := t.quote(receivedAt); ok {
+				events = append(events, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: quote, Day: t.day()})
+			}
+			if p.trades {
+				continue
+			}
+			if tick, ok := t.tick(symbol, receivedAt); ok {
+				events = append(events, feed.Event{Kind: feed.EventTick, Venue: Name, Symbol: symbol, Tick: tick})
+			}
+		}
+	}
+	return events, nil
+}
+
+func parseTime(s string, receivedAt time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	return receivedAt
+}
+
+func (t trade) tick(symbol string, receivedAt time.Time) ringbuffer.Tick {
+	return ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       parseTime(t.Timestamp, receivedAt),
+		ReceivedAt: receivedAt,
+		Price:      t.Price,
+		Size:       t.Qty,
+		Side:       t.Side,
+		Sequence:   t.TradeID,
+	}
+}
+
+// tick converts a ticker update. The ticker carries no trade size.
+func (t ticker) tick(symbol string, receivedAt time.Time) (ringbuffer.Tick, bool) {
+	if t.Last == 0 {
+		return ringbuffer.Tick{}, false
+	}
+	return ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       parseTime(t.Timestamp, receivedAt),
+		ReceivedAt: receivedAt,
+		Price:      t.Last,
+	}, true
+}
+
+func (t ticker) quote(receivedAt time.Time) (feed.Quote, bool) {
+	if t.Bid == 0 || t.Ask == 0 {
+		return feed.Quote{}, false
+	}
+	return feed.Quote{
+		Time:    parseTime(t.Timestamp, receivedAt),
+		Bid:     t.Bid,
+		BidSize: t.BidQty,
+		Ask:     t.Ask,
+		AskSize: t.AskQty,
+	}, true
+}
+
+func (t ticker) day() feed.DayStats {
+	return feed.DayStats{
+		Open:   t.Last - t.Change,
+		High:   t.High,
+		Low:    t.Low,
+		Volume: t.Volume,
+	}
+}
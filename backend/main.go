@@ -1,49 +1,276 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
 	"github.com/stahir80td/quantum-trader/api"
-	"github.com/stahir80td/quantum-trader/binance"
+	"github.com/stahir80td/quantum-trader/backfill"
+	"github.com/stahir80td/quantum-trader/bars"
+	_ "github.com/stahir80td/quantum-trader/binance"
+	_ "github.com/stahir80td/quantum-trader/bitstamp"
+	"github.com/stahir80td/quantum-trader/capture"
+	_ "github.com/stahir80td/quantum-trader/coinbase"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/history"
+	"github.com/stahir80td/quantum-trader/journal"
+	_ "github.com/stahir80td/quantum-trader/kraken"
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/replay"
 	"github.com/stahir80td/quantum-trader/ringbuffer"
+	"github.com/stahir80td/quantum-trader/snapshot"
 	"github.com/stahir80td/quantum-trader/strategies"
+	_ "github.com/stahir80td/quantum-trader/synthetic"
 )
 
 var (
-	buffer   *ringbuffer.RingBuffer
-	upgrader = websocket.Upgrader{
+	buffers      *ringbuffer.Registry
+	barSets      *bars.Registry
+	books        *orderbook.Registry
+	stores       *history.Registry
+	signals      = strategies.NewBoard()
+	tickJournal  *journal.Journal
+	filter       *feed.Filter       // nil when FILTER=off
+	consolidator *feed.Consolidator // nil unless consolidating
+	feeds        []feed.MarketDataFeed
+	symbolsMu    sync.Mutex // serialises runtime symbol changes
+	loops        = make(map[string]context.CancelFunc)
+	appCtx       context.Context
+	upgrader     = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
 	}
 )
 
 func main() {
-	// Initialize ring buffer (1000 slots)
-	buffer = ringbuffer.New(1000)
+	// Everything long-running stops when this is cancelled by SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	appCtx = ctx
 
-	// Start Binance WebSocket client
+	// Initialize one ring buffer (1000 slots) per instrument
 	pairs := []string{"btcusdt", "ethusdt", "solusdt", "bnbusdt"}
-	binanceClient := binance.NewClient(buffer)
+	opts := ringbuffer.DefaultOptions()
+	opts.Mode = ringbuffer.ParseMode(os.Getenv("RINGBUFFER_MODE"))
+	opts.GapThreshold = envDuration("GAP_THRESHOLD", opts.GapThreshold)
+	opts.StaleAfter = envDuration("STALE_AFTER", opts.StaleAfter)
+	buffers = ringbuffer.NewRegistry(pairs, opts)
 
-	for _, pair := range pairs {
-		go binanceClient.Connect(pair)
+	venues := os.Getenv("FEEDS")
+	if venues == "" {
+		venues = "coinbase"
 	}
+	// A replayed session must not mix with the live snapshot and journal
+	replaying := strings.Contains(","+venues+",", ","+replay.Name+",")
+	snapshotPath := os.Getenv("SNAPSHOT_PATH")
+	journalDir := os.Getenv("JOURNAL_DIR")
+	captureDir := os.Getenv("CAPTURE_DIR")
+	if replaying {
+		snapshotPath, journalDir, captureDir = "", "", ""
+	}
+
+	// Restore buffers from the last snapshot so strategies have history
+	// immediately after a restart
+	if snapshotPath != "" {
+		maxAge := envDuration("SNAPSHOT_MAX_AGE", 10*time.Minute)
+		if restored, err := snapshot.Load(snapshotPath, maxAge, buffers); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("⚠️  Snapshot not restored: %v", err)
+		} else if err == nil {
+			log.Printf("💾 Restored %d ticks from %s", restored, snapshotPath)
+		}
+		go snapshot.Run(ctx, snapshotPath, envDuration("SNAPSHOT_INTERVAL", 30*time.Second), buffers)
+	}
+
+	// Journal every live tick to disk, off the write path
+	if journalDir != "" {
+		opts := journal.DefaultOptions()
+		opts.Retention = envDuration("JOURNAL_RETENTION", opts.Retention)
+		var err error
+		tickJournal, err = journal.Open(journalDir, opts)
+		if err != nil {
+			log.Fatalf("❌ Journal: %v", err)
+		}
+		for _, symbol := range buffers.Symbols() {
+			buffer, _ := buffers.Get(symbol)
+			tickJournal.Attach(symbol, buffer)
+		}
+		log.Printf("📼 Journaling ticks to %s", journalDir)
+	}
+
+	// Start the configured exchange feeds; the router is the single writer
+	// into every buffer
+	policy := feed.DefaultPolicy()
+	policy.MaxAttempts = envInt("FEED_MAX_ATTEMPTS", policy.MaxAttempts)
+	policy.ReadTimeout = envDuration("FEED_READ_TIMEOUT", policy.ReadTimeout)
+	policy.PingInterval = envDuration("FEED_PING_INTERVAL", policy.PingInterval)
+	policy.MaxDelay = envDuration("FEED_MAX_BACKOFF", policy.MaxDelay)
+	books = orderbook.NewRegistry()
+
+	// Tee every raw frame to hourly files for replay and test fixtures
+	var recorder *capture.Recorder
+	if captureDir != "" {
+		copts := capture.DefaultOptions()
+		copts.Retention = envDuration("CAPTURE_RETENTION", copts.Retention)
+		var err error
+		if recorder, err = capture.Open(captureDir, copts); err != nil {
+			log.Fatalf("❌ Capture: %v", err)
+		}
+		log.Printf("📼 Capturing raw frames to %s", captureDir)
+	}
+
+	// Screen out bad prints, duplicates and late ticks before they reach
+	// the buffers
+	if os.Getenv("FILTER") != "off" {
+		fcfg := feed.DefaultFilterConfig()
+		fcfg.Window = mustEnv("FILTER_WINDOW", fcfg.Window, strconv.Atoi)
+		fcfg.MaxDeviation = mustEnv("FILTER_MAX_DEVIATION", fcfg.MaxDeviation, parseFloat)
+		fcfg.MinMove = mustEnv("FILTER_MIN_MOVE", fcfg.MinMove, parseFloat)
+		fcfg.MaxLateness = mustEnv("FILTER_MAX_LATENESS", fcfg.MaxLateness, time.ParseDuration)
+		var err error
+		if filter, err = feed.NewFilter(fcfg); err != nil {
+			log.Fatalf("❌ Invalid FILTER_* settings: %v", err)
+		}
+	}
+	router := feed.NewRouter(buffers, books, filter)
 
-	// Start strategy analysis loop
-	go runStrategyLoop()
+	// With several venues, strategies see one composite price per symbol
+	// so a single exchange's glitch cannot drive them
+	method := os.Getenv("CONSOLIDATE")
+	if method == "" && strings.Contains(venues, ",") {
+		method = feed.MethodMedian
+	}
+	if method != "" && method != "off" {
+		ccfg := feed.DefaultConsolidateConfig()
+		ccfg.Method = method
+		ccfg.StaleAfter = envDuration("CONSOLIDATE_STALE_AFTER", ccfg.StaleAfter)
+		ccfg.VolumeWindow = envDuration("CONSOLIDATE_VOLUME_WINDOW", ccfg.VolumeWindow)
+		var err error
+		if consolidator, err = feed.NewConsolidator(ccfg, opts); err != nil {
+			log.Fatalf("❌ Invalid CONSOLIDATE: %v", err)
+		}
+		router.Consolidate(consolidator)
+		log.Printf("🔀 Consolidating %s prices by %s", venues, method)
+	}
+
+	for _, venue := range strings.Split(venues, ",") {
+		venue = strings.TrimSpace(venue)
+		cfg := feed.Config{
+			Venue:   venue,
+			Symbols: pairs,
+			URL:     os.Getenv(strings.ToUpper(venue) + "_WS_URL"),
+			RESTURL: os.Getenv(strings.ToUpper(venue) + "_REST_URL"),
+			Policy:  policy,
+			Params:  envParams(strings.ToUpper(venue) + "_"),
+		}
+		if streams := os.Getenv(strings.ToUpper(venue) + "_STREAMS"); streams != "" {
+			cfg.Streams = strings.Split(streams, ",")
+		}
+		if recorder != nil {
+			cfg.Recorder = recorder
+		}
+		f, err := feed.New(cfg)
+		if err != nil {
+			log.Fatalf("❌ Feed %s: %v", venue, err)
+		}
+		if err := f.Start(ctx); err != nil {
+			log.Fatalf("❌ Feed %s: %v", venue, err)
+		}
+		router.Attach(f)
+		feeds = append(feeds, f)
+	}
+	var player *replay.Feed
+	for _, f := range feeds {
+		if p, ok := f.(*replay.Feed); ok {
+			player = p
+		}
+	}
+	routed := make(chan struct{})
+	go func() {
+		router.Run()
+		close(routed)
+	}()
+
+	// Aggregate ticks into bar series per instrument
+	specs := bars.DefaultSpecs()
+	if list := os.Getenv("BAR_SERIES"); list != "" {
+		var err error
+		if specs, err = bars.ParseSpecs(list); err != nil {
+			log.Fatalf("❌ Invalid BAR_SERIES: %v", err)
+		}
+	}
+	barSets = bars.NewRegistry(buffers, specs, 500)
+	// A replay's exchange times are not the local clock's: its bars close
+	// as the recording's time moves on
+	if replaying {
+		barSets.SetClock(bars.TickClock)
+	}
+
+	// Seed time bars from venue candles so strategies on bars need not wait
+	// for a lookback to accumulate; the builders start once that is done and
+	// continue from the buffered ticks
+	if os.Getenv("BACKFILL") != "off" && !replaying {
+		bcfg := backfill.DefaultConfig()
+		bcfg.Bars = envInt("BACKFILL_BARS", bcfg.Bars)
+		bcfg.Timeout = envDuration("BACKFILL_TIMEOUT", bcfg.Timeout)
+		// Symbols added at runtime are backfilled the same way
+		barSets.SetPrepare(func(ctx context.Context, symbol string, b *bars.Builder) {
+			backfill.Symbol(ctx, feeds, symbol, b, bcfg)
+		})
+		go func() {
+			backfill.Run(ctx, feeds, barSets, buffers.Symbols(), bcfg)
+			barSets.Run(ctx)
+		}()
+	} else {
+		barSets.Run(ctx)
+	}
+
+	// Keep downsampled 1m/15m/daily history per instrument, rebuilt from
+	// the journal so it survives restarts
+	stores = history.NewRegistry(buffers, history.DefaultTiers())
+	if journalDir != "" {
+		stores.SetJournal(journalDir)
+	}
+	if replaying {
+		stores.SetClock(bars.TickClock)
+	}
+	stores.Run(ctx)
+
+	// Start one push-driven strategy loop per instrument
+	for _, symbol := range buffers.Symbols() {
+		buffer, _ := buffers.Get(symbol)
+		startStrategyLoop(ctx, symbol, buffer)
+	}
 
 	// Setup HTTP handlers
 	mux := http.NewServeMux()
 
 	// API endpoints
-	mux.HandleFunc("/api/health", api.HealthHandler)
-	mux.HandleFunc("/api/buffer/status", api.BufferStatusHandler(buffer))
-	mux.HandleFunc("/api/signals", api.SignalsHandler(buffer))
-	mux.HandleFunc("/ws", api.WebSocketHandler(buffer, upgrader))
+	mux.HandleFunc("/api/health", api.HealthHandler(feeds))
+	mux.HandleFunc("/api/metrics", api.MetricsHandler(feeds, filter))
+	mux.HandleFunc("/api/filter", api.FilterHandler(filter))
+	mux.HandleFunc("/api/symbols", api.SymbolsHandler(buffers, addSymbol, removeSymbol))
+	mux.HandleFunc("/api/buffer/status", api.BufferStatusHandler(buffers))
+	mux.HandleFunc("/api/signals", api.SignalsHandler(buffers, barSets, books, signals))
+	mux.HandleFunc("/api/book", api.BookHandler(buffers, books))
+	mux.HandleFunc("/api/gaps", api.GapsHandler(buffers))
+	mux.HandleFunc("/api/venues", api.VenuesHandler(buffers, consolidator))
+	mux.HandleFunc("/api/replay", api.ReplayHandler(player))
+	mux.HandleFunc("/api/bars", api.BarsHandler(buffers, barSets))
+	mux.HandleFunc("/api/history", api.HistoryHandler(buffers, stores))
+	var wsClients sync.WaitGroup
+	mux.HandleFunc("/ws", api.WebSocketHandler(ctx, &wsClients, buffers, barSets, books, signals, upgrader))
 
 	// Serve static frontend
 	fs := http.FileServer(http.Dir("./static"))
@@ -52,7 +279,7 @@ func main() {
 	// CORS middleware
 	handler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
 	}).Handler(mux)
@@ -62,22 +289,280 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("🚀 Quantum Trader starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		log.Printf("🚀 Quantum Trader starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("❌ HTTP server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("🛑 Shutting down")
+
+	// Stop taking requests; WebSocket clients are sent close frames by their
+	// handlers as soon as ctx is cancelled
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), envDuration("SHUTDOWN_TIMEOUT", 10*time.Second))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  HTTP shutdown: %v", err)
+	}
+	wait(shutdownCtx, wsClients.Wait)
+
+	// Stop the feeds and let the router write out what they delivered, so
+	// the final snapshot and journal include every received tick
+	for _, f := range feeds {
+		f.Stop()
+	}
+	wait(shutdownCtx, func() { <-routed })
+
+	if recorder != nil {
+		recorder.Close()
+		log.Printf("📼 Captured %d frames (%d dropped)", recorder.Frames(), recorder.Dropped())
+	}
+	if tickJournal != nil {
+		tickJournal.Close()
+	}
+	if snapshotPath != "" {
+		if err := snapshot.Save(snapshotPath, buffers); err != nil {
+			log.Printf("⚠️  Final snapshot failed: %v", err)
+		} else {
+			log.Printf("💾 Saved final snapshot to %s", snapshotPath)
+		}
+	}
+	log.Printf("👋 Shutdown complete")
 }
 
-func runStrategyLoop() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// wait runs fn and returns when it does or ctx expires, whichever is first.
+func wait(ctx context.Context, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("⚠️  Shutdown timed out waiting; continuing")
+	}
+}
+
+// startStrategyLoop runs the strategy loop for symbol until ctx is cancelled
+// or removeSymbol cancels it through loops. symbolsMu must be held once the
+// server is running.
+func startStrategyLoop(ctx context.Context, symbol string, buffer *ringbuffer.TickBuffer) {
+	ctx, cancel := context.WithCancel(ctx)
+	loops[symbol] = cancel
+	go runStrategyLoop(ctx, symbol, buffer)
+}
+
+// runStrategyLoop re-evaluates the strategies for symbol whenever new ticks
+// arrive and publishes the results to the signal board, which /api/signals
+// and /ws serve. The subscription has capacity 1, so ticks that land while
+// an evaluation is running are coalesced into a single follow-up pass.
+func runStrategyLoop(ctx context.Context, symbol string, buffer *ringbuffer.TickBuffer) {
+	sub := buffer.Subscribe(1)
+	defer sub.Close()
+
+	// Reused across passes so the hot path does not allocate
+	ticks := make([]ringbuffer.Tick, 256)
+	cursor := buffer.NewCursor()
 
-	for range ticker.C {
-		prices := buffer.ReadLast(100)
-		if len(prices) < 20 {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.C:
+		}
+
+		n, missed := cursor.Read(ticks)
+		if missed > 0 {
+			log.Printf("⚠️  Strategy loop lapped on %s: %d ticks overwritten", symbol, missed)
+		}
+		if n == 0 {
 			continue
 		}
 
-		// Run all 4 strategies
-		_ = strategies.AnalyzeAll(prices)
-		// Results will be sent via WebSocket in api package
+		snap := buffer.GetStats()
+		if snap.Count < 20 {
+			continue
+		}
+
+		// Run all 4 strategies on the incrementally maintained statistics,
+		// suppressing those whose lookback spans a gap
+		now := time.Now()
+		results := strategies.AnalyzeSnapshot(snap)
+		results = strategies.ApplyQuality(results, buffer.GetQuality(now))
+		signals.Publish(strategies.Update{
+			Symbol:      symbol,
+			Price:       snap.Last,
+			BufferIndex: buffer.GetWriteIndex(),
+			Sequence:    cursor.Sequence(),
+			Results:     results,
+			At:          now,
+		})
+	}
+}
+
+// addSymbol starts tracking an instrument at runtime. Instruments missing
+// from the instrument table must name their venue products. A new symbol
+// gets the same buffer, bars, history, journal and strategy loop as the
+// configured ones; a known one is simply resubscribed.
+func addSymbol(inst feed.Instrument) error {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+
+	symbol := ringbuffer.NormalizeSymbol(inst.Symbol)
+	listed, ok := feed.Lookup(symbol)
+	if !ok {
+		if len(inst.Venues) == 0 {
+			return fmt.Errorf("unknown instrument %q: venues are required", symbol)
+		}
+		if err := feed.AddInstrument(inst); err != nil {
+			return err
+		}
+		listed, _ = feed.Lookup(symbol)
+	}
+
+	var venues []feed.MarketDataFeed
+	for _, f := range feeds {
+		if _, ok := listed.Venues[f.Name()]; ok {
+			venues = append(venues, f)
+		}
+	}
+	if len(venues) == 0 {
+		return fmt.Errorf("no running feed lists %s", symbol)
+	}
+
+	if _, exists := buffers.Get(symbol); !exists {
+		// Consumers first: handlers treat a registered buffer as a fully
+		// tracked symbol
+		buffer := buffers.NewBuffer()
+		barSets.Add(symbol, buffer)
+		stores.Add(symbol, buffer)
+		if tickJournal != nil {
+			tickJournal.Attach(symbol, buffer)
+		}
+		startStrategyLoop(appCtx, symbol, buffer)
+		buffers.Put(symbol, buffer)
+		log.Printf("➕ Tracking %s", symbol)
+	}
+
+	for _, f := range venues {
+		if err := f.Subscribe([]string{symbol}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeSymbol stops tracking an instrument: its feeds are unsubscribed,
+// its strategy loop, bars and history are stopped and its buffer and books
+// are dropped, so it is no longer listed or queryable. Journaled ticks stay
+// on disk and rebuild its history if it is added again.
+func removeSymbol(symbol string) error {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	if _, ok := buffers.Get(symbol); !ok {
+		return api.ErrUnknownSymbol
+	}
+	for _, f := range feeds {
+		if err := f.Unsubscribe([]string{symbol}); err != nil {
+			return err
+		}
+	}
+
+	// Unlisted first, so handlers never find a buffer without its bars
+	buffers.Remove(symbol)
+	if cancel, ok := loops[symbol]; ok {
+		cancel()
+		delete(loops, symbol)
+	}
+	barSets.Remove(symbol)
+	stores.Remove(symbol)
+	if tickJournal != nil {
+		tickJournal.Detach(symbol)
+	}
+	books.Remove(symbol)
+	signals.Remove(symbol)
+	if filter != nil {
+		filter.Remove(symbol)
+	}
+	if consolidator != nil {
+		consolidator.Remove(symbol)
+	}
+	log.Printf("➖ Stopped tracking %s", symbol)
+	return nil
+}
+
+// envDuration reads a time.Duration such as "30s" from the environment,
+// falling back to def when unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	return envOr(key, def, time.ParseDuration)
+}
+
+// envInt reads an integer from the environment, falling back to def when
+// unset or invalid.
+func envInt(key string, def int) int {
+	return envOr(key, def, strconv.Atoi)
+}
+
+// envFloat reads a float from the environment, falling back to def when
+// unset or invalid.
+func envFloat(key string, def float64) float64 {
+	return envOr(key, def, parseFloat)
+}
+
+// envOr parses key with parse, logging and falling back to def when the
+// value is invalid.
+func envOr[T any](key string, def T, parse func(string) (T, error)) T {
+	v, err := lookupEnv(key, def, parse)
+	if err != nil {
+		log.Printf("⚠️  %v, using %v", err, def)
+		return def
+	}
+	return v
+}
+
+// mustEnv parses key with parse and exits when the value is invalid, for
+// settings where a silent default would hide a misconfiguration.
+func mustEnv[T any](key string, def T, parse func(string) (T, error)) T {
+	v, err := lookupEnv(key, def, parse)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	return v
+}
+
+// lookupEnv parses key with parse, returning def when it is unset.
+func lookupEnv[T any](key string, def T, parse func(string) (T, error)) (T, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+	v, err := parse(s)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s=%q", key, s)
+	}
+	return v, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// envParams collects the variables starting with prefix, keyed by the rest
+// of their name, e.g. SYNTHETIC_SEED=7 as "SEED": "7".
+func envParams(prefix string) map[string]string {
+	params := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutPrefix(key, prefix); ok && name != "" {
+			params[name] = value
+		}
 	}
+	return params
 }
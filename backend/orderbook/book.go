@@ -0,0 +1,256 @@
+package orderbook
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrGap means updates were missed and the book needs a fresh snapshot.
+	ErrGap = errors.New("orderbook: sequence gap")
+	// ErrCrossed means the best bid reached the best ask, which only
+	// happens when the local book has diverged from the venue's.
+	ErrCrossed = errors.New("orderbook: crossed book")
+)
+
+const (
+	// maxPending bounds the deltas held while waiting for a snapshot.
+	maxPending = 1000
+	// resyncRetry is how long an unanswered snapshot request is given
+	// before it is asked for again.
+	resyncRetry = 10 * time.Second
+)
+
+type Level struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// Update is a level 2 snapshot or incremental change as received from a
+// venue. In a delta a zero Size removes the level. FirstSeq and LastSeq are
+// the venue update ids covered, or zero when the venue does not number its
+// updates and ordering is guaranteed by the connection alone.
+type Update struct {
+	Snapshot bool
+	Time     time.Time
+	Bids     []Level
+	Asks     []Level
+	FirstSeq int64
+	LastSeq  int64
+}
+
+// Book is a local L2 order book for one symbol on one venue. Levels are
+// kept sorted best-first, so top-of-book is O(1) and depth-at-N is O(N).
+type Book struct {
+	venue  string
+	symbol string
+
+	mu          sync.RWMutex
+	bids        []Level // descending
+	asks        []Level // ascending
+	seq         int64
+	synced      bool
+	pending     []Update
+	requestedAt time.Time
+	updated     time.Time
+	resyncs     uint64
+}
+
+func NewBook(venue, symbol string) *Book {
+	return &Book{venue: venue, symbol: symbol}
+}
+
+// Apply folds u into the book. It returns ErrGap or ErrCrossed when the
+// book is out of sync and a snapshot should be requested; deltas keep being
+// queued meanwhile and are replayed on top of the snapshot.
+func (b *Book) Apply(u Update) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if u.Snapshot {
+		b.bids = setLevels(b.bids[:0], u.Bids, true)
+		b.asks = setLevels(b.asks[:0], u.Asks, false)
+		b.seq = u.LastSeq
+		b.synced = true
+		b.updated = u.Time
+		b.requestedAt = time.Time{}
+
+		pending := b.pending
+		b.pending = nil
+		for _, d := range pending {
+			// Unnumbered deltas cannot be placed relative to the snapshot
+			if d.LastSeq == 0 {
+				continue
+			}
+			if err := b.applyDelta(d); err != nil {
+				return err
+			}
+		}
+		return b.checkCrossed()
+	}
+
+	if !b.synced {
+		if len(b.pending) == maxPending {
+			b.pending = b.pending[1:]
+		}
+		b.pending = append(b.pending, u)
+		// Ask for a snapshot, again if the last request went unanswered
+		if b.requestedAt.IsZero() || time.Since(b.requestedAt) > resyncRetry {
+			b.requestedAt = time.Now()
+			return ErrGap
+		}
+		return nil
+	}
+
+	if err := b.applyDelta(u); err != nil {
+		return err
+	}
+	return b.checkCrossed()
+}
+
+// applyDelta validates the sequence and applies one delta. Callers hold
+// b.mu.
+func (b *Book) applyDelta(u Update) error {
+	if u.LastSeq != 0 {
+		if u.LastSeq <= b.seq {
+			return nil // already covered by the snapshot
+		}
+		if b.seq != 0 && u.FirstSeq > b.seq+1 {
+			b.desync()
+			b.pending = append(b.pending, u)
+			return ErrGap
+		}
+		b.seq = u.LastSeq
+	}
+	for _, l := range u.Bids {
+		b.bids = setLevel(b.bids, l, true)
+	}
+	for _, l := range u.Asks {
+		b.asks = setLevel(b.asks, l, false)
+	}
+	if u.Time.After(b.updated) {
+		b.updated = u.Time
+	}
+	return nil
+}
+
+func (b *Book) checkCrossed() error {
+	if len(b.bids) > 0 && len(b.asks) > 0 && b.bids[0].Price >= b.asks[0].Price {
+		b.desync()
+		return ErrCrossed
+	}
+	return nil
+}
+
+// desync drops the book's contents until the next snapshot. Callers hold
+// b.mu.
+func (b *Book) desync() {
+	b.bids = b.bids[:0]
+	b.asks = b.asks[:0]
+	b.synced = false
+	b.resyncs++
+	b.requestedAt = time.Now()
+}
+
+// Reset empties the book, e.g. after a disconnect. The next delta asks for
+// a snapshot.
+func (b *Book) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bids = b.bids[:0]
+	b.asks = b.asks[:0]
+	b.pending = nil
+	b.synced = false
+	b.seq = 0
+	b.requestedAt = time.Time{}
+}
+
+// Best returns the top of book. ok is false unless the book is synced and
+// both sides are populated.
+func (b *Book) Best() (bid, ask Level, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.synced || len(b.bids) == 0 || len(b.asks) == 0 {
+		return Level{}, Level{}, false
+	}
+	return b.bids[0], b.asks[0], true
+}
+
+// Depth copies up to n levels per side; n <= 0 copies the whole book.
+func (b *Book) Depth(n int) Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return Snapshot{
+		Venue:    b.venue,
+		Symbol:   b.symbol,
+		Sequence: b.seq,
+		Synced:   b.synced,
+		Updated:  b.updated,
+		Resyncs:  b.resyncs,
+		Bids:     copyLevels(b.bids, n),
+		Asks:     copyLevels(b.asks, n),
+	}
+}
+
+func (b *Book) GetVenue() string {
+	return b.venue
+}
+
+func (b *Book) GetSymbol() string {
+	return b.symbol
+}
+
+func (b *Book) IsSynced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.synced
+}
+
+// setLevels rebuilds a side from a snapshot.
+func setLevels(dst, levels []Level, desc bool) []Level {
+	for _, l := range levels {
+		if l.Size > 0 {
+			dst = append(dst, l)
+		}
+	}
+	sort.Slice(dst, func(i, j int) bool {
+		if desc {
+			return dst[i].Price > dst[j].Price
+		}
+		return dst[i].Price < dst[j].Price
+	})
+	return dst
+}
+
+// setLevel inserts, updates or (for a zero size) removes one level.
+func setLevel(levels []Level, l Level, desc bool) []Level {
+	i := sort.Search(len(levels), func(i int) bool {
+		if desc {
+			return levels[i].Price <= l.Price
+		}
+		return levels[i].Price >= l.Price
+	})
+	found := i < len(levels) && levels[i].Price == l.Price
+	switch {
+	case l.Size <= 0 && found:
+		return append(levels[:i], levels[i+1:]...)
+	case l.Size <= 0:
+		return levels
+	case found:
+		levels[i].Size = l.Size
+		return levels
+	}
+	levels = append(levels, Level{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = l
+	return levels
+}
+
+func copyLevels(levels []Level, n int) []Level {
+	if n <= 0 || n > len(levels) {
+		n = len(levels)
+	}
+	return append([]Level(nil), levels[:n]...)
+}
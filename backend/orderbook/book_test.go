@@ -0,0 +1,141 @@
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// delta is a numbered update covering ids first..last.
+func delta(first, last int64, bids, asks []Level) Update {
+	return Update{FirstSeq: first, LastSeq: last, Bids: bids, Asks: asks}
+}
+
+func snapshot(last int64) Update {
+	return Update{
+		Snapshot: true,
+		LastSeq:  last,
+		Bids:     []Level{{100, 1}, {99, 2}, {98, 3}},
+		Asks:     []Level{{101, 1}, {102, 2}, {103, 3}},
+	}
+}
+
+func levels(ls []Level) string {
+	return fmt.Sprint(ls)
+}
+
+func TestBookBridgesSnapshot(t *testing.T) {
+	b := NewBook("binance", "btcusdt")
+
+	// Deltas before the snapshot are queued; the first asks for it
+	if err := b.Apply(delta(90, 95, []Level{{97, 9}}, nil)); !errors.Is(err, ErrGap) {
+		t.Fatalf("first delta: %v, want ErrGap", err)
+	}
+	if err := b.Apply(delta(96, 103, []Level{{100, 5}}, nil)); err != nil {
+		t.Fatalf("second delta: %v", err)
+	}
+	if err := b.Apply(delta(104, 105, nil, []Level{{101, 0}})); err != nil {
+		t.Fatalf("third delta: %v", err)
+	}
+	if b.IsSynced() {
+		t.Fatal("synced before the snapshot")
+	}
+
+	// The snapshot is at 100: 90-95 is stale, 96-103 bridges it
+	if err := b.Apply(snapshot(100)); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	d := b.Depth(0)
+	if !d.Synced || d.Sequence != 105 {
+		t.Fatalf("synced %v at %d, want synced at 105", d.Synced, d.Sequence)
+	}
+	if got, want := levels(d.Bids), levels([]Level{{100, 5}, {99, 2}, {98, 3}}); got != want {
+		t.Errorf("bids %s, want %s", got, want)
+	}
+	if got, want := levels(d.Asks), levels([]Level{{102, 2}, {103, 3}}); got != want {
+		t.Errorf("asks %s, want %s", got, want)
+	}
+}
+
+func TestBookDropsStaleDiffs(t *testing.T) {
+	b := NewBook("binance", "btcusdt")
+	b.Apply(snapshot(100))
+
+	for _, u := range []Update{delta(95, 99, []Level{{100, 7}}, nil), delta(100, 100, []Level{{100, 8}}, nil)} {
+		if err := b.Apply(u); err != nil {
+			t.Fatalf("stale delta %d-%d: %v", u.FirstSeq, u.LastSeq, err)
+		}
+	}
+	bid, _, ok := b.Best()
+	if !ok || bid.Size != 1 {
+		t.Errorf("best bid %+v, want the snapshot's size 1", bid)
+	}
+	if d := b.Depth(0); d.Sequence != 100 || d.Resyncs != 0 {
+		t.Errorf("sequence %d, resyncs %d; want 100, 0", d.Sequence, d.Resyncs)
+	}
+}
+
+func TestBookGapResyncs(t *testing.T) {
+	b := NewBook("binance", "btcusdt")
+	b.Apply(snapshot(100))
+	if err := b.Apply(delta(101, 102, []Level{{99, 4}}, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 103-104 went missing
+	if err := b.Apply(delta(105, 108, []Level{{100, 6}}, nil)); !errors.Is(err, ErrGap) {
+		t.Fatalf("gap: %v, want ErrGap", err)
+	}
+	if _, _, ok := b.Best(); ok {
+		t.Error("Best reported a book that is out of sync")
+	}
+	// The resync is already requested, so further deltas just queue
+	if err := b.Apply(delta(109, 110, nil, []Level{{104, 1}})); err != nil {
+		t.Fatalf("delta while resyncing: %v", err)
+	}
+
+	if err := b.Apply(snapshot(107)); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	d := b.Depth(0)
+	if !d.Synced || d.Sequence != 110 || d.Resyncs != 1 {
+		t.Fatalf("synced %v at %d after %d resyncs, want synced at 110 after 1", d.Synced, d.Sequence, d.Resyncs)
+	}
+	if got, want := levels(d.Bids), levels([]Level{{100, 6}, {99, 2}, {98, 3}}); got != want {
+		t.Errorf("bids %s, want %s", got, want)
+	}
+	if got, want := levels(d.Asks), levels([]Level{{101, 1}, {102, 2}, {103, 3}, {104, 1}}); got != want {
+		t.Errorf("asks %s, want %s", got, want)
+	}
+}
+
+func TestBookCrossedResyncs(t *testing.T) {
+	b := NewBook("binance", "btcusdt")
+	b.Apply(snapshot(100))
+	if err := b.Apply(delta(101, 101, []Level{{101.5, 1}}, nil)); !errors.Is(err, ErrCrossed) {
+		t.Fatalf("crossing delta: %v, want ErrCrossed", err)
+	}
+	if b.IsSynced() {
+		t.Error("crossed book still synced")
+	}
+}
+
+func TestBookUnnumbered(t *testing.T) {
+	b := NewBook("coinbase", "btcusdt")
+
+	// Unnumbered deltas cannot be placed against a later snapshot
+	b.Apply(Update{Bids: []Level{{97, 9}}})
+	b.Apply(Update{Snapshot: true, Bids: []Level{{100, 1}}, Asks: []Level{{101, 1}}})
+	b.Apply(Update{Bids: []Level{{100, 0}, {99.5, 2}}, Asks: []Level{{100.5, 3}}})
+
+	d := b.Depth(0)
+	if got, want := levels(d.Bids), levels([]Level{{99.5, 2}}); got != want {
+		t.Errorf("bids %s, want %s", got, want)
+	}
+	if got, want := levels(d.Asks), levels([]Level{{100.5, 3}, {101, 1}}); got != want {
+		t.Errorf("asks %s, want %s", got, want)
+	}
+	if d.Imbalance() != -1.0/3 || d.Spread() != 1 {
+		t.Errorf("imbalance %g, spread %g", d.Imbalance(), d.Spread())
+	}
+}
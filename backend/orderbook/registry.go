@@ -0,0 +1,76 @@
+package orderbook
+
+import (
+	"sync"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Registry holds one Book per venue and symbol. Venues are never merged:
+// their price levels are not interchangeable.
+type Registry struct {
+	books  map[string]map[string]*Book // symbol -> venue -> book
+	venues map[string][]string         // symbol -> venues in arrival order
+	mu     sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		books:  make(map[string]map[string]*Book),
+		venues: make(map[string][]string),
+	}
+}
+
+// Book returns the book for venue and symbol, creating it on first use.
+func (r *Registry) Book(venue, symbol string) *Book {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	if b, ok := r.Get(symbol, venue); ok {
+		return b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.books[symbol][venue]; ok {
+		return b
+	}
+	if r.books[symbol] == nil {
+		r.books[symbol] = make(map[string]*Book)
+	}
+	b := NewBook(venue, symbol)
+	r.books[symbol][venue] = b
+	r.venues[symbol] = append(r.venues[symbol], venue)
+	return b
+}
+
+// Get looks up a symbol's book. An empty venue picks the first venue that
+// delivered a book for the symbol.
+func (r *Registry) Get(symbol, venue string) (*Book, bool) {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if venue == "" {
+		venues := r.venues[symbol]
+		if len(venues) == 0 {
+			return nil, false
+		}
+		venue = venues[0]
+	}
+	b, ok := r.books[symbol][venue]
+	return b, ok
+}
+
+// Remove drops every venue's book for symbol.
+func (r *Registry) Remove(symbol string) {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.books, symbol)
+	delete(r.venues, symbol)
+}
+
+// Venues lists the venues with a book for symbol.
+func (r *Registry) Venues(symbol string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.venues[ringbuffer.NormalizeSymbol(symbol)]...)
+}
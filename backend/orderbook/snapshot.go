@@ -0,0 +1,45 @@
+package orderbook
+
+import "time"
+
+// Snapshot is a copy of the top of a Book.
+type Snapshot struct {
+	Venue    string    `json:"venue"`
+	Symbol   string    `json:"symbol"`
+	Sequence int64     `json:"sequence"`
+	Synced   bool      `json:"synced"`
+	Updated  time.Time `json:"updated"`
+	Resyncs  uint64    `json:"resyncs"`
+	Bids     []Level   `json:"bids"`
+	Asks     []Level   `json:"asks"`
+}
+
+func (s Snapshot) Mid() float64 {
+	if len(s.Bids) == 0 || len(s.Asks) == 0 {
+		return 0
+	}
+	return (s.Bids[0].Price + s.Asks[0].Price) / 2
+}
+
+func (s Snapshot) Spread() float64 {
+	if len(s.Bids) == 0 || len(s.Asks) == 0 {
+		return 0
+	}
+	return s.Asks[0].Price - s.Bids[0].Price
+}
+
+// Imbalance is (bid size - ask size) / (bid size + ask size) over the
+// levels in the snapshot: +1 is all bids, -1 all asks.
+func (s Snapshot) Imbalance() float64 {
+	var bid, ask float64
+	for _, l := range s.Bids {
+		bid += l.Size
+	}
+	for _, l := range s.Asks {
+		ask += l.Size
+	}
+	if bid+ask == 0 {
+		return 0
+	}
+	return (bid - ask) / (bid + ask)
+}
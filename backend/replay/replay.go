@@ -0,0 +1,469 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+const Name = "replay"
+
+// ErrSeekBackwards is returned by Seek for a time before the current
+// position: buffers, bars and the ingestion filter only move forward, so
+// an earlier start needs a restart with a FROM param.
+var ErrSeekBackwards = errors.New("replay: cannot seek backwards")
+
+var errFinished = errors.New("recording finished")
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed plays a recording back as if it were live. It is configured through
+// Params:
+//
+//	SOURCE  journal directory, or CSV / JSON lines file or glob (.gz allowed)
+//	FORMAT  journal, csv or jsonl; detected from SOURCE when unset
+//	VENUE   venue of bare JSON frames (coinbase, binance)
+//	SPEED   1 for real time, N for N× or "max" for as fast as possible
+//	FROM    RFC 3339 time to start from
+//	TO      RFC 3339 time to stop at
+//
+// Events keep their recorded exchange times, so bars, gaps and strategies
+// see what they saw live; ReceivedAt is the replay time, so the buffers are
+// not reported stale.
+type Feed struct {
+	path   string
+	format string
+	venue  string
+	from   time.Time
+	to     time.Time
+	events chan feed.Event
+	health *feed.HealthTracker
+
+	mu       sync.Mutex
+	symbols  []string
+	speed    float64 // 0 is as fast as possible
+	paused   bool
+	seekTo   time.Time
+	position time.Time
+	replayed uint64
+	finished bool
+	epoch    uint64 // bumped whenever pacing must restart from position
+	wake     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopped  bool
+}
+
+// Status is the replay position and controls.
+type Status struct {
+	Source   string     `json:"source"`
+	Format   string     `json:"format"`
+	Speed    float64    `json:"speed"` // 0 is as fast as possible
+	Paused   bool       `json:"paused"`
+	Position time.Time  `json:"position"`
+	SeekTo   *time.Time `json:"seekTo,omitempty"` // pending seek
+	Replayed uint64     `json:"replayed"`
+	Finished bool       `json:"finished"`
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	f := &Feed{
+		path:   cfg.Params["SOURCE"],
+		format: strings.ToLower(cfg.Params["FORMAT"]),
+		venue:  strings.ToLower(cfg.Params["VENUE"]),
+		speed:  1,
+		events: make(chan feed.Event, 1024),
+		health: feed.NewHealthTracker(Name),
+		wake:   make(chan struct{}, 1),
+	}
+	if f.path == "" {
+		return nil, errors.New("replay: SOURCE is required")
+	}
+	if f.format == "" {
+		f.format = detectFormat(f.path)
+	}
+	switch f.format {
+	case FormatJournal, FormatCSV, FormatJSONL:
+	default:
+		return nil, fmt.Errorf("replay: unknown format %q", f.format)
+	}
+	if v := cfg.Params["SPEED"]; v != "" {
+		speed, err := ParseSpeed(v)
+		if err != nil {
+			return nil, err
+		}
+		f.speed = speed
+	}
+	for key, t := range map[string]*time.Time{"FROM": &f.from, "TO": &f.to} {
+		if v := cfg.Params[key]; v != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				return nil, fmt.Errorf("replay: bad %s %q", key, v)
+			}
+		}
+	}
+	f.seekTo = f.from
+
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseSpeed parses a replay speed: a positive multiple of real time, or
+// "max" (returned as 0) for as fast as possible.
+func ParseSpeed(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "max" {
+		return 0, nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("replay: bad speed %q", s)
+	}
+	return speed, nil
+}
+
+func (f *Feed) Name() string {
+	return Name
+}
+
+func (f *Feed) Events() <-chan feed.Event {
+	return f.events
+}
+
+func (f *Feed) Health() feed.Health {
+	return f.health.Health()
+}
+
+func (f *Feed) Start(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ctx != nil || f.stopped {
+		return errors.New("replay: feed already started")
+	}
+	f.ctx, f.cancel = context.WithCancel(ctx)
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.run(f.ctx)
+	}()
+	return nil
+}
+
+func (f *Feed) Stop() error {
+	f.mu.Lock()
+	if f.stopped {
+		f.mu.Unlock()
+		return nil
+	}
+	f.stopped = true
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	close(f.events)
+	f.health.Stopped()
+	return nil
+}
+
+// Subscribe selects which recorded symbols are replayed; the rest of the
+// recording is skipped.
+func (f *Feed) Subscribe(symbols []string) error {
+	f.mu.Lock()
+	var added []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if !contains(f.symbols, symbol) && !contains(added, symbol) {
+			added = append(added, symbol)
+		}
+	}
+	f.symbols = append(f.symbols, added...)
+	f.health.SetSymbols(f.symbols)
+	ctx := f.ctx
+	f.mu.Unlock()
+
+	if ctx != nil && len(added) > 0 {
+		f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: true, Symbols: added})
+	}
+	return nil
+}
+
+func (f *Feed) Unsubscribe(symbols []string) error {
+	f.mu.Lock()
+	var removed []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if contains(f.symbols, symbol) && !contains(removed, symbol) {
+			removed = append(removed, symbol)
+		}
+	}
+	kept := f.symbols[:0]
+	for _, symbol := range f.symbols {
+		if !contains(removed, symbol) {
+			kept = append(kept, symbol)
+		}
+	}
+	f.symbols = kept
+	f.health.SetSymbols(f.symbols)
+	ctx := f.ctx
+	f.mu.Unlock()
+
+	if ctx != nil && len(removed) > 0 {
+		f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: false, Symbols: removed})
+	}
+	return nil
+}
+
+// Status reports the replay position and controls.
+func (f *Feed) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := Status{
+		Source:   f.path,
+		Format:   f.format,
+		Speed:    f.speed,
+		Paused:   f.paused,
+		Position: f.position,
+		Replayed: f.replayed,
+		Finished: f.finished,
+	}
+	if !f.seekTo.IsZero() {
+		seekTo := f.seekTo
+		status.SeekTo = &seekTo
+	}
+	return status
+}
+
+// Pause holds the replay at its position until Resume.
+func (f *Feed) Pause() {
+	f.control(func() { f.paused = true })
+}
+
+func (f *Feed) Resume() {
+	f.control(func() { f.paused = false })
+}
+
+// SetSpeed changes the pace; 0 replays as fast as possible.
+func (f *Feed) SetSpeed(speed float64) error {
+	if speed < 0 {
+		return fmt.Errorf("replay: bad speed %g", speed)
+	}
+	f.control(func() { f.speed = speed })
+	return nil
+}
+
+// Seek skips ahead to t without pacing. The skipped stretch shows up as a
+// gap in the buffers, as an outage would.
+func (f *Feed) Seek(t time.Time) error {
+	f.mu.Lock()
+	if t.Before(f.position) {
+		f.mu.Unlock()
+		return ErrSeekBackwards
+	}
+	f.mu.Unlock()
+	f.control(func() { f.seekTo = t })
+	return nil
+}
+
+// control applies a change and restarts pacing from the current position.
+func (f *Feed) control(change func()) {
+	f.mu.Lock()
+	change()
+	f.epoch++
+	f.mu.Unlock()
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (f *Feed) open(ctx context.Context) (source, error) {
+	switch f.format {
+	case FormatJournal:
+		return openJournal(ctx, f.path, f.from, f.to)
+	case FormatCSV:
+		return openCSV(f.path)
+	}
+	return openJSONL(f.path, f.venue)
+}
+
+func (f *Feed) run(ctx context.Context) {
+	f.health.Connecting()
+	src, err := f.open(ctx)
+	if err != nil {
+		log.Printf("❌ Replay of %s failed: %v", f.path, err)
+		f.health.Failed(err)
+		return
+	}
+	defer src.close()
+
+	f.mu.Lock()
+	symbols := append([]string(nil), f.symbols...)
+	speed := f.speed
+	f.mu.Unlock()
+
+	log.Printf("📼 Replaying %s (%s) at %s", f.path, f.format, FormatSpeed(speed))
+	f.health.Connected()
+	f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: true, Symbols: symbols})
+
+	err = f.play(ctx, src)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == io.EOF {
+		log.Printf("🏁 Replay of %s finished", f.path)
+		err = errFinished
+	} else {
+		log.Printf("❌ Replay of %s failed: %v", f.path, err)
+	}
+
+	f.mu.Lock()
+	f.finished = true
+	symbols = append([]string(nil), f.symbols...)
+	f.mu.Unlock()
+	f.health.Disconnected(err)
+	f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: false, Symbols: symbols})
+}
+
+// play emits records at their recorded pace until the source ends.
+// Pacing is anchored at a wall clock and recording time pair, which is
+// reset whenever a control changes.
+func (f *Feed) play(ctx context.Context, src source) error {
+	var anchorWall, anchorAt time.Time
+	var epoch uint64
+	for {
+		rec, err := src.next()
+		if err != nil {
+			return err
+		}
+		if !f.to.IsZero() && !rec.at.Before(f.to) {
+			return io.EOF
+		}
+		if !f.wait(ctx, rec.at, &anchorWall, &anchorAt, &epoch) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		now := time.Now()
+		f.mu.Lock()
+		active := contains(f.symbols, rec.ev.Symbol)
+		f.position = rec.at
+		f.mu.Unlock()
+		if !active {
+			continue
+		}
+		if rec.ev.Kind == feed.EventTick || rec.ev.Kind == feed.EventTrade {
+			rec.ev.Tick.ReceivedAt = now
+		}
+		f.health.Message(now)
+		f.emit(ctx, rec.ev)
+
+		f.mu.Lock()
+		f.replayed++
+		f.mu.Unlock()
+	}
+}
+
+// wait blocks until the record at at is due. It reports false if the
+// record is skipped by a seek or the feed is stopping.
+func (f *Feed) wait(ctx context.Context, at time.Time, anchorWall, anchorAt *time.Time, epoch *uint64) bool {
+	for {
+		f.mu.Lock()
+		if f.epoch != *epoch {
+			// The record after position is due its recorded gap from now,
+			// however long the replay was paused
+			*epoch = f.epoch
+			*anchorWall = time.Time{}
+			if !f.position.IsZero() && !f.position.After(at) {
+				*anchorWall, *anchorAt = time.Now(), f.position
+			}
+		}
+		paused, speed, seekTo := f.paused, f.speed, f.seekTo
+		if !seekTo.IsZero() && !at.Before(seekTo) {
+			// Landed: pacing restarts at this record
+			f.seekTo = time.Time{}
+			*anchorWall = time.Time{}
+		}
+		f.mu.Unlock()
+
+		if !seekTo.IsZero() && at.Before(seekTo) {
+			// Seeking restarts pacing where it lands
+			*anchorWall = time.Time{}
+			return false
+		}
+		var delay time.Duration
+		if !paused {
+			if speed == 0 {
+				return true
+			}
+			if anchorWall.IsZero() {
+				*anchorWall, *anchorAt = time.Now(), at
+			}
+			delay = time.Until(anchorWall.Add(time.Duration(float64(at.Sub(*anchorAt)) / speed)))
+			if delay <= 0 {
+				return true
+			}
+		}
+
+		var timer *time.Timer
+		var due <-chan time.Time
+		if !paused {
+			timer = time.NewTimer(delay)
+			due = timer.C
+		}
+		select {
+		case <-due:
+			return true
+		case <-f.wake:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// FormatSpeed renders a speed as "max speed" or a multiple such as "10x".
+func FormatSpeed(speed float64) string {
+	if speed == 0 {
+		return "max speed"
+	}
+	return strconv.FormatFloat(speed, 'g', -1, 64) + "x"
+}
+
+func (f *Feed) emit(ctx context.Context, ev feed.Event) {
+	select {
+	case f.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
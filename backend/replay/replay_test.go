@@ -0,0 +1,251 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/bars"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/orderbook"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var session = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// writeCSV records a btcusdt tick at each offset from session, priced at
+// 100 plus its index, and returns the file.
+func writeCSV(t *testing.T, offsets ...time.Duration) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("time,symbol,price,size\n")
+	for i, d := range offsets {
+		fmt.Fprintf(&b, "%s,btcusdt,%d,1\n", session.Add(d).Format(time.RFC3339Nano), 100+i)
+	}
+	path := filepath.Join(t.TempDir(), "session.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newReplay(t *testing.T, path, speed string) *Feed {
+	t.Helper()
+	f, err := New(feed.Config{Symbols: []string{"btcusdt"}, Params: map[string]string{"SOURCE": path, "SPEED": speed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.(*Feed)
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestReplayBarsKeepRecordedTimes replays a session from a past day at full
+// speed and checks that its bars are the recorded minutes, not minutes of
+// the local clock, once the builder's clock has ticked.
+func TestReplayBarsKeepRecordedTimes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 14:02 has no trades
+	path := writeCSV(t, 5*time.Second, 30*time.Second, 70*time.Second, 190*time.Second, 250*time.Second)
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt"}, ringbuffer.DefaultOptions())
+	barSets := bars.NewRegistry(buffers, []bars.Spec{{Kind: bars.Time, Interval: time.Minute}}, 100)
+	barSets.SetClock(bars.TickClock)
+	barSets.Run(ctx)
+
+	f := newReplay(t, path, "max")
+	router := feed.NewRouter(buffers, orderbook.NewRegistry(), nil)
+	router.Attach(f)
+	go router.Run()
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	series, _ := barSets.Series("btcusdt", "1m")
+	waitFor(t, "the replay", func() bool {
+		cur, ok := series.Current()
+		return f.Status().Finished && ok && cur.Trades == 1 && cur.Close == 104
+	})
+	// Let the builder's clock fire a few times
+	time.Sleep(1500 * time.Millisecond)
+
+	got := series.ReadLast(100)
+	want := []bars.Bar{
+		{Start: session, End: session.Add(time.Minute), Open: 100, High: 101, Low: 100, Close: 101, Volume: 2, Notional: 201, Trades: 2},
+		{Start: session.Add(time.Minute), End: session.Add(2 * time.Minute), Open: 102, High: 102, Low: 102, Close: 102, Volume: 1, Notional: 102, Trades: 1},
+		{Start: session.Add(2 * time.Minute), End: session.Add(3 * time.Minute), Open: 102, High: 102, Low: 102, Close: 102},
+		{Start: session.Add(3 * time.Minute), End: session.Add(4 * time.Minute), Open: 103, High: 103, Low: 103, Close: 103, Volume: 1, Notional: 103, Trades: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d bars, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bar %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if cur, _ := series.Current(); !cur.Start.Equal(session.Add(4 * time.Minute)) {
+		t.Errorf("forming bar starts %s, want 14:04 of the session", cur.Start)
+	}
+}
+
+// nextTrade returns the next trade the replay emits, or false if none comes
+// within d.
+func nextTrade(t *testing.T, f *Feed, d time.Duration) (ringbuffer.Tick, bool) {
+	t.Helper()
+	timeout := time.After(d)
+	for {
+		select {
+		case ev, ok := <-f.Events():
+			if !ok {
+				t.Fatal("events closed")
+			}
+			if ev.Kind == feed.EventTrade {
+				return ev.Tick, true
+			}
+		case <-timeout:
+			return ringbuffer.Tick{}, false
+		}
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// At 20x the ticks are 50ms apart; then 1x would take another 5s
+	path := writeCSV(t, 0, time.Second, 2*time.Second, 3*time.Second, 4*time.Second, 9*time.Second)
+	f := newReplay(t, path, "20")
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	first, ok := nextTrade(t, f, 5*time.Second)
+	if !ok || first.Price != 100 {
+		t.Fatalf("first trade %+v", first)
+	}
+	start := time.Now()
+	for i := 1; i < 5; i++ {
+		tick, ok := nextTrade(t, f, 5*time.Second)
+		if !ok || tick.Price != float64(100+i) {
+			t.Fatalf("trade %d: %+v", i, tick)
+		}
+		if !tick.Time.Equal(session.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("trade %d stamped %s, want its recorded time", i, tick.Time)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("4 recorded seconds took %s at 20x, want 200ms", elapsed)
+	}
+
+	// Max speed drops the pacing at once, even mid-wait
+	f.SetSpeed(0)
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 105 {
+		t.Fatalf("last trade %+v at max speed", tick)
+	}
+	if err := f.SetSpeed(-1); err == nil {
+		t.Error("negative speed accepted")
+	}
+	waitFor(t, "the end", func() bool { return f.Status().Finished })
+	if s := f.Status(); s.Replayed != 6 || s.Speed != 0 || !s.Position.Equal(session.Add(9*time.Second)) {
+		t.Fatalf("status %+v", s)
+	}
+}
+
+func TestReplayPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := writeCSV(t, 0, 100*time.Millisecond, 200*time.Millisecond, 300*time.Millisecond)
+	f := newReplay(t, path, "1")
+	// Paused from the start, nothing is emitted
+	f.Pause()
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+	if tick, ok := nextTrade(t, f, 300*time.Millisecond); ok {
+		t.Fatalf("paused replay emitted %+v", tick)
+	}
+
+	f.Resume()
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 100 {
+		t.Fatalf("first trade %+v after resuming", tick)
+	}
+	f.Pause()
+	if tick, ok := nextTrade(t, f, 400*time.Millisecond); ok {
+		t.Fatalf("paused replay emitted %+v", tick)
+	}
+	if s := f.Status(); !s.Paused || s.Replayed != 1 || !s.Position.Equal(session) {
+		t.Fatalf("status %+v while paused", s)
+	}
+
+	// Pacing restarts from the position, so the next tick is still 100ms
+	// away rather than long overdue
+	f.Resume()
+	resumed := time.Now()
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 101 {
+		t.Fatalf("second trade %+v after resuming", tick)
+	}
+	if waited := time.Since(resumed); waited < 80*time.Millisecond {
+		t.Fatalf("second trade came %s after resuming, want 100ms", waited)
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := writeCSV(t, 0, time.Hour, 2*time.Hour, 2*time.Hour+50*time.Millisecond, 3*time.Hour)
+	f := newReplay(t, path, "1")
+	if err := f.Seek(session.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if s := f.Status(); s.SeekTo == nil || !s.SeekTo.Equal(session.Add(2*time.Hour)) {
+		t.Fatalf("pending seek %v", s.SeekTo)
+	}
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	// The first two hours are skipped, and pacing resumes where it landed
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 102 {
+		t.Fatalf("first trade %+v after seeking", tick)
+	}
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 103 {
+		t.Fatalf("second trade %+v after seeking", tick)
+	}
+	s := f.Status()
+	if s.Replayed != 2 || s.SeekTo != nil || !s.Position.Equal(session.Add(2*time.Hour+50*time.Millisecond)) {
+		t.Fatalf("status %+v after seeking", s)
+	}
+
+	if err := f.Seek(session.Add(time.Hour)); err != ErrSeekBackwards {
+		t.Fatalf("seeking back an hour: %v", err)
+	}
+	// Seeking to the end skips the hour to the last trade
+	if err := f.Seek(session.Add(3 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if tick, ok := nextTrade(t, f, time.Second); !ok || tick.Price != 104 {
+		t.Fatalf("trade %+v after seeking to the end", tick)
+	}
+	waitFor(t, "the end", func() bool { return f.Status().Finished })
+}
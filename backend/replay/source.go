@@ -0,0 +1,393 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/binance"
+	"github.com/stahir80td/quantum-trader/bitstamp"
+	"github.com/stahir80td/quantum-trader/capture"
+	"github.com/stahir80td/quantum-trader/coinbase"
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/journal"
+	"github.com/stahir80td/quantum-trader/kraken"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Recording formats.
+const (
+	FormatJournal = "journal" // a tick journal directory
+	FormatCSV     = "csv"     // time,symbol,price[,size,side,sequence,venue] with a header row
+	FormatJSONL   = "jsonl"   // raw venue frames, one per line
+)
+
+// record is one recorded event and the time it happened, which paces the
+// replay.
+type record struct {
+	at time.Time
+	ev feed.Event
+}
+
+// source yields records in time order until io.EOF.
+type source interface {
+	next() (record, error)
+	close()
+}
+
+// detectFormat picks a format from the path: directories are journals
+// unless they hold captures, .csv files are CSV, anything else JSON lines.
+func detectFormat(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if paths, _ := capture.Files(path); len(paths) > 0 {
+			return FormatJSONL
+		}
+		return FormatJournal
+	}
+	if strings.HasSuffix(strings.TrimSuffix(path, ".gz"), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSONL
+}
+
+// files expands a path or glob pattern to files in name order, so hourly
+// captures replay chronologically.
+func files(pattern string) ([]string, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("replay: no recordings match %s", pattern)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// openFile opens a recording, decompressing .gz files.
+func openFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, f}, nil
+}
+
+// journalSource merges the journaled ticks of every symbol in the
+// directory by exchange time.
+type journalSource struct {
+	cancel context.CancelFunc
+	heads  []*record
+	chans  []chan record
+	errs   []error
+}
+
+func openJournal(ctx context.Context, dir string, from, to time.Time) (source, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &journalSource{cancel: cancel}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		symbol := e.Name()
+		ch := make(chan record, 256)
+		i := len(s.chans)
+		s.chans = append(s.chans, ch)
+		s.heads = append(s.heads, nil)
+		s.errs = append(s.errs, nil)
+		go func() {
+			defer close(ch)
+			s.errs[i] = journal.Read(dir, symbol, from, to, func(t ringbuffer.Tick) error {
+				t.Symbol = symbol
+				select {
+				case ch <- record{at: t.Time, ev: feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: t}}:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+	}
+	if len(s.chans) == 0 {
+		cancel()
+		return nil, fmt.Errorf("replay: no journaled symbols in %s", dir)
+	}
+	return s, nil
+}
+
+func (s *journalSource) next() (record, error) {
+	best := -1
+	for i, ch := range s.chans {
+		if s.heads[i] == nil && ch != nil {
+			r, ok := <-ch
+			if !ok {
+				// The reader has finished, so its error is visible now
+				if err := s.errs[i]; err != nil && !errors.Is(err, context.Canceled) {
+					return record{}, err
+				}
+				s.chans[i] = nil
+				continue
+			}
+			s.heads[i] = &r
+		}
+		if s.heads[i] != nil && (best < 0 || s.heads[i].at.Before(s.heads[best].at)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return record{}, io.EOF
+	}
+	r := *s.heads[best]
+	s.heads[best] = nil
+	return r, nil
+}
+
+func (s *journalSource) close() {
+	s.cancel()
+	// Let the readers see the cancellation and exit
+	for _, ch := range s.chans {
+		if ch != nil {
+			for range ch {
+			}
+		}
+	}
+}
+
+// lineSource reads the files of a CSV or JSON lines recording one after
+// the other; parse turns each line into records.
+type lineSource struct {
+	paths   []string
+	current io.ReadCloser
+	reader  *bufio.Reader
+	parse   func(line []byte) ([]record, error)
+	pending []record
+	skipped map[string]int // lines per venue that could not be parsed
+}
+
+func (s *lineSource) next() (record, error) {
+	for len(s.pending) == 0 {
+		if s.reader == nil {
+			if len(s.paths) == 0 {
+				return record{}, io.EOF
+			}
+			f, err := openFile(s.paths[0])
+			if err != nil {
+				return record{}, err
+			}
+			s.paths = s.paths[1:]
+			s.current, s.reader = f, bufio.NewReaderSize(f, 1<<16)
+		}
+		line, err := s.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			recs, perr := s.parse(line)
+			if perr != nil {
+				return record{}, perr
+			}
+			s.pending = recs
+		}
+		if err == io.EOF {
+			s.current.Close()
+			s.current, s.reader = nil, nil
+		} else if err != nil {
+			return record{}, err
+		}
+	}
+	r := s.pending[0]
+	s.pending = s.pending[1:]
+	return r, nil
+}
+
+func (s *lineSource) close() {
+	if s.current != nil {
+		s.current.Close()
+	}
+	for venue, n := range s.skipped {
+		log.Printf("⚠️  Replay skipped %d %q frames: no parser for that venue", n, venue)
+	}
+}
+
+// openCSV reads ticks from CSV files with a header row naming the columns.
+// time is RFC 3339 or Unix milliseconds; symbol and price are required.
+func openCSV(pattern string) (source, error) {
+	paths, err := files(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var columns map[string]int
+	s := &lineSource{paths: paths}
+	s.parse = func(line []byte) ([]record, error) {
+		row, err := csv.NewReader(strings.NewReader(string(line))).Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		// Every file starts with its own header
+		if columns == nil || strings.EqualFold(strings.TrimSpace(row[0]), "time") {
+			columns = make(map[string]int)
+			for i, name := range row {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			for _, name := range []string{"time", "symbol", "price"} {
+				if _, ok := columns[name]; !ok {
+					return nil, fmt.Errorf("replay: CSV header lacks a %s column", name)
+				}
+			}
+			return nil, nil
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		t, err := parseTime(field("time"))
+		if err != nil {
+			return nil, err
+		}
+		symbol := ringbuffer.NormalizeSymbol(field("symbol"))
+		tick := ringbuffer.Tick{Symbol: symbol, Time: t, Side: field("side")}
+		if tick.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+			return nil, fmt.Errorf("replay: bad price %q", field("price"))
+		}
+		tick.Size, _ = strconv.ParseFloat(field("size"), 64)
+		tick.Sequence, _ = strconv.ParseInt(field("sequence"), 10, 64)
+		venue := field("venue")
+		if venue == "" {
+			venue = Name
+		}
+		ev := feed.Event{Kind: feed.EventTrade, Venue: venue, Symbol: symbol, Tick: tick, TradeID: tick.Sequence}
+		return []record{{at: t, ev: ev}}, nil
+	}
+	return s, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Time{}, fmt.Errorf("replay: bad time %q", s)
+}
+
+// parseFunc converts one raw frame into the events it carries.
+type parseFunc func(frame []byte, receivedAt time.Time) ([]feed.Event, error)
+
+// parsers make the frame parsers of the venues whose captures can be
+// replayed. Each source gets its own, since some keep state across frames.
+var parsers = map[string]func() parseFunc{
+	coinbase.Name: func() parseFunc { return new(coinbase.Parser).Parse },
+	binance.Name:  stateless(single(binance.Parse)),
+	kraken.Name:   func() parseFunc { return new(kraken.Parser).Parse },
+	bitstamp.Name: stateless(bitstamp.Parse),
+}
+
+// stateless shares one parser between all sources.
+func stateless(parse parseFunc) func() parseFunc {
+	return func() parseFunc { return parse }
+}
+
+// single adapts a parser for venues whose frames carry one event each.
+func single(parse func(frame []byte, receivedAt time.Time) (feed.Event, bool, error)) parseFunc {
+	return func(frame []byte, receivedAt time.Time) ([]feed.Event, error) {
+		ev, ok, err := parse(frame, receivedAt)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []feed.Event{ev}, nil
+	}
+}
+
+// openJSONL reads raw frames, either wrapped in capture records or bare, in
+// which case they are from venue and paced by their own timestamps. A
+// directory is read as a capture recorder's output. Frames of venues with
+// no parser are skipped and counted.
+func openJSONL(pattern, venue string) (source, error) {
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		pattern = capture.Pattern(pattern)
+	}
+	paths, err := files(pattern)
+	if err != nil {
+		return nil, err
+	}
+	s := &lineSource{paths: paths, skipped: make(map[string]int)}
+	venues := make(map[string]parseFunc)
+	s.parse = func(line []byte) ([]record, error) {
+		var c capture.Record
+		if err := json.Unmarshal(line, &c); err != nil {
+			return nil, nil
+		}
+		if len(c.Frame) == 0 {
+			c = capture.Record{Venue: venue, Frame: line}
+		}
+		parse, ok := venues[c.Venue]
+		if !ok {
+			newParser, listed := parsers[c.Venue]
+			if !listed {
+				s.skipped[c.Venue]++
+				return nil, nil
+			}
+			parse = newParser()
+			venues[c.Venue] = parse
+		}
+		events, err := parse(c.Frame, c.Received)
+		if err != nil {
+			// Unlisted products and malformed frames
+			return nil, nil
+		}
+		recs := make([]record, 0, len(events))
+		for _, ev := range events {
+			at := c.Received
+			if at.IsZero() {
+				at = eventTime(ev)
+			}
+			recs = append(recs, record{at: at, ev: ev})
+		}
+		return recs, nil
+	}
+	return s, nil
+}
+
+// eventTime is the exchange time of an event's payload.
+func eventTime(ev feed.Event) time.Time {
+	switch ev.Kind {
+	case feed.EventQuote:
+		return ev.Quote.Time
+	case feed.EventBook:
+		return ev.Book.Time
+	case feed.EventKline:
+		return ev.Kline.End
+	}
+	return ev.Tick.Time
+}
@@ -0,0 +1,104 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/capture"
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+// TestCaptureReplaysEveryVenue replays a capture written with the default
+// venues plus one that has no parser.
+func TestCaptureReplaysEveryVenue(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := capture.Open(dir, capture.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	frames := []struct{ venue, frame string }{
+		{"coinbase", `{"type":"subscriptions","channels":[]}`},
+		{"coinbase", `{"type":"match","trade_id":7,"product_id":"BTC-USD","price":"64000.5","size":"0.1","side":"sell","time":"2024-05-01T14:00:00.1Z"}`},
+		{"binance", `{"stream":"ethusdt@trade","data":{"e":"trade","E":1714572000200,"s":"ETHUSDT","t":9,"p":"3000.1","q":"2","T":1714572000200,"m":false}}`},
+		{"kraken", `{"channel":"heartbeat"}`},
+		{"kraken", `{"channel":"trade","type":"update","data":[` +
+			`{"symbol":"BTC/USD","side":"buy","price":64001,"qty":0.5,"trade_id":11,"timestamp":"2024-05-01T14:00:00.3Z"},` +
+			`{"symbol":"BTC/USD","side":"sell","price":64000,"qty":0.2,"trade_id":12,"timestamp":"2024-05-01T14:00:00.3Z"},` +
+			`{"symbol":"XRP/USD","side":"sell","price":0.5,"qty":10,"trade_id":3,"timestamp":"2024-05-01T14:00:00.3Z"}]}`},
+		{"kraken", `{"channel":"ticker","type":"update","data":[{"symbol":"ETH/USD","bid":2999,"bid_qty":1,"ask":3001,"ask_qty":2,"last":3000,"volume":100,"low":2900,"high":3100,"change":50}]}`},
+		{"bitstamp", `{"event":"bts:subscription_succeeded","channel":"live_trades_btcusd","data":{}}`},
+		{"bitstamp", `{"event":"trade","channel":"live_trades_btcusd","data":{"id":21,"amount":0.3,"price":64002,"type":1,"microtimestamp":"1714572000400000"}}`},
+		{"bitstamp", `{"event":"data","channel":"order_book_ethusd","data":{"microtimestamp":"1714572000500000","bids":[["2999.5","1.5"]],"asks":[["3000.5","2.5"]]}}`},
+		{"okx", `{"arg":{"channel":"trades"},"data":[]}`},
+		{"okx", `{"arg":{"channel":"trades"},"data":[]}`},
+	}
+	for i, f := range frames {
+		rec.Record(f.venue, at.Add(time.Duration(i)*time.Millisecond), []byte(f.frame))
+	}
+	rec.Close()
+
+	src, err := openJSONL(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []feed.Event
+	for {
+		r, err := src.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		got = append(got, r.ev)
+	}
+	skipped := src.(*lineSource).skipped
+	src.close()
+
+	want := []struct {
+		venue  string
+		kind   feed.EventKind
+		symbol string
+		price  float64
+	}{
+		{"coinbase", feed.EventTrade, "btcusdt", 64000.5},
+		{"binance", feed.EventTrade, "ethusdt", 3000.1},
+		{"kraken", feed.EventTrade, "btcusdt", 64001},
+		{"kraken", feed.EventTrade, "btcusdt", 64000},
+		{"kraken", feed.EventQuote, "ethusdt", 2999},
+		{"bitstamp", feed.EventTrade, "btcusdt", 64002},
+		{"bitstamp", feed.EventQuote, "ethusdt", 2999.5},
+		{"bitstamp", feed.EventBook, "ethusdt", 2999.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("replayed %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		ev := got[i]
+		price := ev.Tick.Price
+		switch ev.Kind {
+		case feed.EventQuote:
+			price = ev.Quote.Bid
+		case feed.EventBook:
+			price = ev.Book.Bids[0].Price
+		}
+		if ev.Venue != w.venue || ev.Kind != w.kind || ev.Symbol != w.symbol || price != w.price {
+			t.Errorf("event %d: %s %v %s %g, want %s %v %s %g", i, ev.Venue, ev.Kind, ev.Symbol, price, w.venue, w.kind, w.symbol, w.price)
+		}
+	}
+	if got[2].TradeID != 11 || got[2].Tick.Side != "buy" {
+		t.Errorf("kraken trade %+v", got[2])
+	}
+	if got[5].Tick.Side != "sell" || !got[5].Tick.Time.Equal(time.UnixMicro(1714572000400000)) {
+		t.Errorf("bitstamp trade %+v", got[5].Tick)
+	}
+	if got[4].Day.Open != 2950 {
+		t.Errorf("kraken day open %g, want 2950", got[4].Day.Open)
+	}
+	if len(skipped) != 1 || skipped["okx"] != 2 {
+		t.Errorf("skipped %v, want 2 okx frames", skipped)
+	}
+}
@@ -1,39 +1,92 @@
 package ringbuffer
 
 import (
+	"strings"
 	"sync"
 )
 
-type RingBuffer struct {
-	data       []float64
+// Buffer is the common interface of the mutex-guarded RingBuffer and the
+// LockFreeRingBuffer.
+type Buffer[T any] interface {
+	Write(v T)
+	ReadLast(n int) []T
+	ReadLastInto(dst []T) int
+	Visit(n int, fn func(i int, v *T)) int
+	ReadFrom(seq uint64, dst []T) (n int, next uint64, missed uint64)
+	GetLast() (T, bool)
+	GetSequence() uint64
+	GetWriteIndex() int
+	GetCount() int
+	GetSize() int
+}
+
+// Mode selects the Buffer implementation at construction time.
+type Mode int
+
+const (
+	// Mutex guards every read and write with a sync.RWMutex.
+	Mutex Mode = iota
+	// LockFree uses atomic sequence counters; only one goroutine may write.
+	// It covers the ring alone: a TickBuffer's rolling statistics and gap
+	// records stay behind short mutexes of their own, which WriteTick and
+	// the readers of those views (GetStats, GetQuality, GetGaps) share.
+	// Tick reads, cursors and subscriptions never touch them.
+	LockFree
+)
+
+// ParseMode maps a config value ("mutex", "lockfree") to a Mode, defaulting
+// to Mutex.
+func ParseMode(s string) Mode {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "lockfree", "lock-free":
+		return LockFree
+	default:
+		return Mutex
+	}
+}
+
+func NewBuffer[T any](size int, mode Mode) Buffer[T] {
+	if mode == LockFree {
+		return NewLockFreeRingBuffer[T](size)
+	}
+	return NewRingBuffer[T](size)
+}
+
+// RingBuffer is a fixed-capacity circular buffer of T. Writes overwrite the
+// oldest element once the buffer is full.
+type RingBuffer[T any] struct {
+	data       []T
 	writeIndex int
 	size       int
 	count      int
+	seq        uint64 // total writes; sequence number of the next write
 	mu         sync.RWMutex
 }
 
-func New(size int) *RingBuffer {
-	return &RingBuffer{
-		data:       make([]float64, size),
+func NewRingBuffer[T any](size int) *RingBuffer[T] {
+	return &RingBuffer[T]{
+		data:       make([]T, size),
 		size:       size,
 		writeIndex: 0,
 		count:      0,
 	}
 }
 
-func (rb *RingBuffer) Write(price float64) {
+func (rb *RingBuffer[T]) Write(v T) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
-	rb.data[rb.writeIndex] = price
+	rb.data[rb.writeIndex] = v
 	rb.writeIndex = (rb.writeIndex + 1) % rb.size
 
 	if rb.count < rb.size {
 		rb.count++
 	}
+	rb.seq++
 }
 
-func (rb *RingBuffer) ReadLast(n int) []float64 {
+// ReadLast returns a copy of the last n elements, oldest first.
+func (rb *RingBuffer[T]) ReadLast(n int) []T {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
 
@@ -41,41 +94,141 @@ func (rb *RingBuffer) ReadLast(n int) []float64 {
 		n = rb.count
 	}
 
-	result := make([]float64, n)
-	startIdx := (rb.writeIndex - n + rb.size) % rb.size
+	result := make([]T, n)
+	rb.copyLast(result)
+	return result
+}
 
-	for i := 0; i < n; i++ {
-		idx := (startIdx + i) % rb.size
-		result[i] = rb.data[idx]
-	}
+// ReadLastInto fills dst with the last len(dst) elements, oldest first, and
+// returns how many were written. It does not allocate, so hot-path callers
+// should keep dst around between calls.
+func (rb *RingBuffer[T]) ReadLastInto(dst []T) int {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
 
-	return result
+	n := len(dst)
+	if n > rb.count {
+		n = rb.count
+	}
+	return rb.copyLast(dst[:n])
 }
 
-func (rb *RingBuffer) GetWriteIndex() int {
+// Visit calls fn for each of the last n elements, oldest first, under the
+// read lock and returns how many were visited.
+func (rb *RingBuffer[T]) Visit(n int, fn func(i int, v *T)) int {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
-	return rb.writeIndex
+
+	if n > rb.count {
+		n = rb.count
+	}
+	startIdx := (rb.writeIndex - n + rb.size) % rb.size
+	for i := 0; i < n; i++ {
+		fn(i, &rb.data[(startIdx+i)%rb.size])
+	}
+	return n
 }
 
-func (rb *RingBuffer) GetCount() int {
+// ReadFrom copies elements starting at sequence number seq into dst, oldest
+// first. It returns how many were copied, the sequence number to pass on the
+// next call, and how many elements in [seq, oldest retained) were already
+// overwritten before they could be read.
+func (rb *RingBuffer[T]) ReadFrom(seq uint64, dst []T) (n int, next uint64, missed uint64) {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
-	return rb.count
+
+	start, k, missed := readRange(seq, rb.seq, rb.count, len(dst))
+	startIdx := (rb.writeIndex - int(rb.seq-start) + rb.size) % rb.size
+	for i := 0; i < k; i++ {
+		dst[i] = rb.data[(startIdx+i)%rb.size]
+	}
+	return k, start + uint64(k), missed
 }
 
-func (rb *RingBuffer) GetSize() int {
-	return rb.size
+// Segments calls fn with the last n elements as at most two contiguous
+// slices of the backing array (older first), without copying. fn runs under
+// the read lock: it must not retain the slices or write to the buffer.
+func (rb *RingBuffer[T]) Segments(n int, fn func(older, newer []T)) {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if n > rb.count {
+		n = rb.count
+	}
+	older, newer := rb.segments(n)
+	fn(older, newer)
 }
 
-func (rb *RingBuffer) GetCurrentPrice() float64 {
+// GetLast returns the most recent element and false if the buffer is empty.
+func (rb *RingBuffer[T]) GetLast() (T, bool) {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
 
 	if rb.count == 0 {
-		return 0
+		var zero T
+		return zero, false
 	}
 
 	lastIdx := (rb.writeIndex - 1 + rb.size) % rb.size
-	return rb.data[lastIdx]
+	return rb.data[lastIdx], true
+}
+
+// GetSequence returns the total number of writes, i.e. the sequence number
+// the next write will get.
+func (rb *RingBuffer[T]) GetSequence() uint64 {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.seq
+}
+
+func (rb *RingBuffer[T]) GetWriteIndex() int {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.writeIndex
+}
+
+func (rb *RingBuffer[T]) GetCount() int {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.count
+}
+
+func (rb *RingBuffer[T]) GetSize() int {
+	return rb.size
+}
+
+// segments splits the last n elements into the part before the wrap point
+// and the part after it. Callers must hold the lock and ensure n <= count.
+func (rb *RingBuffer[T]) segments(n int) (older, newer []T) {
+	startIdx := (rb.writeIndex - n + rb.size) % rb.size
+	if startIdx+n <= rb.size {
+		return rb.data[startIdx : startIdx+n], nil
+	}
+	return rb.data[startIdx:], rb.data[:rb.writeIndex]
+}
+
+func (rb *RingBuffer[T]) copyLast(dst []T) int {
+	older, newer := rb.segments(len(dst))
+	n := copy(dst, older)
+	return n + copy(dst[n:], newer)
+}
+
+// readRange clamps a read starting at seq to what is still retained behind
+// head and to the caller's capacity. It returns the first sequence number to
+// read, how many elements to read and how many were lost to overwrites.
+func readRange(seq, head uint64, count, capacity int) (start uint64, n int, missed uint64) {
+	oldest := head - uint64(count)
+	start = seq
+	if start < oldest {
+		missed = oldest - start
+		start = oldest
+	}
+	if start > head {
+		start = head
+	}
+	n = int(head - start)
+	if n > capacity {
+		n = capacity
+	}
+	return start, n, missed
 }
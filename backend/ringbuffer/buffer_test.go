@@ -0,0 +1,134 @@
+package ringbuffer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRingBufferWraps(t *testing.T) {
+	rb := NewRingBuffer[int](5)
+	for i := 0; i < 7; i++ {
+		rb.Write(i)
+	}
+
+	dst := make([]int, 8)
+	if n := rb.ReadLastInto(dst); fmt.Sprint(dst[:n]) != "[2 3 4 5 6]" {
+		t.Errorf("ReadLastInto %v", dst[:n])
+	}
+	if n := rb.ReadLastInto(dst[:2]); fmt.Sprint(dst[:n]) != "[5 6]" {
+		t.Errorf("ReadLastInto short %v", dst[:n])
+	}
+
+	tests := []struct {
+		n            int
+		older, newer string
+	}{
+		{5, "[2 3 4]", "[5 6]"},
+		{2, "[5 6]", "[]"},
+		{3, "[4]", "[5 6]"},
+		{9, "[2 3 4]", "[5 6]"},
+		{0, "[]", "[]"},
+	}
+	for _, tt := range tests {
+		rb.Segments(tt.n, func(older, newer []int) {
+			if fmt.Sprint(older) != tt.older || fmt.Sprint(newer) != tt.newer {
+				t.Errorf("Segments(%d) = %v %v, want %s %s", tt.n, older, newer, tt.older, tt.newer)
+			}
+		})
+	}
+}
+
+func TestReadsDoNotAllocate(t *testing.T) {
+	for _, mode := range []Mode{Mutex, LockFree} {
+		tb := NewTickBuffer(Options{Size: 64, Mode: mode})
+		for i := 0; i < 100; i++ {
+			tb.WriteTick(testTick(i))
+		}
+		prices, ticks := make([]float64, 32), make([]Tick, 32)
+		var seq uint64
+		allocs := testing.AllocsPerRun(1000, func() {
+			tb.ReadLastInto(prices)
+			tb.ReadLastTicksInto(ticks)
+			tb.GetLastTick()
+			tb.GetCurrentPrice()
+			_, seq, _ = tb.ring.ReadFrom(seq-8, ticks)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: reads allocate %.1f times per pass", modeName(mode), allocs)
+		}
+	}
+
+	rb := NewRingBuffer[Tick](64)
+	for i := 0; i < 100; i++ {
+		rb.Write(testTick(i))
+	}
+	var sum float64
+	sumPrices := func(older, newer []Tick) {
+		for i := range older {
+			sum += older[i].Price
+		}
+		for i := range newer {
+			sum += newer[i].Price
+		}
+	}
+	if allocs := testing.AllocsPerRun(1000, func() { rb.Segments(50, sumPrices) }); allocs != 0 {
+		t.Errorf("Segments allocates %.1f times per call", allocs)
+	}
+}
+
+func BenchmarkReadLastInto(b *testing.B) {
+	for _, mode := range []Mode{Mutex, LockFree} {
+		for _, n := range []int{10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/n=%d", modeName(mode), n), func(b *testing.B) {
+				tb := NewTickBuffer(Options{Size: 1000, Mode: mode})
+				for i := 0; i < 1500; i++ {
+					tb.WriteTick(testTick(i))
+				}
+				prices := make([]float64, n)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					tb.ReadLastInto(prices)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkReadLast is the allocating baseline for BenchmarkReadLastInto.
+func BenchmarkReadLast(b *testing.B) {
+	tb := NewTickBuffer(Options{Size: 1000})
+	for i := 0; i < 1500; i++ {
+		tb.WriteTick(testTick(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tb.ReadLast(100)
+	}
+}
+
+func BenchmarkSegments(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			rb := NewRingBuffer[Tick](1000)
+			for i := 0; i < 1500; i++ {
+				rb.Write(testTick(i))
+			}
+			var sum float64
+			sumPrices := func(older, newer []Tick) {
+				for i := range older {
+					sum += older[i].Price
+				}
+				for i := range newer {
+					sum += newer[i].Price
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rb.Segments(n, sumPrices)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package ringbuffer
+
+// Cursor tracks one consumer's position in a Buffer so that each element is
+// delivered exactly once. A Cursor is not safe for concurrent use; give each
+// consumer its own.
+type Cursor[T any] struct {
+	buf    Buffer[T]
+	next   uint64
+	missed uint64
+}
+
+// NewCursor returns a cursor positioned at the oldest element still retained,
+// so the first Read returns everything currently buffered.
+func NewCursor[T any](buf Buffer[T]) *Cursor[T] {
+	// An empty read from 0 resolves the oldest retained sequence number
+	// from a single consistent snapshot of the buffer.
+	_, oldest, _ := buf.ReadFrom(0, nil)
+	return &Cursor[T]{buf: buf, next: oldest}
+}
+
+// Read copies the elements written since the previous Read into dst and
+// returns how many were copied. missed is the number of elements that were
+// overwritten before this consumer got to them (the reader was lapped). If
+// dst fills up, the remainder is returned by the next Read.
+func (c *Cursor[T]) Read(dst []T) (n int, missed uint64) {
+	n, c.next, missed = c.buf.ReadFrom(c.next, dst)
+	c.missed += missed
+	return n, missed
+}
+
+// Pending reports how many elements are waiting to be read, including any
+// that have already been overwritten.
+func (c *Cursor[T]) Pending() uint64 {
+	return c.buf.GetSequence() - c.next
+}
+
+// SeekLatest skips everything written so far.
+func (c *Cursor[T]) SeekLatest() {
+	c.next = c.buf.GetSequence()
+}
+
+// Sequence returns the sequence number of the next element to be read.
+func (c *Cursor[T]) Sequence() uint64 {
+	return c.next
+}
+
+// Missed returns the total number of elements lost to overwrites over the
+// cursor's lifetime.
+func (c *Cursor[T]) Missed() uint64 {
+	return c.missed
+}
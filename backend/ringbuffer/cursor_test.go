@@ -0,0 +1,83 @@
+package ringbuffer
+
+import "testing"
+
+// TestCursor runs the same script of writes and reads against both buffer
+// implementations and checks every Read against the expected values.
+func TestCursor(t *testing.T) {
+	type step struct {
+		write   int // values written before the read, continuing the count
+		dst     int // capacity of the read
+		want    []int
+		missed  uint64
+		next    uint64 // Sequence after the read
+		pending uint64 // Pending after the read
+	}
+	steps := []step{
+		{dst: 4, next: 0}, // empty buffer
+		{write: 3, dst: 4, want: []int{0, 1, 2}, next: 3},
+		{dst: 4, next: 3}, // nothing new
+		{write: 3, dst: 2, want: []int{3, 4}, next: 5, pending: 1}, // dst full
+		{dst: 4, want: []int{5}, next: 6},
+		// Lapped: 10 writes into 4 slots lose 6..11
+		{write: 10, dst: 8, want: []int{12, 13, 14, 15}, missed: 6, next: 16},
+		// Lapped with a short read: resumes right after what it copied
+		{write: 9, dst: 2, want: []int{21, 22}, missed: 5, next: 23, pending: 2},
+		{dst: 8, want: []int{23, 24}, next: 25},
+	}
+
+	for _, mode := range []Mode{Mutex, LockFree} {
+		buf := NewBuffer[int](4, mode)
+		c := NewCursor(buf)
+		written := 0
+		var lost uint64
+		for i, s := range steps {
+			for j := 0; j < s.write; j++ {
+				buf.Write(written)
+				written++
+			}
+			dst := make([]int, s.dst)
+			n, missed := c.Read(dst)
+			lost += missed
+			if !equalInts(dst[:n], s.want) || missed != s.missed {
+				t.Fatalf("mode %v step %d: read %v missed %d, want %v missed %d", mode, i, dst[:n], missed, s.want, s.missed)
+			}
+			if c.Sequence() != s.next || c.Pending() != s.pending || c.Missed() != lost {
+				t.Fatalf("mode %v step %d: sequence %d pending %d missed %d, want %d %d %d",
+					mode, i, c.Sequence(), c.Pending(), c.Missed(), s.next, s.pending, lost)
+			}
+		}
+	}
+}
+
+func TestCursorStartsAtOldest(t *testing.T) {
+	buf := NewBuffer[int](4, Mutex)
+	for i := 0; i < 6; i++ {
+		buf.Write(i)
+	}
+	// A new cursor has not missed what was overwritten before it existed
+	c := NewCursor(buf)
+	dst := make([]int, 8)
+	if n, missed := c.Read(dst); !equalInts(dst[:n], []int{2, 3, 4, 5}) || missed != 0 {
+		t.Errorf("read %v missed %d", dst[:n], missed)
+	}
+
+	c = NewCursor(buf)
+	c.SeekLatest()
+	buf.Write(6)
+	if n, _ := c.Read(dst); !equalInts(dst[:n], []int{6}) {
+		t.Errorf("read %v after SeekLatest", dst[:n])
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
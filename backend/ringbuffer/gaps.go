@@ -0,0 +1,147 @@
+package ringbuffer
+
+import (
+	"sync"
+	"time"
+)
+
+// Gap kinds.
+const (
+	// GapDisconnect is a feed connection loss, from the moment the feed
+	// noticed it until it reconnected.
+	GapDisconnect = "disconnect"
+	// GapSilence is a stretch between two consecutive ticks longer than the
+	// buffer's GapThreshold, including the hole left by a disconnect.
+	GapSilence = "silence"
+)
+
+// Gap is an interval in which the buffer may be missing ticks.
+type Gap struct {
+	Kind     string        `json:"kind"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Quality describes how trustworthy the newest window of ticks is.
+type Quality struct {
+	Connected bool          `json:"connected"`
+	Stale     bool          `json:"stale"`
+	Age       time.Duration `json:"age"` // since the latest tick was received
+	// TicksSinceGap is the number of ticks written since the most recent
+	// gap; a lookback of more ticks than this spans the gap. -1 if no gap
+	// has been recorded.
+	TicksSinceGap int `json:"ticksSinceGap"`
+}
+
+// SpansGap reports whether the last lookback ticks straddle a gap.
+func (q Quality) SpansGap(lookback int) bool {
+	return q.TicksSinceGap >= 0 && q.TicksSinceGap < lookback
+}
+
+const maxGaps = 64
+
+// gapTracker keeps the gap log and connection state of a TickBuffer. It is
+// updated by the single writer and read by any goroutine.
+type gapTracker struct {
+	threshold  time.Duration
+	staleAfter time.Duration
+	log        *RingBuffer[Gap]
+
+	mu             sync.Mutex
+	connected      bool
+	disconnectedAt time.Time
+	outage         bool // a disconnect happened since the last tick
+	lastTick       Tick
+	hasTick        bool
+	gapSeq         uint64 // sequence number of the first tick after the last gap
+	hasGap         bool
+}
+
+func newGapTracker(threshold, staleAfter time.Duration) gapTracker {
+	return gapTracker{
+		threshold:  threshold,
+		staleAfter: staleAfter,
+		log:        NewRingBuffer[Gap](maxGaps),
+	}
+}
+
+// observe is called with each tick before it is written at sequence seq.
+func (g *gapTracker) observe(tick Tick, seq uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.hasTick {
+		silence := tick.Time.Sub(g.lastTick.Time)
+		if g.outage || (g.threshold > 0 && silence > g.threshold) {
+			g.log.Write(Gap{Kind: GapSilence, From: g.lastTick.Time, To: tick.Time, Duration: silence})
+			g.gapSeq = seq
+			g.hasGap = true
+		}
+	}
+	g.outage = false
+	g.lastTick = tick
+	g.hasTick = true
+}
+
+// MarkDisconnected records that the feed for this buffer lost its
+// connection at t. The next tick written is treated as following a gap.
+// Buffers start out disconnected until the first MarkConnected.
+func (tb *TickBuffer) MarkDisconnected(t time.Time) {
+	g := &tb.gaps
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.connected && !g.disconnectedAt.IsZero() {
+		return
+	}
+	g.connected = false
+	g.disconnectedAt = t
+	g.outage = true
+}
+
+// MarkConnected records that the feed reconnected at t, logging the outage.
+func (tb *TickBuffer) MarkConnected(t time.Time) {
+	g := &tb.gaps
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.connected {
+		return
+	}
+	g.connected = true
+	// The very first connection is not an outage
+	if !g.disconnectedAt.IsZero() {
+		g.log.Write(Gap{Kind: GapDisconnect, From: g.disconnectedAt, To: t, Duration: t.Sub(g.disconnectedAt)})
+	}
+}
+
+// GetGaps returns the most recent gaps, oldest first.
+func (tb *TickBuffer) GetGaps() []Gap {
+	return tb.gaps.log.ReadLast(maxGaps)
+}
+
+// GetQuality reports connection state, staleness of the latest tick as of
+// now and how many ticks have arrived since the last gap.
+func (tb *TickBuffer) GetQuality(now time.Time) Quality {
+	g := &tb.gaps
+	seq := tb.ring.GetSequence()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	q := Quality{Connected: g.connected, TicksSinceGap: -1}
+	if g.hasTick {
+		q.Age = now.Sub(g.lastTick.ReceivedAt)
+	}
+	q.Stale = !g.hasTick || (g.staleAfter > 0 && q.Age > g.staleAfter)
+	if g.hasGap {
+		// seq was read before taking the lock, so the writer may already
+		// have observed a newer gap
+		q.TicksSinceGap = 0
+		if seq > g.gapSeq {
+			q.TicksSinceGap = int(seq - g.gapSeq)
+		}
+	}
+	return q
+}
@@ -0,0 +1,137 @@
+package ringbuffer
+
+import (
+	"testing"
+	"time"
+)
+
+var clock0 = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// tickAt is a tick stamped and received d after clock0.
+func tickAt(d time.Duration) Tick {
+	return Tick{Symbol: "btcusdt", Time: clock0.Add(d), ReceivedAt: clock0.Add(d), Price: 100, Size: 1}
+}
+
+func TestGapThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		silence   time.Duration
+		gap       bool
+	}{
+		{"below", 30 * time.Second, 29 * time.Second, false},
+		{"at", 30 * time.Second, 30 * time.Second, false},
+		{"above", 30 * time.Second, 30*time.Second + time.Millisecond, true},
+		{"disabled", 0, time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := NewTickBuffer(Options{Size: 16, GapThreshold: tt.threshold})
+			tb.WriteTick(tickAt(0))
+			tb.WriteTick(tickAt(tt.silence))
+			tb.WriteTick(tickAt(tt.silence + time.Second))
+
+			gaps := tb.GetGaps()
+			if (len(gaps) == 1) != tt.gap || len(gaps) > 1 {
+				t.Fatalf("gaps %+v, want gap %v", gaps, tt.gap)
+			}
+			q := tb.GetQuality(clock0.Add(tt.silence + time.Second))
+			if !tt.gap {
+				if q.TicksSinceGap != -1 || q.SpansGap(1000) {
+					t.Errorf("quality %+v without a gap", q)
+				}
+				return
+			}
+			want := Gap{Kind: GapSilence, From: clock0, To: clock0.Add(tt.silence), Duration: tt.silence}
+			if gaps[0] != want {
+				t.Errorf("gap %+v, want %+v", gaps[0], want)
+			}
+			// The ticks after the gap are its end and one more
+			if q.TicksSinceGap != 2 || !q.SpansGap(3) || q.SpansGap(2) {
+				t.Errorf("quality %+v, want 2 ticks since the gap", q)
+			}
+		})
+	}
+}
+
+func TestDisconnectGaps(t *testing.T) {
+	tb := NewTickBuffer(Options{Size: 16, GapThreshold: time.Minute})
+	if q := tb.GetQuality(clock0); q.Connected {
+		t.Error("buffer connected before its feed")
+	}
+
+	// The first connection is not an outage
+	tb.MarkConnected(clock0)
+	tb.WriteTick(tickAt(time.Second))
+	if gaps := tb.GetGaps(); len(gaps) != 0 {
+		t.Fatalf("gaps %+v after connecting", gaps)
+	}
+
+	// Repeated disconnects keep the first time
+	tb.MarkDisconnected(clock0.Add(2 * time.Second))
+	tb.MarkDisconnected(clock0.Add(3 * time.Second))
+	if q := tb.GetQuality(clock0.Add(4 * time.Second)); q.Connected {
+		t.Error("still connected after MarkDisconnected")
+	}
+	tb.MarkConnected(clock0.Add(7 * time.Second))
+	tb.MarkConnected(clock0.Add(8 * time.Second))
+
+	// A tick after an outage follows a gap however short the silence
+	tb.WriteTick(tickAt(8 * time.Second))
+	gaps := tb.GetGaps()
+	want := []Gap{
+		{Kind: GapDisconnect, From: clock0.Add(2 * time.Second), To: clock0.Add(7 * time.Second), Duration: 5 * time.Second},
+		{Kind: GapSilence, From: clock0.Add(time.Second), To: clock0.Add(8 * time.Second), Duration: 7 * time.Second},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("gaps %+v, want %+v", gaps, want)
+	}
+	for i := range want {
+		if gaps[i] != want[i] {
+			t.Errorf("gap %d: %+v, want %+v", i, gaps[i], want[i])
+		}
+	}
+	if q := tb.GetQuality(clock0.Add(8 * time.Second)); !q.Connected || q.TicksSinceGap != 1 {
+		t.Errorf("quality %+v", q)
+	}
+}
+
+func TestStaleness(t *testing.T) {
+	tb := NewTickBuffer(Options{Size: 16, StaleAfter: 30 * time.Second})
+	if q := tb.GetQuality(clock0); !q.Stale {
+		t.Error("empty buffer not stale")
+	}
+
+	tb.WriteTick(tickAt(0))
+	steps := []struct {
+		now   time.Duration
+		write bool // a tick arrives at now first
+		stale bool
+	}{
+		{now: 0, stale: false},
+		{now: 30 * time.Second, stale: false},
+		{now: 30*time.Second + time.Millisecond, stale: true},
+		{now: 5 * time.Minute, stale: true},
+		{now: 5 * time.Minute, write: true, stale: false},
+		{now: 5*time.Minute + 31*time.Second, stale: true},
+	}
+	for _, s := range steps {
+		if s.write {
+			tb.WriteTick(tickAt(s.now))
+		}
+		q := tb.GetQuality(clock0.Add(s.now))
+		if q.Stale != s.stale {
+			t.Errorf("at %s: stale %v, want %v", s.now, q.Stale, s.stale)
+		}
+	}
+	if q := tb.GetQuality(clock0.Add(6 * time.Minute)); q.Age != 60*time.Second {
+		t.Errorf("age %s, want 1m", q.Age)
+	}
+
+	// StaleAfter 0 only flags an empty buffer
+	tb = NewTickBuffer(Options{Size: 16})
+	tb.WriteTick(tickAt(0))
+	if q := tb.GetQuality(clock0.Add(24 * time.Hour)); q.Stale {
+		t.Error("stale with the check disabled")
+	}
+}
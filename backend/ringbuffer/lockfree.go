@@ -0,0 +1,181 @@
+package ringbuffer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// LockFreeRingBuffer is a single-producer, multi-consumer ring buffer whose
+// readers never take a lock. Exactly one goroutine may call Write.
+//
+// Slots are allocated once and written in place, so writes do not allocate.
+// Each slot carries a seqlock stamp: the writer makes it odd before storing
+// the value and even again afterwards, encoding the sequence number it
+// holds. A reader copies the value out and accepts the copy only if the
+// stamp named the wanted sequence both before and after, so a torn read is
+// never returned; if a slot was overwritten, the whole read is retried
+// against the new head.
+type LockFreeRingBuffer[T any] struct {
+	slots   []lockFreeSlot[T]
+	head    atomic.Uint64 // sequence number of the next write
+	size    int
+	scratch sync.Pool // *T copies handed to Visit callbacks
+}
+
+type lockFreeSlot[T any] struct {
+	// stamp is 2*seq+1 while sequence seq is being written and 2*seq+2
+	// once it is complete; 0 means the slot was never written
+	stamp atomic.Uint64
+	value T
+}
+
+func NewLockFreeRingBuffer[T any](size int) *LockFreeRingBuffer[T] {
+	rb := &LockFreeRingBuffer[T]{
+		slots: make([]lockFreeSlot[T], size),
+		size:  size,
+	}
+	rb.scratch.New = func() interface{} { return new(T) }
+	return rb
+}
+
+func (rb *LockFreeRingBuffer[T]) Write(v T) {
+	seq := rb.head.Load()
+	slot := &rb.slots[seq%uint64(rb.size)]
+	slot.stamp.Store(2*seq + 1)
+	copyRacy(&slot.value, &v)
+	slot.stamp.Store(2*seq + 2)
+	rb.head.Store(seq + 1)
+}
+
+// read copies sequence seq into dst and reports whether the copy is
+// consistent. It is not if the slot no longer holds seq or was rewritten
+// while being copied; dst then holds garbage and must be discarded.
+func (rb *LockFreeRingBuffer[T]) read(seq uint64, dst *T) bool {
+	slot := &rb.slots[seq%uint64(rb.size)]
+	want := 2*seq + 2
+	if slot.stamp.Load() != want {
+		return false
+	}
+	copyRacy(dst, &slot.value)
+	return slot.stamp.Load() == want
+}
+
+// copyRacy is the one place a slot's value is accessed without
+// synchronization. The stamps make a torn copy detectable, so the race is
+// benign, and the race detector is told to ignore it.
+//
+//go:norace
+func copyRacy[T any](dst, src *T) {
+	*dst = *src
+}
+
+func (rb *LockFreeRingBuffer[T]) ReadLast(n int) []T {
+	if count := rb.GetCount(); n > count {
+		n = count
+	}
+	result := make([]T, n)
+	return result[:rb.ReadLastInto(result)]
+}
+
+// ReadLastInto copies straight into dst. A retried pass never covers fewer
+// elements than the one before, so any torn copy is overwritten.
+func (rb *LockFreeRingBuffer[T]) ReadLastInto(dst []T) int {
+	for {
+		head := rb.head.Load()
+		k := len(dst)
+		if count := rb.count(head); k > count {
+			k = count
+		}
+		start := head - uint64(k)
+
+		consistent := true
+		for i := 0; i < k && consistent; i++ {
+			consistent = rb.read(start+uint64(i), &dst[i])
+		}
+		if consistent {
+			return k
+		}
+	}
+}
+
+// Visit calls fn for each of the last n elements, oldest first, with a
+// private copy of each. If a concurrent write laps the reader, the whole
+// pass is retried against the new head, so fn may see the same index more
+// than once and must only record v at position i.
+func (rb *LockFreeRingBuffer[T]) Visit(n int, fn func(i int, v *T)) int {
+	v := rb.scratch.Get().(*T)
+	defer rb.scratch.Put(v)
+
+	for {
+		head := rb.head.Load()
+		k := n
+		if count := rb.count(head); k > count {
+			k = count
+		}
+		start := head - uint64(k)
+
+		consistent := true
+		for i := 0; i < k; i++ {
+			if !rb.read(start+uint64(i), v) {
+				consistent = false
+				break
+			}
+			fn(i, v)
+		}
+		if consistent {
+			return k
+		}
+	}
+}
+
+func (rb *LockFreeRingBuffer[T]) ReadFrom(seq uint64, dst []T) (n int, next uint64, missed uint64) {
+	for {
+		head := rb.head.Load()
+		start, k, missed := readRange(seq, head, rb.count(head), len(dst))
+
+		consistent := true
+		for i := 0; i < k && consistent; i++ {
+			consistent = rb.read(start+uint64(i), &dst[i])
+		}
+		if consistent {
+			return k, start + uint64(k), missed
+		}
+	}
+}
+
+func (rb *LockFreeRingBuffer[T]) GetLast() (T, bool) {
+	var v T
+	for {
+		head := rb.head.Load()
+		if head == 0 {
+			var zero T
+			return zero, false
+		}
+		if rb.read(head-1, &v) {
+			return v, true
+		}
+	}
+}
+
+func (rb *LockFreeRingBuffer[T]) GetSequence() uint64 {
+	return rb.head.Load()
+}
+
+func (rb *LockFreeRingBuffer[T]) GetWriteIndex() int {
+	return int(rb.head.Load() % uint64(rb.size))
+}
+
+func (rb *LockFreeRingBuffer[T]) GetCount() int {
+	return rb.count(rb.head.Load())
+}
+
+func (rb *LockFreeRingBuffer[T]) GetSize() int {
+	return rb.size
+}
+
+func (rb *LockFreeRingBuffer[T]) count(head uint64) int {
+	if head < uint64(rb.size) {
+		return int(head)
+	}
+	return rb.size
+}
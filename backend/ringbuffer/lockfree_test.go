@@ -0,0 +1,226 @@
+package ringbuffer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTick builds a tick whose fields all derive from seq, so a reader can
+// tell a torn copy from a whole one.
+func testTick(seq int) Tick {
+	t := Tick{Price: float64(seq), Size: float64(seq) * 2, Sequence: int64(seq), Symbol: "btcusdt", Side: "buy"}
+	if seq%2 == 1 {
+		t.Symbol, t.Side = "ethusdt-with-a-longer-name", "sell"
+	}
+	return t
+}
+
+func checkTick(t Tick) error {
+	want := testTick(int(t.Sequence))
+	if t.Price != want.Price || t.Size != want.Size || t.Symbol != want.Symbol || t.Side != want.Side {
+		return fmt.Errorf("torn tick: %+v", t)
+	}
+	return nil
+}
+
+func TestLockFreeMatchesMutex(t *testing.T) {
+	for _, writes := range []int{0, 3, 8, 21} {
+		locked, free := NewRingBuffer[Tick](8), NewLockFreeRingBuffer[Tick](8)
+		for i := 0; i < writes; i++ {
+			locked.Write(testTick(i))
+			free.Write(testTick(i))
+		}
+
+		if got, want := free.GetCount(), locked.GetCount(); got != want {
+			t.Errorf("%d writes: count %d, want %d", writes, got, want)
+		}
+		if got, want := free.GetWriteIndex(), locked.GetWriteIndex(); got != want {
+			t.Errorf("%d writes: write index %d, want %d", writes, got, want)
+		}
+		if got, want := fmt.Sprint(free.ReadLast(5)), fmt.Sprint(locked.ReadLast(5)); got != want {
+			t.Errorf("%d writes: ReadLast %s, want %s", writes, got, want)
+		}
+		gotLast, gotOK := free.GetLast()
+		wantLast, wantOK := locked.GetLast()
+		if gotLast != wantLast || gotOK != wantOK {
+			t.Errorf("%d writes: GetLast %v %v, want %v %v", writes, gotLast, gotOK, wantLast, wantOK)
+		}
+
+		var visited []int64
+		free.Visit(4, func(i int, v *Tick) { visited = append(visited[:i], v.Sequence) })
+		var want []int64
+		locked.Visit(4, func(i int, v *Tick) { want = append(want[:i], v.Sequence) })
+		if fmt.Sprint(visited) != fmt.Sprint(want) {
+			t.Errorf("%d writes: Visit %v, want %v", writes, visited, want)
+		}
+
+		dstA, dstB := make([]Tick, 16), make([]Tick, 16)
+		n, next, missed := free.ReadFrom(2, dstA)
+		wn, wnext, wmissed := locked.ReadFrom(2, dstB)
+		if n != wn || next != wnext || missed != wmissed || fmt.Sprint(dstA[:n]) != fmt.Sprint(dstB[:wn]) {
+			t.Errorf("%d writes: ReadFrom %d %d %d, want %d %d %d", writes, n, next, missed, wn, wnext, wmissed)
+		}
+	}
+}
+
+func TestWriteTickDoesNotAllocate(t *testing.T) {
+	for _, mode := range []Mode{Mutex, LockFree} {
+		tb := NewTickBuffer(Options{Size: 64, Mode: mode})
+		tick := Tick{Time: time.Now(), Price: 100, Size: 1, Symbol: "btcusdt"}
+		allocs := testing.AllocsPerRun(1000, func() {
+			tick.Price++
+			tb.WriteTick(tick)
+		})
+		if allocs != 0 {
+			t.Errorf("mode %d: WriteTick allocates %.1f times per call", mode, allocs)
+		}
+	}
+}
+
+// TestLockFreeConcurrentReaders hammers one writer against many readers;
+// run it with -race. Every tick a reader gets back must be whole, and
+// multi-element reads must be consecutive.
+func TestLockFreeConcurrentReaders(t *testing.T) {
+	const writes = 200000
+	rb := NewLockFreeRingBuffer[Tick](64)
+
+	var (
+		done   atomic.Bool
+		wg     sync.WaitGroup
+		failed atomic.Value
+	)
+	fail := func(err error) {
+		failed.CompareAndSwap(nil, err)
+	}
+
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			dst := make([]Tick, 16)
+			var seq uint64
+			for !done.Load() {
+				switch r % 4 {
+				case 0:
+					n := rb.ReadLastInto(dst)
+					for i := 0; i < n; i++ {
+						if err := checkTick(dst[i]); err != nil {
+							fail(err)
+						}
+						if i > 0 && dst[i].Sequence != dst[i-1].Sequence+1 {
+							fail(fmt.Errorf("ReadLastInto not consecutive: %d after %d", dst[i].Sequence, dst[i-1].Sequence))
+						}
+					}
+				case 1:
+					var prev int64 = -1
+					rb.Visit(16, func(i int, v *Tick) {
+						if err := checkTick(*v); err != nil {
+							fail(err)
+						}
+						if i > 0 && v.Sequence != prev+1 {
+							// A retried pass restarts at i == 0
+							fail(fmt.Errorf("Visit not consecutive: %d after %d", v.Sequence, prev))
+						}
+						prev = v.Sequence
+					})
+				case 2:
+					n, next, _ := rb.ReadFrom(seq, dst)
+					for i := 0; i < n; i++ {
+						if err := checkTick(dst[i]); err != nil {
+							fail(err)
+						}
+					}
+					if n > 0 && uint64(dst[n-1].Sequence)+1 != next {
+						fail(fmt.Errorf("ReadFrom next %d after sequence %d", next, dst[n-1].Sequence))
+					}
+					seq = next
+				case 3:
+					if v, ok := rb.GetLast(); ok {
+						if err := checkTick(v); err != nil {
+							fail(err)
+						}
+					}
+				}
+			}
+		}(r)
+	}
+
+	for i := 0; i < writes; i++ {
+		rb.Write(testTick(i))
+	}
+	done.Store(true)
+	wg.Wait()
+
+	if err, _ := failed.Load().(error); err != nil {
+		t.Fatal(err)
+	}
+	if got := rb.GetSequence(); got != writes {
+		t.Fatalf("sequence %d, want %d", got, writes)
+	}
+}
+
+func BenchmarkWriteTick(b *testing.B) {
+	for _, mode := range []Mode{Mutex, LockFree} {
+		b.Run(modeName(mode), func(b *testing.B) {
+			tb := NewTickBuffer(Options{Size: 1000, Mode: mode})
+			tick := Tick{Time: time.Now(), Price: 100, Size: 1}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tb.WriteTick(tick)
+			}
+		})
+	}
+}
+
+// BenchmarkContendedRead measures reads by many goroutines while one
+// writer keeps writing, which is where the two modes differ.
+func BenchmarkContendedRead(b *testing.B) {
+	for _, mode := range []Mode{Mutex, LockFree} {
+		for _, readers := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("%s/readers=%d", modeName(mode), readers), func(b *testing.B) {
+				ring := NewBuffer[Tick](1000, mode)
+				for i := 0; i < 1000; i++ {
+					ring.Write(testTick(i))
+				}
+
+				stop := make(chan struct{})
+				var writer sync.WaitGroup
+				writer.Add(1)
+				go func() {
+					defer writer.Done()
+					for i := 0; ; i++ {
+						select {
+						case <-stop:
+							return
+						default:
+							ring.Write(testTick(i))
+						}
+					}
+				}()
+
+				b.ReportAllocs()
+				b.SetParallelism(readers)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					dst := make([]Tick, 50)
+					for pb.Next() {
+						ring.ReadLastInto(dst)
+					}
+				})
+				b.StopTimer()
+				close(stop)
+				writer.Wait()
+			})
+		}
+	}
+}
+
+func modeName(mode Mode) string {
+	if mode == LockFree {
+		return "lockfree"
+	}
+	return "mutex"
+}
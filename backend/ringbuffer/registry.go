@@ -0,0 +1,106 @@
+package ringbuffer
+
+import (
+	"strings"
+	"sync"
+)
+
+// Registry owns one TickBuffer per instrument, keyed by normalized symbol
+// (e.g. "btcusdt"), so ticks from different markets never share a buffer.
+type Registry struct {
+	buffers map[string]*TickBuffer
+	symbols []string
+	opts    Options
+	mu      sync.RWMutex
+}
+
+func NewRegistry(symbols []string, opts Options) *Registry {
+	r := &Registry{
+		buffers: make(map[string]*TickBuffer, len(symbols)),
+		opts:    opts,
+	}
+	for _, symbol := range symbols {
+		r.Add(symbol)
+	}
+	return r
+}
+
+// Add creates the buffer for symbol if it does not exist yet and returns it.
+func (r *Registry) Add(symbol string) *TickBuffer {
+	return r.Put(symbol, r.NewBuffer())
+}
+
+// NewBuffer creates a buffer with the registry's options without
+// registering it, so its consumers can be set up before Put publishes it.
+func (r *Registry) NewBuffer() *TickBuffer {
+	return NewTickBuffer(r.opts)
+}
+
+// Put registers buffer for symbol unless one exists already, and returns
+// the registered buffer.
+func (r *Registry) Put(symbol string, buffer *TickBuffer) *TickBuffer {
+	symbol = NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rb, ok := r.buffers[symbol]; ok {
+		return rb
+	}
+	r.buffers[symbol] = buffer
+	r.symbols = append(r.symbols, symbol)
+	return buffer
+}
+
+// Remove drops the buffer for symbol, reporting whether it existed. Readers
+// that already hold the buffer keep it, but it is no longer listed or
+// written by the router.
+func (r *Registry) Remove(symbol string) bool {
+	symbol = NormalizeSymbol(symbol)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.buffers[symbol]; !ok {
+		return false
+	}
+	delete(r.buffers, symbol)
+	for i, s := range r.symbols {
+		if s == symbol {
+			r.symbols = append(r.symbols[:i], r.symbols[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (r *Registry) Get(symbol string) (*TickBuffer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rb, ok := r.buffers[NormalizeSymbol(symbol)]
+	return rb, ok
+}
+
+// Symbols returns the registered symbols in registration order.
+func (r *Registry) Symbols() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, len(r.symbols))
+	copy(out, r.symbols)
+	return out
+}
+
+// Default returns the first registered symbol, used when a request does not
+// name one.
+func (r *Registry) Default() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.symbols) == 0 {
+		return ""
+	}
+	return r.symbols[0]
+}
+
+func NormalizeSymbol(symbol string) string {
+	return strings.ToLower(strings.TrimSpace(symbol))
+}
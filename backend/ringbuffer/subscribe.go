@@ -0,0 +1,101 @@
+package ringbuffer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hub fans written values out to subscribers. Delivery never blocks the
+// writer: if a subscriber's channel is full the value is dropped and counted.
+//
+// The subscriber list is copy-on-write behind an atomic pointer, so Publish
+// takes no lock and stays safe to call from the lock-free write path.
+type Hub[T any] struct {
+	subs    atomic.Pointer[[]*Subscription[T]]
+	dropped atomic.Uint64
+	mu      sync.Mutex // serializes Subscribe and Close
+}
+
+// Subscription is one consumer's channel of published values.
+type Subscription[T any] struct {
+	C       <-chan T
+	c       chan T
+	dropped atomic.Uint64
+	hub     *Hub[T]
+}
+
+// Subscribe registers a new subscriber with the given channel capacity. A
+// capacity of 1 gives coalesced wake-ups: bursts collapse into a single
+// pending value and the consumer reads the buffer for the actual data.
+func (h *Hub[T]) Subscribe(capacity int) *Subscription[T] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	c := make(chan T, capacity)
+	sub := &Subscription[T]{C: c, c: c, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var subs []*Subscription[T]
+	if cur := h.subs.Load(); cur != nil {
+		subs = append(subs, *cur...)
+	}
+	subs = append(subs, sub)
+	h.subs.Store(&subs)
+	return sub
+}
+
+func (h *Hub[T]) Publish(v T) {
+	cur := h.subs.Load()
+	if cur == nil {
+		return
+	}
+	for _, sub := range *cur {
+		select {
+		case sub.c <- v:
+		default:
+			sub.dropped.Add(1)
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers returns the number of active subscriptions.
+func (h *Hub[T]) Subscribers() int {
+	if cur := h.subs.Load(); cur != nil {
+		return len(*cur)
+	}
+	return 0
+}
+
+// Dropped returns the number of values dropped across all subscribers.
+func (h *Hub[T]) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// Close unsubscribes. The channel is left open because a concurrent Publish
+// may still hold the old subscriber list; it simply stops receiving values.
+func (s *Subscription[T]) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cur := h.subs.Load()
+	if cur == nil {
+		return
+	}
+	subs := make([]*Subscription[T], 0, len(*cur))
+	for _, other := range *cur {
+		if other != s {
+			subs = append(subs, other)
+		}
+	}
+	h.subs.Store(&subs)
+}
+
+// Dropped returns the number of values this subscriber missed because its
+// channel was full.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
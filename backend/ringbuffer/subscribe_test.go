@@ -0,0 +1,97 @@
+package ringbuffer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHubDropsForFullSubscribers(t *testing.T) {
+	var h Hub[int]
+	h.Publish(0) // no subscribers: nothing to drop
+	fast := h.Subscribe(8)
+	slow := h.Subscribe(1)
+	if h.Subscribers() != 2 {
+		t.Fatalf("%d subscribers", h.Subscribers())
+	}
+
+	// The slow subscriber never reads: it keeps the first value and the
+	// rest are dropped without holding up the fast one
+	for i := 1; i <= 5; i++ {
+		h.Publish(i)
+	}
+	for i := 1; i <= 5; i++ {
+		if v := <-fast.C; v != i {
+			t.Fatalf("fast subscriber got %d, want %d", v, i)
+		}
+	}
+	if v := <-slow.C; v != 1 {
+		t.Errorf("slow subscriber got %d, want 1", v)
+	}
+	if fast.Dropped() != 0 || slow.Dropped() != 4 || h.Dropped() != 4 {
+		t.Errorf("dropped fast %d slow %d hub %d, want 0 4 4", fast.Dropped(), slow.Dropped(), h.Dropped())
+	}
+
+	// Capacity 1 coalesces: after draining, the next burst leaves one value
+	h.Publish(6)
+	h.Publish(7)
+	if v := <-slow.C; v != 6 || slow.Dropped() != 5 {
+		t.Errorf("slow subscriber got %d with %d dropped", v, slow.Dropped())
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	var h Hub[int]
+	a := h.Subscribe(4)
+	b := h.Subscribe(4)
+	a.Close()
+	a.Close() // idempotent
+	if h.Subscribers() != 1 {
+		t.Fatalf("%d subscribers after Close", h.Subscribers())
+	}
+	h.Publish(1)
+	select {
+	case v := <-a.C:
+		t.Errorf("closed subscriber got %d", v)
+	default:
+	}
+	if v := <-b.C; v != 1 {
+		t.Errorf("open subscriber got %d", v)
+	}
+	if a.Dropped() != 0 || h.Dropped() != 0 {
+		t.Error("values for a closed subscriber counted as dropped")
+	}
+}
+
+// TestHubConcurrent publishes while subscribers come and go; run with -race.
+func TestHubConcurrent(t *testing.T) {
+	var h Hub[int]
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				h.Publish(i)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		sub := h.Subscribe(1)
+		select {
+		case <-sub.C:
+		case <-time.After(time.Second):
+			t.Fatal("no value delivered")
+		}
+		sub.Close()
+	}
+	close(stop)
+	wg.Wait()
+	if h.Subscribers() != 0 {
+		t.Errorf("%d subscribers left", h.Subscribers())
+	}
+}
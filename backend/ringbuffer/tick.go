@@ -0,0 +1,94 @@
+package ringbuffer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// Tick is a single trade print as received from an exchange feed.
+type Tick struct {
+	Symbol     string    `json:"symbol"`
+	Time       time.Time `json:"time"`       // exchange timestamp
+	ReceivedAt time.Time `json:"receivedAt"` // local receive timestamp
+	Price      float64   `json:"price"`
+	Size       float64   `json:"size"`
+	Side       string    `json:"side"`     // "buy", "sell" or "" if unknown
+	Sequence   int64     `json:"sequence"` // exchange sequence number, 0 if unknown
+}
+
+// Latency is the delay between the exchange timestamp and local receipt.
+func (t Tick) Latency() time.Duration {
+	if t.Time.IsZero() || t.ReceivedAt.IsZero() {
+		return 0
+	}
+	return t.ReceivedAt.Sub(t.Time)
+}
+
+// TickBinarySize is the length of a Tick's binary encoding.
+const TickBinarySize = 41
+
+var errShortTick = errors.New("ringbuffer: short tick encoding")
+
+// AppendBinary appends the fixed-size little-endian encoding of t (everything
+// but Symbol, which containers store once) to b.
+func (t Tick) AppendBinary(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(unixNano(t.Time)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(unixNano(t.ReceivedAt)))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(t.Price))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(t.Size))
+	b = append(b, encodeSide(t.Side))
+	b = binary.LittleEndian.AppendUint64(b, uint64(t.Sequence))
+	return b
+}
+
+// UnmarshalBinary decodes an encoding produced by AppendBinary. Symbol is
+// left untouched.
+func (t *Tick) UnmarshalBinary(b []byte) error {
+	if len(b) < TickBinarySize {
+		return errShortTick
+	}
+	t.Time = fromUnixNano(int64(binary.LittleEndian.Uint64(b[0:])))
+	t.ReceivedAt = fromUnixNano(int64(binary.LittleEndian.Uint64(b[8:])))
+	t.Price = math.Float64frombits(binary.LittleEndian.Uint64(b[16:]))
+	t.Size = math.Float64frombits(binary.LittleEndian.Uint64(b[24:]))
+	t.Side = decodeSide(b[32])
+	t.Sequence = int64(binary.LittleEndian.Uint64(b[33:]))
+	return nil
+}
+
+// unixNano maps the zero time to 0, which time.Time.UnixNano does not.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+func encodeSide(side string) uint8 {
+	switch side {
+	case "buy":
+		return 1
+	case "sell":
+		return 2
+	}
+	return 0
+}
+
+func decodeSide(side uint8) string {
+	switch side {
+	case 1:
+		return "buy"
+	case 2:
+		return "sell"
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package ringbuffer
+
+import (
+	"time"
+
+	"github.com/stahir80td/quantum-trader/stats"
+)
+
+// TickBuffer is the per-instrument tick store: a RingBuffer[Tick] plus the
+// price-only views the strategies consume.
+type TickBuffer struct {
+	ring  Buffer[Tick]
+	hub   Hub[Tick]
+	stats *stats.Tracker
+	gaps  gapTracker
+}
+
+// Options configures a TickBuffer.
+type Options struct {
+	Size int
+	Mode Mode
+	// GapThreshold is the tick-to-tick silence recorded as a data gap;
+	// 0 records only connection losses
+	GapThreshold time.Duration
+	// StaleAfter is how old the latest tick may be before the buffer is
+	// reported stale; 0 disables the check
+	StaleAfter time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Size:         1000,
+		Mode:         Mutex,
+		GapThreshold: 30 * time.Second,
+		StaleAfter:   30 * time.Second,
+	}
+}
+
+func New(size int) *TickBuffer {
+	opts := DefaultOptions()
+	opts.Size = size
+	return NewTickBuffer(opts)
+}
+
+func NewTickBuffer(opts Options) *TickBuffer {
+	return &TickBuffer{
+		ring:  NewBuffer[Tick](opts.Size, opts.Mode),
+		stats: stats.NewTracker(stats.DefaultConfig()),
+		gaps:  newGapTracker(opts.GapThreshold, opts.StaleAfter),
+	}
+}
+
+// Write stores a bare price, stamping it with the current time.
+func (tb *TickBuffer) Write(price float64) {
+	now := time.Now()
+	tb.WriteTick(Tick{Time: now, ReceivedAt: now, Price: price})
+}
+
+// WriteTick stores tick, updates the rolling statistics and then notifies
+// subscribers without blocking. The statistics and gap records are locked
+// even in LockFree mode; see Mode.
+func (tb *TickBuffer) WriteTick(tick Tick) {
+	tb.gaps.observe(tick, tb.ring.GetSequence())
+	tb.ring.Write(tick)
+	tb.stats.Push(tick.Price)
+	tb.hub.Publish(tick)
+}
+
+// GetStats returns the rolling statistics over the prices written so far.
+func (tb *TickBuffer) GetStats() stats.Snapshot {
+	return tb.stats.Snapshot()
+}
+
+// Subscribe returns a subscription that receives every tick written after
+// this call; see Hub.Subscribe for the meaning of capacity.
+func (tb *TickBuffer) Subscribe(capacity int) *Subscription[Tick] {
+	return tb.hub.Subscribe(capacity)
+}
+
+func (tb *TickBuffer) GetSubscribers() int {
+	return tb.hub.Subscribers()
+}
+
+// GetDropped returns how many notifications were dropped because a
+// subscriber fell behind.
+func (tb *TickBuffer) GetDropped() uint64 {
+	return tb.hub.Dropped()
+}
+
+// ReadLast returns the prices of the last n ticks, oldest first.
+func (tb *TickBuffer) ReadLast(n int) []float64 {
+	if count := tb.ring.GetCount(); n > count {
+		n = count
+	}
+	result := make([]float64, n)
+	return result[:tb.ReadLastInto(result)]
+}
+
+// ReadLastInto fills dst with the prices of the last len(dst) ticks, oldest
+// first, without allocating. It returns the number of prices written.
+func (tb *TickBuffer) ReadLastInto(dst []float64) int {
+	return tb.visit(len(dst), func(i int, t *Tick) {
+		dst[i] = t.Price
+	})
+}
+
+// ReadLastTicks returns the last n ticks, oldest first.
+func (tb *TickBuffer) ReadLastTicks(n int) []Tick {
+	return tb.ring.ReadLast(n)
+}
+
+// ReadLastTicksInto fills dst with the last len(dst) ticks without allocating.
+func (tb *TickBuffer) ReadLastTicksInto(dst []Tick) int {
+	return tb.ring.ReadLastInto(dst)
+}
+
+// ReadSince returns all buffered ticks whose exchange time is at or after t,
+// oldest first.
+func (tb *TickBuffer) ReadSince(t time.Time) []Tick {
+	ticks := tb.ring.ReadLast(tb.ring.GetSize())
+
+	// Ticks are appended in arrival order, so walk back from the newest
+	// until we cross t.
+	i := len(ticks)
+	for i > 0 && !ticks[i-1].Time.Before(t) {
+		i--
+	}
+	return ticks[i:]
+}
+
+// NewCursor returns a cursor over this buffer's ticks; see Cursor.
+func (tb *TickBuffer) NewCursor() *Cursor[Tick] {
+	return NewCursor[Tick](tb.ring)
+}
+
+// GetSequence returns the total number of ticks ever written.
+func (tb *TickBuffer) GetSequence() uint64 {
+	return tb.ring.GetSequence()
+}
+
+func (tb *TickBuffer) GetWriteIndex() int {
+	return tb.ring.GetWriteIndex()
+}
+
+func (tb *TickBuffer) GetCount() int {
+	return tb.ring.GetCount()
+}
+
+func (tb *TickBuffer) GetSize() int {
+	return tb.ring.GetSize()
+}
+
+func (tb *TickBuffer) GetCurrentPrice() float64 {
+	return tb.GetLastTick().Price
+}
+
+// GetOldestTick returns the oldest tick still retained and false if the
+// buffer is empty.
+func (tb *TickBuffer) GetOldestTick() (Tick, bool) {
+	// Reading from sequence 0 starts at the oldest retained tick
+	var oldest [1]Tick
+	n, _, _ := tb.ring.ReadFrom(0, oldest[:])
+	return oldest[0], n > 0
+}
+
+// GetLastTick returns the most recent tick, or the zero Tick if empty.
+func (tb *TickBuffer) GetLastTick() Tick {
+	tick, _ := tb.ring.GetLast()
+	return tick
+}
+
+// visit dispatches on the concrete buffer type so the compiler can keep fn
+// on the stack; calling Visit through the interface would make every
+// closure escape and allocate.
+func (tb *TickBuffer) visit(n int, fn func(i int, t *Tick)) int {
+	switch ring := tb.ring.(type) {
+	case *RingBuffer[Tick]:
+		return ring.Visit(n, fn)
+	case *LockFreeRingBuffer[Tick]:
+		return ring.Visit(n, fn)
+	}
+	panic("ringbuffer: unknown Buffer implementation")
+}
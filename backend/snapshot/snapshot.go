@@ -0,0 +1,207 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// File layout (little-endian):
+//
+//	magic    [4]byte  "QTSB"
+//	version  uint16
+//	created  int64    unix nanoseconds
+//	symbols  uint32
+//	per symbol:
+//	  name   uint16 length + bytes
+//	  ticks  uint32
+//	  per tick: ringbuffer.TickBinarySize bytes, see Tick.AppendBinary
+//	crc      uint32   CRC-32C of everything above
+const version = 1
+
+var magic = [4]byte{'Q', 'T', 'S', 'B'}
+
+var (
+	ErrBadMagic = errors.New("snapshot: not a ring buffer snapshot")
+	ErrVersion  = errors.New("snapshot: unsupported format version")
+	ErrChecksum = errors.New("snapshot: checksum mismatch")
+	ErrStale    = errors.New("snapshot: too old to restore")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Save writes every buffer in the registry to path. The file is written to
+// a temporary name and renamed into place so a crash never leaves a torn
+// snapshot behind.
+func Save(path string, buffers *ringbuffer.Registry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	crc := crc32.New(crcTable)
+	w := bufio.NewWriter(io.MultiWriter(tmp, crc))
+
+	// Resolve the buffers first: a symbol removed meanwhile is left out
+	var symbols []string
+	var tracked []*ringbuffer.TickBuffer
+	for _, symbol := range buffers.Symbols() {
+		if buffer, ok := buffers.Get(symbol); ok {
+			symbols = append(symbols, symbol)
+			tracked = append(tracked, buffer)
+		}
+	}
+	buf := make([]byte, 0, ringbuffer.TickBinarySize)
+	write(w, magic)
+	write(w, uint16(version))
+	write(w, time.Now().UnixNano())
+	write(w, uint32(len(symbols)))
+
+	for i, symbol := range symbols {
+		buffer := tracked[i]
+		ticks := buffer.ReadLastTicks(buffer.GetSize())
+
+		write(w, uint16(len(symbol)))
+		w.WriteString(symbol)
+		write(w, uint32(len(ticks)))
+		for _, t := range ticks {
+			buf = t.AppendBinary(buf[:0])
+			w.Write(buf)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := binary.Write(tmp, binary.LittleEndian, crc.Sum32()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load restores the snapshot at path into the registry's buffers and
+// returns the number of ticks restored. Snapshots older than maxAge are
+// rejected with ErrStale (maxAge <= 0 disables the check). Symbols that are
+// not registered are skipped. Call Load before any feed starts writing.
+func Load(path string, maxAge time.Duration, buffers *ringbuffer.Registry) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < len(magic)+4 {
+		return 0, ErrBadMagic
+	}
+
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if !bytes.Equal(body[:len(magic)], magic[:]) {
+		return 0, ErrBadMagic
+	}
+	if crc32.Checksum(body, crcTable) != sum {
+		return 0, ErrChecksum
+	}
+
+	r := &reader{r: bytes.NewReader(body[len(magic):])}
+
+	var ver uint16
+	var created int64
+	var symbols uint32
+	r.read(&ver)
+	if r.err == nil && ver != version {
+		return 0, fmt.Errorf("%w: %d", ErrVersion, ver)
+	}
+	r.read(&created)
+	if age := time.Since(time.Unix(0, created)); r.err == nil && maxAge > 0 && age > maxAge {
+		return 0, fmt.Errorf("%w: %s old", ErrStale, age.Round(time.Second))
+	}
+	r.read(&symbols)
+
+	restored := 0
+	for i := uint32(0); i < symbols && r.err == nil; i++ {
+		var nameLen uint16
+		r.read(&nameLen)
+		name := make([]byte, nameLen)
+		r.read(name)
+		symbol := string(name)
+
+		var count uint32
+		r.read(&count)
+
+		buffer, ok := buffers.Get(symbol)
+		buf := make([]byte, ringbuffer.TickBinarySize)
+		for j := uint32(0); j < count && r.err == nil; j++ {
+			r.read(buf)
+			if ok && r.err == nil {
+				tick := ringbuffer.Tick{Symbol: symbol}
+				tick.UnmarshalBinary(buf)
+				buffer.WriteTick(tick)
+				restored++
+			}
+		}
+	}
+	if r.err != nil {
+		return restored, fmt.Errorf("snapshot: truncated: %w", r.err)
+	}
+	return restored, nil
+}
+
+// Run saves a snapshot every interval until ctx is cancelled. The final
+// snapshot on shutdown is left to the caller, once writers have stopped.
+func Run(ctx context.Context, path string, interval time.Duration, buffers *ringbuffer.Registry) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Save(path, buffers); err != nil {
+			log.Printf("⚠️  Snapshot save failed: %v", err)
+		}
+	}
+}
+
+// write ignores errors; bufio.Writer latches the first one and Flush
+// reports it.
+func write(w io.Writer, v interface{}) {
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+// reader latches the first read error so decoding can stay linear.
+type reader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *reader) read(v interface{}) {
+	if r.err != nil {
+		return
+	}
+	if b, ok := v.([]byte); ok {
+		_, r.err = io.ReadFull(r.r, b)
+		return
+	}
+	r.err = binary.Read(r.r, binary.LittleEndian, v)
+}
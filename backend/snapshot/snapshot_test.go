@@ -0,0 +1,199 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var epoch = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// saved writes a snapshot of two symbols, btcusdt having wrapped its
+// buffer, and returns its path and the ticks it holds per symbol.
+func saved(t *testing.T) (string, map[string][]ringbuffer.Tick) {
+	t.Helper()
+	opts := ringbuffer.DefaultOptions()
+	opts.Size = 8
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt", "ethusdt"}, opts)
+	for i := 0; i < 11; i++ {
+		at := epoch.Add(time.Duration(i) * time.Second)
+		buffer, _ := buffers.Get("btcusdt")
+		buffer.WriteTick(ringbuffer.Tick{Symbol: "btcusdt", Time: at, ReceivedAt: at.Add(time.Millisecond), Price: 64000 + float64(i), Size: 0.5, Side: "buy", Sequence: int64(i + 1)})
+		if i < 3 {
+			buffer, _ = buffers.Get("ethusdt")
+			buffer.WriteTick(ringbuffer.Tick{Symbol: "ethusdt", Time: at, Price: 3000, Size: 2, Side: "sell"})
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "buffers.snap")
+	if err := Save(path, buffers); err != nil {
+		t.Fatal(err)
+	}
+	want := make(map[string][]ringbuffer.Tick)
+	for _, symbol := range buffers.Symbols() {
+		buffer, _ := buffers.Get(symbol)
+		want[symbol] = buffer.ReadLastTicks(buffer.GetSize())
+	}
+	return path, want
+}
+
+// rewrite changes a snapshot's body and reseals it with a valid checksum.
+func rewrite(t *testing.T, path string, change func(body []byte)) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := data[:len(data)-4]
+	change(body)
+	binary.LittleEndian.PutUint32(data[len(body):], crc32.Checksum(body, crcTable))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	path, want := saved(t)
+
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt", "ethusdt"}, ringbuffer.DefaultOptions())
+	n, err := Load(path, time.Minute, buffers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8+3 {
+		t.Errorf("restored %d ticks, want 11", n)
+	}
+	for symbol, ticks := range want {
+		buffer, _ := buffers.Get(symbol)
+		got := buffer.ReadLastTicks(100)
+		if len(got) != len(ticks) {
+			t.Fatalf("%s: %d ticks, want %d", symbol, len(got), len(ticks))
+		}
+		for i, w := range ticks {
+			g := got[i]
+			if g.Symbol != w.Symbol || !g.Time.Equal(w.Time) || !g.ReceivedAt.Equal(w.ReceivedAt) ||
+				g.Price != w.Price || g.Size != w.Size || g.Side != w.Side || g.Sequence != w.Sequence {
+				t.Errorf("%s tick %d: %+v, want %+v", symbol, i, g, w)
+			}
+		}
+	}
+}
+
+func TestLoadSkipsUnknownSymbols(t *testing.T) {
+	path, _ := saved(t)
+	buffers := ringbuffer.NewRegistry([]string{"ethusdt"}, ringbuffer.DefaultOptions())
+	n, err := Load(path, 0, buffers)
+	if err != nil || n != 3 {
+		t.Errorf("Load = %d, %v; want the 3 ethusdt ticks", n, err)
+	}
+	if _, ok := buffers.Get("btcusdt"); ok {
+		t.Error("Load registered btcusdt")
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		damage func(t *testing.T, path string)
+		want   error
+	}{
+		{
+			name: "flipped bit",
+			damage: func(t *testing.T, path string) {
+				data, _ := os.ReadFile(path)
+				data[40] ^= 1
+				os.WriteFile(path, data, 0o644)
+			},
+			want: ErrChecksum,
+		},
+		{
+			name: "truncated",
+			damage: func(t *testing.T, path string) {
+				data, _ := os.ReadFile(path)
+				os.WriteFile(path, data[:len(data)-10], 0o644)
+			},
+			want: ErrChecksum,
+		},
+		{
+			name: "not a snapshot",
+			damage: func(t *testing.T, path string) {
+				os.WriteFile(path, []byte("{\"ticks\":[]}"), 0o644)
+			},
+			want: ErrBadMagic,
+		},
+		{
+			name: "newer version",
+			damage: func(t *testing.T, path string) {
+				rewrite(t, path, func(body []byte) { binary.LittleEndian.PutUint16(body[4:], version+1) })
+			},
+			want: ErrVersion,
+		},
+		{
+			name:   "older than max age",
+			maxAge: time.Hour,
+			damage: func(t *testing.T, path string) {
+				created := time.Now().Add(-2 * time.Hour).UnixNano()
+				rewrite(t, path, func(body []byte) { binary.LittleEndian.PutUint64(body[6:], uint64(created)) })
+			},
+			want: ErrStale,
+		},
+		{
+			name:   "max age elapsed since saving",
+			maxAge: time.Nanosecond,
+			damage: func(t *testing.T, path string) { time.Sleep(time.Millisecond) },
+			want:   ErrStale,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := saved(t)
+			tt.damage(t, path)
+
+			buffers := ringbuffer.NewRegistry([]string{"btcusdt", "ethusdt"}, ringbuffer.DefaultOptions())
+			n, err := Load(path, tt.maxAge, buffers)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Load error %v, want %v", err, tt.want)
+			}
+			if n != 0 {
+				t.Errorf("restored %d ticks from a rejected snapshot", n)
+			}
+			buffer, _ := buffers.Get("btcusdt")
+			if buffer.GetCount() != 0 {
+				t.Errorf("rejected snapshot wrote %d ticks", buffer.GetCount())
+			}
+		})
+	}
+}
+
+func TestLoadAnyAgeWhenUnlimited(t *testing.T) {
+	path, _ := saved(t)
+	rewrite(t, path, func(body []byte) {
+		binary.LittleEndian.PutUint64(body[6:], uint64(time.Now().Add(-30*24*time.Hour).UnixNano()))
+	})
+	buffers := ringbuffer.NewRegistry([]string{"btcusdt"}, ringbuffer.DefaultOptions())
+	if n, err := Load(path, 0, buffers); err != nil || n != 8 {
+		t.Errorf("Load = %d, %v; want 8 ticks", n, err)
+	}
+}
+
+func TestSaveLeavesNoTempFiles(t *testing.T) {
+	path, _ := saved(t)
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(path) {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory holds %v, want only %s", names, filepath.Base(path))
+	}
+}
@@ -0,0 +1,222 @@
+package stats
+
+import "math"
+
+// Window keeps the rolling sum, mean and population variance of the last n
+// values. Push is O(1): the variance uses Welford's update with removal of
+// the value that falls out of the window.
+type Window struct {
+	values []float64
+	next   int
+	count  int
+	sum    float64
+	mean   float64
+	m2     float64
+}
+
+func NewWindow(n int) *Window {
+	return &Window{values: make([]float64, n)}
+}
+
+func (w *Window) Push(x float64) {
+	size := len(w.values)
+
+	if w.count < size {
+		w.count++
+		d := x - w.mean
+		w.mean += d / float64(w.count)
+		w.m2 += d * (x - w.mean)
+		w.sum += x
+	} else {
+		old := w.values[w.next]
+		oldMean := w.mean
+		w.mean += (x - old) / float64(size)
+		w.m2 += (x - old) * (x - w.mean + old - oldMean)
+		w.sum += x - old
+	}
+
+	w.values[w.next] = x
+	w.next = (w.next + 1) % size
+
+	// Rolling updates accumulate floating-point error; recompute exactly
+	// once per full pass over the window, which keeps Push amortized O(1).
+	if w.next == 0 && w.count == size {
+		w.recompute()
+	}
+}
+
+func (w *Window) recompute() {
+	sum := 0.0
+	for _, v := range w.values {
+		sum += v
+	}
+	mean := sum / float64(len(w.values))
+	m2 := 0.0
+	for _, v := range w.values {
+		d := v - mean
+		m2 += d * d
+	}
+	w.sum, w.mean, w.m2 = sum, mean, m2
+}
+
+// Full reports whether the window holds n values.
+func (w *Window) Full() bool {
+	return w.count == len(w.values)
+}
+
+func (w *Window) Count() int {
+	return w.count
+}
+
+func (w *Window) Sum() float64 {
+	return w.sum
+}
+
+func (w *Window) Mean() float64 {
+	return w.mean
+}
+
+// Variance is the population variance of the values in the window.
+func (w *Window) Variance() float64 {
+	if w.count == 0 || w.m2 < 0 {
+		return 0
+	}
+	return w.m2 / float64(w.count)
+}
+
+func (w *Window) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+// Oldest returns the value that the next Push will evict, i.e. the value
+// pushed n calls ago once the window is full.
+func (w *Window) Oldest() float64 {
+	if w.count < len(w.values) {
+		return w.values[0]
+	}
+	return w.values[w.next]
+}
+
+// MinMax tracks the minimum and maximum of the last n values with two
+// monotonic deques, giving amortized O(1) Push.
+type MinMax struct {
+	window int
+	seq    int
+	min    deque
+	max    deque
+}
+
+func NewMinMax(n int) *MinMax {
+	return &MinMax{
+		window: n,
+		min:    newDeque(n),
+		max:    newDeque(n),
+	}
+}
+
+func (m *MinMax) Push(x float64) {
+	// Evict entries that slid out of the window
+	for m.min.len > 0 && m.min.front().seq <= m.seq-m.window {
+		m.min.popFront()
+	}
+	for m.max.len > 0 && m.max.front().seq <= m.seq-m.window {
+		m.max.popFront()
+	}
+
+	// Anything no smaller (larger) than x can never be the min (max) again
+	for m.min.len > 0 && m.min.back().value >= x {
+		m.min.popBack()
+	}
+	for m.max.len > 0 && m.max.back().value <= x {
+		m.max.popBack()
+	}
+
+	m.min.pushBack(entry{seq: m.seq, value: x})
+	m.max.pushBack(entry{seq: m.seq, value: x})
+	m.seq++
+}
+
+// Min returns the smallest value in the window, or 0 if it is empty.
+func (m *MinMax) Min() float64 {
+	if m.min.len == 0 {
+		return 0
+	}
+	return m.min.front().value
+}
+
+// Max returns the largest value in the window, or 0 if it is empty.
+func (m *MinMax) Max() float64 {
+	if m.max.len == 0 {
+		return 0
+	}
+	return m.max.front().value
+}
+
+type entry struct {
+	seq   int
+	value float64
+}
+
+// deque is a fixed-capacity circular double-ended queue. A window of n never
+// holds more than n+1 entries between evictions, hence the extra slot.
+type deque struct {
+	buf  []entry
+	head int
+	len  int
+}
+
+func newDeque(n int) deque {
+	return deque{buf: make([]entry, n+1)}
+}
+
+func (d *deque) front() entry {
+	return d.buf[d.head]
+}
+
+func (d *deque) back() entry {
+	return d.buf[(d.head+d.len-1)%len(d.buf)]
+}
+
+func (d *deque) pushBack(e entry) {
+	d.buf[(d.head+d.len)%len(d.buf)] = e
+	d.len++
+}
+
+func (d *deque) popFront() {
+	d.head = (d.head + 1) % len(d.buf)
+	d.len--
+}
+
+func (d *deque) popBack() {
+	d.len--
+}
+
+// Wilder is Wilder's smoothed moving average: a simple average of the first
+// n values, then avg = (avg*(n-1) + x) / n.
+type Wilder struct {
+	period int
+	count  int
+	avg    float64
+}
+
+func NewWilder(n int) *Wilder {
+	return &Wilder{period: n}
+}
+
+func (w *Wilder) Push(x float64) {
+	if w.count < w.period {
+		w.count++
+		w.avg += (x - w.avg) / float64(w.count)
+		return
+	}
+	w.avg = (w.avg*float64(w.period-1) + x) / float64(w.period)
+}
+
+// Ready reports whether the seed period has been filled.
+func (w *Wilder) Ready() bool {
+	return w.count >= w.period
+}
+
+func (w *Wilder) Value() float64 {
+	return w.avg
+}
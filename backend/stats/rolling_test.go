@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// walk is a random walk around 100 with occasional flat steps and jumps,
+// the kind of series that trips up incremental updates.
+func walk(n int) []float64 {
+	r := rand.New(rand.NewSource(7))
+	prices := make([]float64, n)
+	price := 100.0
+	for i := range prices {
+		switch r.Intn(10) {
+		case 0: // unchanged
+		case 1:
+			price += (r.Float64() - 0.5) * 20
+		default:
+			price += (r.Float64() - 0.5) * 0.5
+		}
+		prices[i] = price
+	}
+	return prices
+}
+
+// window returns the last n values of prices[:end].
+func window(prices []float64, end, n int) []float64 {
+	if start := end - n; start > 0 {
+		return prices[start:end]
+	}
+	return prices[:end]
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestWindowMatchesRescan(t *testing.T) {
+	prices := walk(2000)
+	for _, n := range []int{1, 2, 14, 20, 50} {
+		w := NewWindow(n)
+		for i, x := range prices {
+			w.Push(x)
+			values := window(prices, i+1, n)
+
+			sum := 0.0
+			for _, v := range values {
+				sum += v
+			}
+			mean := sum / float64(len(values))
+			m2 := 0.0
+			for _, v := range values {
+				m2 += (v - mean) * (v - mean)
+			}
+			variance := m2 / float64(len(values))
+
+			if w.Count() != len(values) || w.Full() != (len(values) == n) {
+				t.Fatalf("n=%d push %d: count %d full %v", n, i, w.Count(), w.Full())
+			}
+			if !near(w.Sum(), sum) || !near(w.Mean(), mean) {
+				t.Fatalf("n=%d push %d: sum %g mean %g, want %g %g", n, i, w.Sum(), w.Mean(), sum, mean)
+			}
+			if math.Abs(w.Variance()-variance) > 1e-6 {
+				t.Fatalf("n=%d push %d: variance %g, want %g", n, i, w.Variance(), variance)
+			}
+			if i+1 >= n && i+1 < len(prices) && w.Oldest() != prices[i+1-n] {
+				t.Fatalf("n=%d push %d: oldest %g, want %g", n, i, w.Oldest(), prices[i+1-n])
+			}
+		}
+	}
+}
+
+func TestMinMaxMatchesRescan(t *testing.T) {
+	prices := walk(2000)
+	// Runs of equal values exercise the >= and <= evictions
+	prices = append(prices, 5, 5, 5, 5, 1, 1, 9, 9, 9)
+	for _, n := range []int{1, 2, 3, 49} {
+		m := NewMinMax(n)
+		if m.Min() != 0 || m.Max() != 0 {
+			t.Errorf("empty MinMax reports %g, %g", m.Min(), m.Max())
+		}
+		for i, x := range prices {
+			m.Push(x)
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, v := range window(prices, i+1, n) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+			if m.Min() != lo || m.Max() != hi {
+				t.Fatalf("n=%d push %d: min %g max %g, want %g %g", n, i, m.Min(), m.Max(), lo, hi)
+			}
+		}
+	}
+}
+
+func TestWilderMatchesRescan(t *testing.T) {
+	prices := walk(500)
+	const period = 14
+	w := NewWilder(period)
+	for i, x := range prices {
+		w.Push(x)
+
+		// Seed with the simple average of the first period values, then
+		// smooth every later one in
+		want := 0.0
+		seed := i + 1
+		if seed > period {
+			seed = period
+		}
+		for _, v := range prices[:seed] {
+			want += v
+		}
+		want /= float64(seed)
+		for _, v := range prices[seed : i+1] {
+			want = (want*(period-1) + v) / period
+		}
+
+		if w.Ready() != (i+1 >= period) {
+			t.Fatalf("push %d: ready %v", i, w.Ready())
+		}
+		if !near(w.Value(), want) {
+			t.Fatalf("push %d: %g, want %g", i, w.Value(), want)
+		}
+	}
+}
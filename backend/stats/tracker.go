@@ -0,0 +1,135 @@
+package stats
+
+import "sync"
+
+// Config sets the lookbacks a Tracker maintains. DefaultConfig matches the
+// periods used by package strategies.
+type Config struct {
+	MeanWindow     int // mean reversion SMA and standard deviation
+	BreakoutWindow int // breakout channel, including the current price
+	RSIPeriod      int
+	MomentumPeriod int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MeanWindow:     20,
+		BreakoutWindow: 50,
+		RSIPeriod:      14,
+		MomentumPeriod: 10,
+	}
+}
+
+// Snapshot is a consistent view of a Tracker's statistics after the most
+// recent price.
+type Snapshot struct {
+	Count int     `json:"count"` // prices seen
+	Last  float64 `json:"last"`
+
+	SMA    float64 `json:"sma"`
+	StdDev float64 `json:"stdDev"`
+
+	// High and Low span the BreakoutWindow-1 prices before Last
+	High float64 `json:"high"`
+	Low  float64 `json:"low"`
+
+	// Simple averages over the last RSIPeriod changes, and their
+	// Wilder-smoothed counterparts
+	AvgGain    float64 `json:"avgGain"`
+	AvgLoss    float64 `json:"avgLoss"`
+	WilderGain float64 `json:"wilderGain"`
+	WilderLoss float64 `json:"wilderLoss"`
+
+	// MomentumBase is the price MomentumPeriod ticks before Last; Ups and
+	// Downs count rising and falling changes over the same span
+	MomentumBase float64 `json:"momentumBase"`
+	Ups          int     `json:"ups"`
+	Downs        int     `json:"downs"`
+
+	Config Config `json:"-"`
+}
+
+// Tracker maintains the statistics the strategies need in O(1) per price,
+// so evaluating them no longer rescans the buffer.
+type Tracker struct {
+	cfg   Config
+	count int
+	last  float64
+
+	mean       *Window
+	prior      *MinMax
+	gains      *Window
+	losses     *Window
+	wilderGain *Wilder
+	wilderLoss *Wilder
+	momentum   *Window
+	ups        *Window
+	downs      *Window
+
+	mu sync.Mutex
+}
+
+func NewTracker(cfg Config) *Tracker {
+	return &Tracker{
+		cfg:        cfg,
+		mean:       NewWindow(cfg.MeanWindow),
+		prior:      NewMinMax(cfg.BreakoutWindow - 1),
+		gains:      NewWindow(cfg.RSIPeriod),
+		losses:     NewWindow(cfg.RSIPeriod),
+		wilderGain: NewWilder(cfg.RSIPeriod),
+		wilderLoss: NewWilder(cfg.RSIPeriod),
+		momentum:   NewWindow(cfg.MomentumPeriod + 1),
+		ups:        NewWindow(cfg.MomentumPeriod),
+		downs:      NewWindow(cfg.MomentumPeriod),
+	}
+}
+
+func (t *Tracker) Push(price float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.count > 0 {
+		change := price - t.last
+		gain, loss, up, down := 0.0, 0.0, 0.0, 0.0
+		if change > 0 {
+			gain, up = change, 1
+		} else if change < 0 {
+			loss, down = -change, 1
+		}
+		t.gains.Push(gain)
+		t.losses.Push(loss)
+		t.wilderGain.Push(gain)
+		t.wilderLoss.Push(loss)
+		t.ups.Push(up)
+		t.downs.Push(down)
+		t.prior.Push(t.last)
+	}
+
+	t.mean.Push(price)
+	t.momentum.Push(price)
+	t.last = price
+	t.count++
+}
+
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	period := float64(t.cfg.RSIPeriod)
+	return Snapshot{
+		Count:        t.count,
+		Last:         t.last,
+		SMA:          t.mean.Mean(),
+		StdDev:       t.mean.StdDev(),
+		High:         t.prior.Max(),
+		Low:          t.prior.Min(),
+		AvgGain:      t.gains.Sum() / period,
+		AvgLoss:      t.losses.Sum() / period,
+		WilderGain:   t.wilderGain.Value(),
+		WilderLoss:   t.wilderLoss.Value(),
+		MomentumBase: t.momentum.Oldest(),
+		Ups:          int(t.ups.Sum()),
+		Downs:        int(t.downs.Sum()),
+		Config:       t.cfg,
+	}
+}
@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// rsi computes the Wilder RSI of prices by rescanning them: the first
+// period changes seed the averages, later ones are smoothed in.
+func rsi(prices []float64, period int) float64 {
+	var gain, loss float64
+	for i := 1; i < len(prices); i++ {
+		change := prices[i] - prices[i-1]
+		g, l := math.Max(change, 0), math.Max(-change, 0)
+		if i <= period {
+			gain += (g - gain) / float64(i)
+			loss += (l - loss) / float64(i)
+			continue
+		}
+		gain = (gain*float64(period-1) + g) / float64(period)
+		loss = (loss*float64(period-1) + l) / float64(period)
+	}
+	if loss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+func TestTrackerMatchesRescan(t *testing.T) {
+	cfg := DefaultConfig()
+	prices := walk(1000)
+	tr := NewTracker(cfg)
+
+	for i, price := range prices {
+		tr.Push(price)
+		snap := tr.Snapshot()
+		seen := prices[:i+1]
+
+		if snap.Count != i+1 || snap.Last != price {
+			t.Fatalf("push %d: count %d last %g", i, snap.Count, snap.Last)
+		}
+		values := window(seen, len(seen), cfg.MeanWindow)
+		sma, sd := 0.0, 0.0
+		for _, v := range values {
+			sma += v / float64(len(values))
+		}
+		for _, v := range values {
+			sd += (v - sma) * (v - sma) / float64(len(values))
+		}
+		sd = math.Sqrt(sd)
+		if !near(snap.SMA, sma) || math.Abs(snap.StdDev-sd) > 1e-6 {
+			t.Fatalf("push %d: sma %g sd %g, want %g %g", i, snap.SMA, snap.StdDev, sma, sd)
+		}
+
+		// The breakout channel spans the prices before the current one
+		if i >= 1 {
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, v := range window(seen, i, cfg.BreakoutWindow-1) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+			if snap.Low != lo || snap.High != hi {
+				t.Fatalf("push %d: channel %g-%g, want %g-%g", i, snap.Low, snap.High, lo, hi)
+			}
+		}
+
+		if i < cfg.RSIPeriod {
+			continue
+		}
+		var gains, losses float64
+		ups, downs := 0, 0
+		for j := i - cfg.RSIPeriod + 1; j <= i; j++ {
+			change := prices[j] - prices[j-1]
+			gains += math.Max(change, 0)
+			losses += math.Max(-change, 0)
+		}
+		for j := i - cfg.MomentumPeriod + 1; j <= i; j++ {
+			if prices[j] > prices[j-1] {
+				ups++
+			} else if prices[j] < prices[j-1] {
+				downs++
+			}
+		}
+		period := float64(cfg.RSIPeriod)
+		if !near(snap.AvgGain, gains/period) || !near(snap.AvgLoss, losses/period) {
+			t.Fatalf("push %d: avg gain %g loss %g, want %g %g", i, snap.AvgGain, snap.AvgLoss, gains/period, losses/period)
+		}
+		if snap.MomentumBase != prices[i-cfg.MomentumPeriod] || snap.Ups != ups || snap.Downs != downs {
+			t.Fatalf("push %d: momentum %g %d/%d, want %g %d/%d", i, snap.MomentumBase, snap.Ups, snap.Downs, prices[i-cfg.MomentumPeriod], ups, downs)
+		}
+
+		got := 100.0
+		if snap.WilderLoss != 0 {
+			got = 100 - 100/(1+snap.WilderGain/snap.WilderLoss)
+		}
+		if want := rsi(seen, cfg.RSIPeriod); math.Abs(got-want) > 1e-6 {
+			t.Fatalf("push %d: Wilder RSI %g, want %g", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package strategies
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Update is one evaluation published by a symbol's strategy loop.
+type Update struct {
+	Symbol      string
+	Price       float64
+	BufferIndex int
+	Sequence    uint64 // the evaluation covers the ticks before this sequence number
+	Results     StrategyResults
+	At          time.Time
+}
+
+// Board keeps the latest Update per symbol, so that handlers serve what the
+// strategy loops computed on each tick instead of evaluating again, and
+// wakes the subscribers of a symbol whenever it publishes.
+type Board struct {
+	latest map[string]Update
+	hubs   map[string]*ringbuffer.Hub[struct{}]
+	mu     sync.RWMutex
+}
+
+func NewBoard() *Board {
+	return &Board{
+		latest: make(map[string]Update),
+		hubs:   make(map[string]*ringbuffer.Hub[struct{}]),
+	}
+}
+
+// Publish replaces the symbol's latest update and notifies its subscribers.
+func (b *Board) Publish(u Update) {
+	u.Symbol = ringbuffer.NormalizeSymbol(u.Symbol)
+	b.mu.Lock()
+	b.latest[u.Symbol] = u
+	hub := b.hub(u.Symbol)
+	b.mu.Unlock()
+	hub.Publish(struct{}{})
+}
+
+func (b *Board) Latest(symbol string) (Update, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	u, ok := b.latest[ringbuffer.NormalizeSymbol(symbol)]
+	return u, ok
+}
+
+// Subscribe returns coalesced wake-ups for symbol's updates; read Latest
+// for the update itself.
+func (b *Board) Subscribe(symbol string) *ringbuffer.Subscription[struct{}] {
+	b.mu.Lock()
+	hub := b.hub(ringbuffer.NormalizeSymbol(symbol))
+	b.mu.Unlock()
+	return hub.Subscribe(1)
+}
+
+// Remove forgets symbol's latest update. Its subscribers are no longer
+// woken.
+func (b *Board) Remove(symbol string) {
+	symbol = ringbuffer.NormalizeSymbol(symbol)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.latest, symbol)
+	delete(b.hubs, symbol)
+}
+
+// hub returns symbol's hub, creating it on first use. b.mu must be held.
+func (b *Board) hub(symbol string) *ringbuffer.Hub[struct{}] {
+	hub, ok := b.hubs[symbol]
+	if !ok {
+		hub = new(ringbuffer.Hub[struct{}])
+		b.hubs[symbol] = hub
+	}
+	return hub
+}
@@ -0,0 +1,42 @@
+package strategies
+
+import "testing"
+
+func TestBoard(t *testing.T) {
+	b := NewBoard()
+	if _, ok := b.Latest("btcusdt"); ok {
+		t.Fatal("empty board has an update")
+	}
+	sub := b.Subscribe("BTCUSDT")
+	defer sub.Close()
+	other := b.Subscribe("ethusdt")
+	defer other.Close()
+
+	b.Publish(Update{Symbol: "BTCUSDT", Sequence: 1, Results: StrategyResults{Consensus: "HOLD"}})
+	b.Publish(Update{Symbol: "btcusdt", Sequence: 2, Results: StrategyResults{Consensus: "BUY"}})
+
+	// Wake-ups are coalesced; the latest update is read from the board
+	select {
+	case <-sub.C:
+	default:
+		t.Fatal("subscriber not woken")
+	}
+	select {
+	case <-sub.C:
+		t.Error("wake-ups not coalesced")
+	default:
+	}
+	if u, ok := b.Latest("btcusdt"); !ok || u.Sequence != 2 || u.Symbol != "btcusdt" || u.Results.Consensus != "BUY" {
+		t.Errorf("latest %+v", u)
+	}
+	select {
+	case <-other.C:
+		t.Error("another symbol's subscriber woken")
+	default:
+	}
+
+	b.Remove("btcusdt")
+	if _, ok := b.Latest("btcusdt"); ok {
+		t.Error("removed symbol still has an update")
+	}
+}
@@ -0,0 +1,36 @@
+package strategies
+
+import "github.com/stahir80td/quantum-trader/orderbook"
+
+// BookLevels is how many levels per side the book summary looks at.
+const BookLevels = 10
+
+// BookSummary is the top of the order book alongside the signals.
+type BookSummary struct {
+	Venue     string  `json:"venue"`
+	Bid       float64 `json:"bid"`
+	Ask       float64 `json:"ask"`
+	Spread    float64 `json:"spread"`
+	SpreadBps float64 `json:"spreadBps"`
+	Imbalance float64 `json:"imbalance"` // -1 (all asks) to +1 (all bids)
+}
+
+// ApplyBook attaches a summary of book to results. Books that are out of
+// sync or one-sided are left out rather than reported wrongly.
+func ApplyBook(results StrategyResults, book orderbook.Snapshot) StrategyResults {
+	if !book.Synced || len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return results
+	}
+	summary := BookSummary{
+		Venue:     book.Venue,
+		Bid:       book.Bids[0].Price,
+		Ask:       book.Asks[0].Price,
+		Spread:    book.Spread(),
+		Imbalance: book.Imbalance(),
+	}
+	if mid := book.Mid(); mid > 0 {
+		summary.SpreadBps = summary.Spread / mid * 10000
+	}
+	results.Book = &summary
+	return results
+}
@@ -0,0 +1,55 @@
+package strategies
+
+import (
+	"fmt"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+// Lookback of each strategy in ticks, including the current one.
+const (
+	MeanReversionLookback = 20
+	MomentumLookback      = 11
+	BreakoutLookback      = 50
+	RSILookback           = 15
+)
+
+// ApplyQuality attaches the data quality to results and neutralizes the
+// signals it makes untrustworthy: all of them when the latest tick is stale,
+// and those whose lookback window spans a gap. Consensus is recomputed.
+func ApplyQuality(results StrategyResults, q ringbuffer.Quality) StrategyResults {
+	results.Quality = &q
+
+	if q.Stale {
+		reason := fmt.Sprintf("Suppressed: no ticks for %s", q.Age.Round(1e9))
+		results.MeanReversion = suppressed(reason)
+		results.Momentum = suppressed(reason)
+		results.Breakout = suppressed(reason)
+		results.RSI = suppressed(reason)
+	} else {
+		reason := fmt.Sprintf("Suppressed: lookback spans a data gap %d ticks ago", q.TicksSinceGap)
+		if q.SpansGap(MeanReversionLookback) {
+			results.MeanReversion = suppressed(reason)
+		}
+		if q.SpansGap(MomentumLookback) {
+			results.Momentum = suppressed(reason)
+		}
+		if q.SpansGap(BreakoutLookback) {
+			results.Breakout = suppressed(reason)
+		}
+		if q.SpansGap(RSILookback) {
+			results.RSI = suppressed(reason)
+		}
+	}
+
+	results.Consensus = GenerateConsensus(results)
+	return results
+}
+
+func suppressed(reason string) Signal {
+	return Signal{
+		Type:     "NEUTRAL",
+		Strength: 0,
+		Reason:   reason,
+	}
+}
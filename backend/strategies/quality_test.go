@@ -0,0 +1,83 @@
+package strategies
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+var clock0 = time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+// climb writes n ticks a second apart from start, a choppy uptrend every
+// strategy has an opinion on, and returns the time of the last one.
+func climb(tb *ringbuffer.TickBuffer, start time.Time, n int) time.Time {
+	at := start
+	for i := 0; i < n; i++ {
+		at = start.Add(time.Duration(i) * time.Second)
+		tb.WriteTick(ringbuffer.Tick{Time: at, ReceivedAt: at, Price: 100 + float64(i%7)*0.3 + float64(i)*0.05, Size: 1})
+	}
+	return at
+}
+
+func TestApplyQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		after      int           // ticks after a 2 minute silence, if any
+		wait       time.Duration // from the last tick to the evaluation
+		suppressed []string
+	}{
+		{name: "healthy", wait: time.Second},
+		{name: "stale", wait: 31 * time.Second, suppressed: []string{"meanReversion", "momentum", "breakout", "rsi"}},
+		{name: "gap behind every lookback", after: 50, wait: time.Second},
+		{name: "gap in breakout lookback", after: 30, wait: time.Second, suppressed: []string{"breakout"}},
+		// Momentum looks back 11 ticks and still fits after 12
+		{name: "gap in most lookbacks", after: 12, wait: time.Second, suppressed: []string{"meanReversion", "breakout", "rsi"}},
+		{name: "gap and stale", after: 5, wait: time.Minute, suppressed: []string{"meanReversion", "momentum", "breakout", "rsi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := ringbuffer.NewTickBuffer(ringbuffer.DefaultOptions())
+			last := climb(tb, clock0, 60)
+			if tt.after > 0 {
+				last = climb(tb, last.Add(2*time.Minute), tt.after)
+			}
+			q := tb.GetQuality(last.Add(tt.wait))
+			analyzed := AnalyzeSnapshot(tb.GetStats())
+			results := ApplyQuality(analyzed, q)
+
+			signals := map[string][2]Signal{
+				"meanReversion": {analyzed.MeanReversion, results.MeanReversion},
+				"momentum":      {analyzed.Momentum, results.Momentum},
+				"breakout":      {analyzed.Breakout, results.Breakout},
+				"rsi":           {analyzed.RSI, results.RSI},
+			}
+			var got []string
+			for _, name := range []string{"meanReversion", "momentum", "breakout", "rsi"} {
+				before, after := signals[name][0], signals[name][1]
+				if !strings.HasPrefix(after.Reason, "Suppressed") {
+					if after != before {
+						t.Errorf("%s changed to %+v", name, after)
+					}
+					continue
+				}
+				if after.Type != "NEUTRAL" || after.Strength != 0 {
+					t.Errorf("%s suppressed as %+v", name, after)
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.suppressed) {
+				t.Fatalf("suppressed %v with quality %+v, want %v", got, q, tt.suppressed)
+			}
+
+			if results.Quality == nil || *results.Quality != q {
+				t.Errorf("quality %+v not attached", results.Quality)
+			}
+			if want := GenerateConsensus(results); results.Consensus != want {
+				t.Errorf("consensus %q, want %q", results.Consensus, want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package strategies
 import (
 	"fmt"
 	"math"
+
+	"github.com/stahir80td/quantum-trader/stats"
 )
 
 // AnalyzeAll runs all strategies and generates consensus with conviction scoring
@@ -21,6 +23,43 @@ func AnalyzeAll(prices []float64) StrategyResults {
 	return results
 }
 
+// AnalyzeSnapshot runs all strategies on incrementally maintained statistics
+// instead of rescanning prices. For the same price history it produces the
+// same signals as AnalyzeAll.
+func AnalyzeSnapshot(snap stats.Snapshot) StrategyResults {
+	results := StrategyResults{
+		MeanReversion: insufficientData(20),
+		Momentum:      insufficientData(14),
+		Breakout:      insufficientData(50),
+		RSI:           insufficientData(15),
+	}
+
+	if snap.Count >= 20 {
+		results.MeanReversion = meanReversionSignal(snap.Last, snap.SMA, snap.StdDev)
+	}
+	if snap.Count >= 14 {
+		results.Momentum = momentumSignal(snap.Last, snap.MomentumBase, snap.Ups, snap.Downs, snap.Config.MomentumPeriod)
+	}
+	if snap.Count >= 50 {
+		results.Breakout = breakoutSignal(snap.Last, snap.High, snap.Low)
+	}
+	if snap.Count >= 15 {
+		results.RSI = rsiSignal(snap.AvgGain, snap.AvgLoss)
+	}
+
+	results.Consensus = GenerateConsensus(results)
+
+	return results
+}
+
+func insufficientData(required int) Signal {
+	return Signal{
+		Type:     "NEUTRAL",
+		Strength: 0,
+		Reason:   fmt.Sprintf("Need at least %d data points", required),
+	}
+}
+
 // GenerateConsensus implements weighted ensemble voting
 func GenerateConsensus(results StrategyResults) string {
 	strategies := []Signal{
@@ -117,7 +156,6 @@ func MeanReversion(prices []float64) Signal {
 	sma := sum / float64(period)
 
 	currentPrice := prices[len(prices)-1]
-	deviation := ((currentPrice - sma) / sma) * 100
 
 	// Calculate standard deviation for dynamic thresholds
 	variance := 0.0
@@ -126,6 +164,12 @@ func MeanReversion(prices []float64) Signal {
 		variance += diff * diff
 	}
 	stdDev := math.Sqrt(variance / float64(period))
+
+	return meanReversionSignal(currentPrice, sma, stdDev)
+}
+
+func meanReversionSignal(currentPrice, sma, stdDev float64) Signal {
+	deviation := ((currentPrice - sma) / sma) * 100
 	stdDevPercent := (stdDev / sma) * 100
 
 	threshold := stdDevPercent * 1.5
@@ -166,7 +210,6 @@ func Momentum(prices []float64) Signal {
 	period := 10
 	currentPrice := prices[len(prices)-1]
 	oldPrice := prices[len(prices)-period-1]
-	roc := ((currentPrice - oldPrice) / oldPrice) * 100
 
 	consecutiveUps := 0
 	consecutiveDowns := 0
@@ -179,6 +222,11 @@ func Momentum(prices []float64) Signal {
 		}
 	}
 
+	return momentumSignal(currentPrice, oldPrice, consecutiveUps, consecutiveDowns, period)
+}
+
+func momentumSignal(currentPrice, oldPrice float64, consecutiveUps, consecutiveDowns, period int) Signal {
+	roc := ((currentPrice - oldPrice) / oldPrice) * 100
 	momentumScore := float64(consecutiveUps) / float64(period) * 100
 
 	if roc > 2.0 && momentumScore >= 70 {
@@ -240,6 +288,11 @@ func Breakout(prices []float64) Signal {
 	}
 
 	currentPrice := prices[len(prices)-1]
+
+	return breakoutSignal(currentPrice, high, low)
+}
+
+func breakoutSignal(currentPrice, high, low float64) Signal {
 	priceRange := high - low
 	rangePosition := ((currentPrice - low) / priceRange) * 100
 
@@ -313,6 +366,10 @@ func RSI(prices []float64) Signal {
 	avgGain := gains / float64(period)
 	avgLoss := losses / float64(period)
 
+	return rsiSignal(avgGain, avgLoss)
+}
+
+func rsiSignal(avgGain, avgLoss float64) Signal {
 	if avgLoss == 0 {
 		if avgGain == 0 {
 			return Signal{
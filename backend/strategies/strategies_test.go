@@ -0,0 +1,48 @@
+package strategies
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stahir80td/quantum-trader/stats"
+)
+
+// TestSnapshotMatchesRescan checks that the incremental statistics give the
+// signals the rescanning strategies give, for every prefix of a random walk.
+func TestSnapshotMatchesRescan(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	tracker := stats.NewTracker(stats.DefaultConfig())
+	var prices []float64
+	price := 100.0
+	for i := 0; i < 1000; i++ {
+		switch r.Intn(8) {
+		case 0: // unchanged
+		case 1:
+			price += (r.Float64() - 0.5) * 10
+		default:
+			price += (r.Float64() - 0.5) * 0.4
+		}
+		prices = append(prices, price)
+		tracker.Push(price)
+
+		want := AnalyzeAll(prices)
+		got := AnalyzeSnapshot(tracker.Snapshot())
+		pairs := []struct {
+			name      string
+			got, want Signal
+		}{
+			{"mean reversion", got.MeanReversion, want.MeanReversion},
+			{"momentum", got.Momentum, want.Momentum},
+			{"breakout", got.Breakout, want.Breakout},
+			{"rsi", got.RSI, want.RSI},
+		}
+		for _, p := range pairs {
+			if p.got.Type != p.want.Type || p.got.Strength != p.want.Strength {
+				t.Fatalf("price %d %s: %+v, want %+v", i, p.name, p.got, p.want)
+			}
+		}
+		if got.Consensus != want.Consensus {
+			t.Fatalf("price %d consensus %q, want %q", i, got.Consensus, want.Consensus)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package strategies
 
+import "github.com/stahir80td/quantum-trader/ringbuffer"
+
 type Signal struct {
 	Type     string `json:"type"`     // "BUY", "SELL", "NEUTRAL"
 	Strength int    `json:"strength"` // 0-100
@@ -12,4 +14,9 @@ type StrategyResults struct {
 	Breakout      Signal `json:"breakout"`
 	RSI           Signal `json:"rsi"`
 	Consensus     string `json:"consensus"`
+
+	// Quality is set when the results went through ApplyQuality
+	Quality *ringbuffer.Quality `json:"quality,omitempty"`
+	// Book is set when the results went through ApplyBook
+	Book *BookSummary `json:"book,omitempty"`
 }
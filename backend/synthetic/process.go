@@ -0,0 +1,210 @@
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// year is the unit of every rate and volatility parameter, as is usual for
+// price processes; the feed converts each tick interval to a fraction of it.
+const year = 365 * 24 * time.Hour
+
+// Process kinds.
+const (
+	GBM    = "gbm"    // geometric Brownian motion
+	OU     = "ou"     // Ornstein–Uhlenbeck mean reversion of the log price
+	Regime = "regime" // GBM switching between a calm and a stormy regime
+	Jump   = "jump"   // Merton jump diffusion
+)
+
+// Spec describes one symbol's price process. Rates are per year and
+// volatilities annualised; unset parameters keep their defaults.
+type Spec struct {
+	Process string
+	Price   float64 // starting price; 0 picks one from defaultPrices
+	Mu      float64 // drift
+	Sigma   float64 // volatility
+	Theta   float64 // OU: speed of reversion to Mean
+	Mean    float64 // OU: level reverted to; 0 means the starting price
+	Storm   float64 // regime: volatility multiplier in the stormy regime
+	Switch  float64 // regime: rate of regime changes
+	Lambda  float64 // jump: rate of jumps
+	JumpMu  float64 // jump: mean log size of a jump
+	JumpSig float64 // jump: standard deviation of the log jump size
+}
+
+// defaultPrices start the listed pairs near realistic levels.
+var defaultPrices = map[string]float64{
+	"btcusdt": 65000,
+	"ethusdt": 3400,
+	"solusdt": 150,
+	"bnbusdt": 580,
+}
+
+func DefaultSpec() Spec {
+	return Spec{
+		Process: GBM,
+		Sigma:   0.6,
+		Theta:   2000, // half-life of about 3 hours
+		Storm:   4,
+		Switch:  2000,
+		Lambda:  500,
+		JumpSig: 0.02,
+	}
+}
+
+// ParseSpec parses "<process>[;key=value...]", e.g. "ou;sigma=0.4;theta=5000".
+func ParseSpec(s string) (Spec, error) {
+	spec := DefaultSpec()
+	parts := strings.Split(s, ";")
+	if name := strings.TrimSpace(parts[0]); name != "" {
+		spec.Process = strings.ToLower(name)
+	}
+	switch spec.Process {
+	case GBM, OU, Regime, Jump:
+	default:
+		return Spec{}, fmt.Errorf("synthetic: unknown process %q", spec.Process)
+	}
+
+	fields := map[string]*float64{
+		"price":   &spec.Price,
+		"mu":      &spec.Mu,
+		"sigma":   &spec.Sigma,
+		"theta":   &spec.Theta,
+		"mean":    &spec.Mean,
+		"storm":   &spec.Storm,
+		"switch":  &spec.Switch,
+		"lambda":  &spec.Lambda,
+		"jumpmu":  &spec.JumpMu,
+		"jumpsig": &spec.JumpSig,
+	}
+	for _, kv := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		field, known := fields[strings.ToLower(key)]
+		if !ok || !known {
+			return Spec{}, fmt.Errorf("synthetic: bad parameter %q", kv)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Spec{}, fmt.Errorf("synthetic: bad parameter %q: %w", kv, err)
+		}
+		*field = v
+	}
+	return spec, nil
+}
+
+// process evolves one symbol's log price. It is driven only by its own
+// random source, so a seed reproduces the same path.
+type process struct {
+	spec   Spec
+	rng    *rand.Rand
+	logP   float64
+	mean   float64 // OU target, log price
+	stormy bool
+}
+
+func newProcess(symbol string, spec Spec, rng *rand.Rand) *process {
+	price := spec.Price
+	if price <= 0 {
+		price = defaultPrices[symbol]
+	}
+	if price <= 0 {
+		price = 100
+	}
+	mean := spec.Mean
+	if mean <= 0 {
+		mean = price
+	}
+	return &process{spec: spec, rng: rng, logP: math.Log(price), mean: math.Log(mean)}
+}
+
+// step advances the process by dt years, with extra drift and volatility
+// scaling from any active scenario, and returns the new price.
+func (p *process) step(dt, drift, volScale float64) float64 {
+	s := p.spec
+	sigma := s.Sigma * volScale
+	dw := p.rng.NormFloat64() * math.Sqrt(dt)
+
+	switch s.Process {
+	case GBM:
+		p.logP += (s.Mu-sigma*sigma/2)*dt + sigma*dw
+	case OU:
+		p.logP += s.Theta*(p.mean-p.logP)*dt + sigma*dw
+	case Regime:
+		if p.rng.Float64() < s.Switch*dt {
+			p.stormy = !p.stormy
+		}
+		if p.stormy {
+			sigma *= s.Storm
+		}
+		p.logP += (s.Mu-sigma*sigma/2)*dt + sigma*dw
+	case Jump:
+		p.logP += (s.Mu-sigma*sigma/2)*dt + sigma*dw
+		if p.rng.Float64() < s.Lambda*dt {
+			p.logP += s.JumpMu + s.JumpSig*p.rng.NormFloat64()
+		}
+	}
+	p.logP += drift * dt
+	return math.Exp(p.logP)
+}
+
+// Scenario is a scheduled move layered on top of every symbol's process:
+// from At after the feed starts, the log price drifts by Return in total
+// over For. Crashes also triple volatility while they last.
+type Scenario struct {
+	Kind   string // "trend" or "crash"
+	At     time.Duration
+	For    time.Duration
+	Return float64
+}
+
+// ParseScenarios parses "<kind>@<start>+<duration>:<return>" entries
+// separated by ";", e.g. "trend@5m+10m:0.05;crash@20m+30s:-0.15".
+func ParseScenarios(s string) ([]Scenario, error) {
+	var out []Scenario
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, rest, ok1 := strings.Cut(entry, "@")
+		window, ret, ok2 := strings.Cut(rest, ":")
+		start, length, ok3 := strings.Cut(window, "+")
+		if !ok1 || !ok2 || !ok3 || (kind != "trend" && kind != "crash") {
+			return nil, fmt.Errorf("synthetic: bad scenario %q", entry)
+		}
+		sc := Scenario{Kind: kind}
+		var err error
+		if sc.At, err = time.ParseDuration(start); err != nil {
+			return nil, fmt.Errorf("synthetic: bad scenario %q: %w", entry, err)
+		}
+		if sc.For, err = time.ParseDuration(length); err != nil || sc.For <= 0 {
+			return nil, fmt.Errorf("synthetic: bad scenario %q: duration must be positive", entry)
+		}
+		if sc.Return, err = strconv.ParseFloat(ret, 64); err != nil {
+			return nil, fmt.Errorf("synthetic: bad scenario %q: %w", entry, err)
+		}
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+// active returns the extra annual drift and the volatility multiplier of
+// the scenarios running at elapsed.
+func active(scenarios []Scenario, elapsed time.Duration) (drift, volScale float64) {
+	volScale = 1
+	for _, sc := range scenarios {
+		if elapsed < sc.At || elapsed >= sc.At+sc.For {
+			continue
+		}
+		drift += sc.Return / (float64(sc.For) / float64(year))
+		if sc.Kind == "crash" {
+			volScale *= 3
+		}
+	}
+	return drift, volScale
+}
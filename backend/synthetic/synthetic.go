@@ -0,0 +1,328 @@
+package synthetic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+	"github.com/stahir80td/quantum-trader/ringbuffer"
+)
+
+const Name = "synthetic"
+
+// DefaultRate is how many trades per second each symbol prints.
+const DefaultRate = 5
+
+// spread is the quoted bid/ask spread as a fraction of the price.
+const spread = 0.0002
+
+func init() {
+	feed.Register(Name, New)
+}
+
+// Feed generates trades and quotes from price processes, for development
+// and demos without network access. It is configured through Params:
+//
+//	SEED      random seed; the same seed, rate and specs give the same prices
+//	RATE      trades per second per symbol
+//	PROCESS   default spec for every symbol, see ParseSpec
+//	SCENARIO  scheduled moves, see ParseScenarios
+//
+// Streams override the spec per symbol as "<symbol>=<spec>".
+type Feed struct {
+	seed      int64
+	interval  time.Duration
+	spec      Spec
+	specs     map[string]Spec
+	scenarios []Scenario
+	events    chan feed.Event
+	health    *feed.HealthTracker
+
+	mu      sync.Mutex
+	symbols []string
+	procs   map[string]*generator
+	running bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// generator is one symbol's process and trade numbering.
+type generator struct {
+	proc    *process
+	rng     *rand.Rand
+	price   float64
+	tradeID int64
+}
+
+func New(cfg feed.Config) (feed.MarketDataFeed, error) {
+	f := &Feed{
+		seed:     time.Now().UnixNano(),
+		interval: time.Second / DefaultRate,
+		spec:     DefaultSpec(),
+		specs:    make(map[string]Spec),
+		procs:    make(map[string]*generator),
+		events:   make(chan feed.Event, 1024),
+		health:   feed.NewHealthTracker(Name),
+	}
+	if v := cfg.Params["SEED"]; v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("synthetic: bad seed %q", v)
+		}
+		f.seed = seed
+	}
+	if v := cfg.Params["RATE"]; v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("synthetic: bad rate %q", v)
+		}
+		f.interval = time.Duration(float64(time.Second) / rate)
+	}
+	if v := cfg.Params["PROCESS"]; v != "" {
+		spec, err := ParseSpec(v)
+		if err != nil {
+			return nil, err
+		}
+		f.spec = spec
+	}
+	var err error
+	if f.scenarios, err = ParseScenarios(cfg.Params["SCENARIO"]); err != nil {
+		return nil, err
+	}
+	for _, stream := range cfg.Streams {
+		symbol, s, ok := strings.Cut(stream, "=")
+		if !ok {
+			return nil, fmt.Errorf("synthetic: stream %q is not <symbol>=<spec>", stream)
+		}
+		spec, err := ParseSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		f.specs[ringbuffer.NormalizeSymbol(symbol)] = spec
+	}
+
+	if err := f.Subscribe(cfg.Symbols); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Feed) Name() string {
+	return Name
+}
+
+func (f *Feed) Events() <-chan feed.Event {
+	return f.events
+}
+
+func (f *Feed) Health() feed.Health {
+	return f.health.Health()
+}
+
+func (f *Feed) Start(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ctx != nil || f.stopped {
+		return errors.New("synthetic: feed already started")
+	}
+	f.ctx, f.cancel = context.WithCancel(ctx)
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.run(f.ctx)
+	}()
+	return nil
+}
+
+func (f *Feed) Stop() error {
+	f.mu.Lock()
+	if f.stopped {
+		f.mu.Unlock()
+		return nil
+	}
+	f.stopped = true
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	close(f.events)
+	f.health.Stopped()
+	return nil
+}
+
+// Subscribe starts generating symbols. Each symbol's random source is
+// derived from the seed and its name, so adding symbols does not change
+// the paths of the others.
+func (f *Feed) Subscribe(symbols []string) error {
+	f.mu.Lock()
+	var added []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if contains(f.symbols, symbol) || contains(added, symbol) {
+			continue
+		}
+		if _, ok := feed.VenueSymbol(Name, symbol); !ok {
+			log.Printf("⚠️  Synthetic feed does not list %s, skipping", symbol)
+			continue
+		}
+		if _, ok := f.procs[symbol]; !ok {
+			spec, ok := f.specs[symbol]
+			if !ok {
+				spec = f.spec
+			}
+			h := fnv.New64a()
+			h.Write([]byte(symbol))
+			rng := rand.New(rand.NewSource(f.seed ^ int64(h.Sum64())))
+			proc := newProcess(symbol, spec, rng)
+			f.procs[symbol] = &generator{proc: proc, rng: rng, price: math.Exp(proc.logP)}
+		}
+		added = append(added, symbol)
+	}
+	f.symbols = append(f.symbols, added...)
+	f.health.SetSymbols(f.symbols)
+	live := f.running && len(added) > 0
+	ctx := f.ctx
+	f.mu.Unlock()
+
+	if live {
+		log.Printf("➕ Synthetic feed generating: %s", strings.Join(added, ", "))
+		f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: true, Symbols: added})
+	}
+	return nil
+}
+
+// Unsubscribe stops generating symbols. Their processes are kept, so a
+// later Subscribe continues the same path.
+func (f *Feed) Unsubscribe(symbols []string) error {
+	f.mu.Lock()
+	var removed []string
+	for _, symbol := range symbols {
+		symbol = ringbuffer.NormalizeSymbol(symbol)
+		if contains(f.symbols, symbol) && !contains(removed, symbol) {
+			removed = append(removed, symbol)
+		}
+	}
+	kept := f.symbols[:0]
+	for _, symbol := range f.symbols {
+		if !contains(removed, symbol) {
+			kept = append(kept, symbol)
+		}
+	}
+	f.symbols = kept
+	f.health.SetSymbols(f.symbols)
+	ctx := f.ctx
+	f.mu.Unlock()
+
+	if len(removed) > 0 && ctx != nil {
+		log.Printf("➖ Synthetic feed stopped: %s", strings.Join(removed, ", "))
+		f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: false, Symbols: removed})
+	}
+	return nil
+}
+
+func (f *Feed) run(ctx context.Context) {
+	f.health.Connecting()
+	f.mu.Lock()
+	f.running = true
+	symbols := append([]string(nil), f.symbols...)
+	f.mu.Unlock()
+
+	log.Printf("✅ Synthetic feed generating %s (seed %d, %s per tick)", strings.Join(symbols, ", "), f.seed, f.interval)
+	f.health.Connected()
+	f.emit(ctx, feed.Event{Kind: feed.EventStatus, Venue: Name, Connected: true, Symbols: symbols})
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	// Simulated time advances by exactly one interval per tick, so a slow
+	// consumer delays the path but never changes it
+	dt := float64(f.interval) / float64(year)
+	var elapsed time.Duration
+	// The status event owns symbols now
+	var current []string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed += f.interval
+			drift, volScale := active(f.scenarios, elapsed)
+			f.health.Message(now)
+
+			f.mu.Lock()
+			current = append(current[:0], f.symbols...)
+			f.mu.Unlock()
+			for _, symbol := range current {
+				f.tick(ctx, symbol, now, dt, drift, volScale)
+			}
+		}
+	}
+}
+
+// tick advances one symbol and emits its trade and quote.
+func (f *Feed) tick(ctx context.Context, symbol string, now time.Time, dt, drift, volScale float64) {
+	f.mu.Lock()
+	g := f.procs[symbol]
+	f.mu.Unlock()
+
+	last := g.price
+	g.price = g.proc.step(dt, drift, volScale)
+	g.tradeID++
+
+	side := "buy"
+	if g.price < last {
+		side = "sell"
+	}
+	// Trade notionals are log-normal around $2,000
+	size := 2000 * math.Exp(g.rng.NormFloat64()) / g.price
+	tick := ringbuffer.Tick{
+		Symbol:     symbol,
+		Time:       now,
+		ReceivedAt: now,
+		Price:      g.price,
+		Size:       size,
+		Side:       side,
+		Sequence:   g.tradeID,
+	}
+	f.emit(ctx, feed.Event{Kind: feed.EventTrade, Venue: Name, Symbol: symbol, Tick: tick, TradeID: g.tradeID})
+
+	quote := feed.Quote{
+		Time:     now,
+		Bid:      g.price * (1 - spread/2),
+		BidSize:  size * (1 + g.rng.Float64()),
+		Ask:      g.price * (1 + spread/2),
+		AskSize:  size * (1 + g.rng.Float64()),
+		Sequence: g.tradeID,
+	}
+	f.emit(ctx, feed.Event{Kind: feed.EventQuote, Venue: Name, Symbol: symbol, Quote: quote})
+}
+
+func (f *Feed) emit(ctx context.Context, ev feed.Event) {
+	select {
+	case f.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
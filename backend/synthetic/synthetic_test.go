@@ -0,0 +1,140 @@
+package synthetic
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stahir80td/quantum-trader/feed"
+)
+
+// prices runs a feed until it has printed n trades of each symbol and
+// returns their prices by symbol.
+func prices(t *testing.T, params map[string]string, symbols []string, n int) map[string][]float64 {
+	t.Helper()
+	f, err := New(feed.Config{Venue: Name, Symbols: symbols, Params: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := f.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+
+	out := make(map[string][]float64)
+	for done := 0; done < len(symbols); {
+		select {
+		case ev := <-f.Events():
+			if ev.Kind != feed.EventTrade || len(out[ev.Symbol]) == n {
+				continue
+			}
+			if ev.TradeID != int64(len(out[ev.Symbol])+1) {
+				t.Fatalf("%s trade %d numbered %d", ev.Symbol, len(out[ev.Symbol])+1, ev.TradeID)
+			}
+			out[ev.Symbol] = append(out[ev.Symbol], ev.Tick.Price)
+			if len(out[ev.Symbol]) == n {
+				done++
+			}
+		case <-ctx.Done():
+			t.Fatalf("only %v trades before the deadline", out)
+		}
+	}
+	return out
+}
+
+func equalPrices(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSeedReproducesPaths(t *testing.T) {
+	params := func(seed string) map[string]string {
+		return map[string]string{"SEED": seed, "RATE": "1000", "PROCESS": "jump;lambda=50000"}
+	}
+	symbols := []string{"btcusdt", "ethusdt"}
+	a := prices(t, params("42"), symbols, 100)
+	b := prices(t, params("42"), symbols, 100)
+	c := prices(t, params("43"), symbols, 100)
+
+	for _, symbol := range symbols {
+		if !equalPrices(a[symbol], b[symbol]) {
+			t.Errorf("%s: seed 42 gave two paths\n%v\n%v", symbol, a[symbol], b[symbol])
+		}
+		if equalPrices(a[symbol], c[symbol]) {
+			t.Errorf("%s: seeds 42 and 43 gave the same path", symbol)
+		}
+	}
+	if equalPrices(a["btcusdt"][:10], a["ethusdt"][:10]) {
+		t.Error("symbols share a random source")
+	}
+
+	// A symbol's path does not depend on which others are generated
+	alone := prices(t, params("42"), []string{"ethusdt"}, 100)
+	if !equalPrices(alone["ethusdt"], a["ethusdt"]) {
+		t.Error("ethusdt path changed when generated alone")
+	}
+}
+
+func TestScenarioTrend(t *testing.T) {
+	// Ticks are 1ms of simulated time apart and volatility is negligible,
+	// so ticks 20 to 59 carry the whole 10% move and the rest are flat
+	params := map[string]string{
+		"SEED":     "7",
+		"RATE":     "1000",
+		"PROCESS":  "gbm;sigma=0.0001;price=100",
+		"SCENARIO": "trend@20ms+40ms:0.1",
+	}
+	path := prices(t, params, []string{"btcusdt"}, 80)["btcusdt"]
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-3 }
+	if !near(path[18], 100) {
+		t.Errorf("price %v before the trend, want 100", path[18])
+	}
+	if want := 100 * math.Exp(0.05); !near(path[38], want) {
+		t.Errorf("price %v halfway through, want %v", path[38], want)
+	}
+	if want := 100 * math.Exp(0.1); !near(path[58], want) || !near(path[79], want) {
+		t.Errorf("price %v at the end and %v after, want %v", path[58], path[79], want)
+	}
+}
+
+func TestActiveScenarios(t *testing.T) {
+	scenarios, err := ParseScenarios("trend@1m+2m:0.05; crash@2m+30s:-0.15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	perYear := func(ret float64, d time.Duration) float64 { return ret / (float64(d) / float64(year)) }
+	tests := []struct {
+		elapsed  time.Duration
+		drift    float64
+		volScale float64
+	}{
+		{elapsed: 59 * time.Second, volScale: 1},
+		{elapsed: time.Minute, drift: perYear(0.05, 2*time.Minute), volScale: 1},
+		{elapsed: 2 * time.Minute, drift: perYear(0.05, 2*time.Minute) + perYear(-0.15, 30*time.Second), volScale: 3},
+		{elapsed: 150 * time.Second, drift: perYear(0.05, 2*time.Minute), volScale: 1},
+		{elapsed: 3 * time.Minute, volScale: 1},
+	}
+	for _, tt := range tests {
+		drift, volScale := active(scenarios, tt.elapsed)
+		if math.Abs(drift-tt.drift) > 1e-9*math.Abs(tt.drift) || volScale != tt.volScale {
+			t.Errorf("at %s: drift %v x%v, want %v x%v", tt.elapsed, drift, volScale, tt.drift, tt.volScale)
+		}
+	}
+
+	for _, bad := range []string{"dip@1m+1m:0.1", "trend@1m:0.1", "trend@1m+0s:0.1", "crash@x+1m:-0.1"} {
+		if _, err := ParseScenarios(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}